@@ -0,0 +1,208 @@
+package alert
+
+import (
+	"context"
+	"log"
+	"sort"
+	"sync"
+	"time"
+)
+
+// State is the state of a rule.
+type State string
+
+const (
+	// StatePending means the condition holds but not yet for the rule's
+	// duration.
+	StatePending State = "pending"
+	// StateFiring means the condition has held for the rule's duration.
+	StateFiring State = "firing"
+	// StateResolved means the condition of a firing rule stopped holding.
+	StateResolved State = "resolved"
+)
+
+// notifyTimeout bounds how long a single notification may take.
+const notifyTimeout = 10 * time.Second
+
+// Event is a state change of a rule.
+type Event struct {
+	ID        int64   `json:"id,omitempty"`
+	Rule      string  `json:"rule"`
+	Expr      string  `json:"expr,omitempty"`
+	Metric    string  `json:"metric"`
+	Label     string  `json:"label,omitempty"`
+	State     State   `json:"state"`
+	Severity  string  `json:"severity"`
+	Value     float64 `json:"value"`
+	Threshold float64 `json:"threshold"`
+	// StartedAt is when the condition started holding, in unix seconds.
+	StartedAt int64 `json:"started_at"`
+	Timestamp int64 `json:"timestamp"`
+}
+
+// Active is a rule that is currently pending or firing.
+type Active struct {
+	Rule      string  `json:"rule"`
+	State     State   `json:"state"`
+	Severity  string  `json:"severity"`
+	Value     float64 `json:"value"`
+	StartedAt int64   `json:"started_at"`
+}
+
+type ruleState struct {
+	state State
+	since time.Time
+	value float64
+}
+
+// Engine evaluates rules against observed samples.
+type Engine struct {
+	rules     []Rule
+	store     *Store
+	notifiers []Notifier
+
+	mu     sync.Mutex
+	states map[string]*ruleState
+
+	// Called with every state change, registered before the first Observe
+	listeners []func(Event)
+}
+
+// NewEngine creates an Engine that records state changes in store and
+// sends firing and resolved events to notifiers.
+func NewEngine(rules []Rule, store *Store, notifiers []Notifier) *Engine {
+	return &Engine{
+		rules:     rules,
+		store:     store,
+		notifiers: notifiers,
+		states:    make(map[string]*ruleState),
+	}
+}
+
+// AddListener registers fn to be called with every state change. It must be
+// called before samples are observed.
+func (e *Engine) AddListener(fn func(Event)) {
+	e.listeners = append(e.listeners, fn)
+}
+
+// Rules returns the configured rules.
+func (e *Engine) Rules() []Rule {
+	return e.rules
+}
+
+// Active returns the pending and firing rules, sorted by name.
+func (e *Engine) Active() []Active {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	active := make([]Active, 0, len(e.states))
+	for _, rule := range e.rules {
+		if s, ok := e.states[rule.Name]; ok {
+			active = append(active, Active{
+				Rule:      rule.Name,
+				State:     s.state,
+				Severity:  rule.Severity,
+				Value:     s.value,
+				StartedAt: s.since.Unix(),
+			})
+		}
+	}
+	sort.Slice(active, func(i, j int) bool {
+		return active[i].Rule < active[j].Rule
+	})
+	return active
+}
+
+// History returns up to limit recorded state changes, most recent first.
+func (e *Engine) History(limit int) ([]Event, error) {
+	return e.store.History(limit)
+}
+
+// Observe evaluates the rules on a metric series against a sample taken at
+// t. It is safe to call from several collectors concurrently.
+func (e *Engine) Observe(metric, label string, value float64, t time.Time) {
+	var events []Event
+
+	e.mu.Lock()
+	for _, rule := range e.rules {
+		if rule.Metric != metric || rule.Label != label {
+			continue
+		}
+		if event, changed := e.evaluate(rule, value, t); changed {
+			events = append(events, event)
+		}
+	}
+	e.mu.Unlock()
+
+	for _, event := range events {
+		e.dispatch(event)
+	}
+}
+
+// evaluate advances the state of rule. The caller must hold e.mu.
+func (e *Engine) evaluate(rule Rule, value float64, t time.Time) (Event, bool) {
+	s, active := e.states[rule.Name]
+	holds := rule.Op.compare(value, rule.Threshold)
+	if active {
+		s.value = value
+	}
+
+	switch {
+	case holds && !active:
+		s = &ruleState{state: StatePending, since: t, value: value}
+		if rule.For == 0 {
+			s.state = StateFiring
+		}
+		e.states[rule.Name] = s
+	case holds && s.state == StatePending && t.Sub(s.since) >= rule.For:
+		s.state = StateFiring
+	case !holds && active:
+		delete(e.states, rule.Name)
+		if s.state == StatePending {
+			// Never fired, so there is nothing to resolve.
+			return Event{}, false
+		}
+		s.state = StateResolved
+	default:
+		return Event{}, false
+	}
+	return newEvent(rule, s, t), true
+}
+
+func newEvent(rule Rule, s *ruleState, t time.Time) Event {
+	return Event{
+		Rule:      rule.Name,
+		Expr:      rule.Expr,
+		Metric:    rule.Metric,
+		Label:     rule.Label,
+		State:     s.state,
+		Severity:  rule.Severity,
+		Value:     s.value,
+		Threshold: rule.Threshold,
+		StartedAt: s.since.Unix(),
+		Timestamp: t.Unix(),
+	}
+}
+
+// dispatch records an event and notifies about firing and resolved rules.
+// Notifiers run in the background so slow endpoints cannot stall sampling.
+func (e *Engine) dispatch(event Event) {
+	log.Printf("Alert '%s' is %s (value %g, threshold %g)", event.Rule, event.State, event.Value, event.Threshold)
+	if err := e.store.Record(event); err != nil {
+		log.Printf("Error recording alert event: %v", err)
+	}
+	for _, fn := range e.listeners {
+		fn(event)
+	}
+	if event.State == StatePending {
+		return
+	}
+	for _, n := range e.notifiers {
+		go func(n Notifier) {
+			ctx, cancel := context.WithTimeout(context.Background(), notifyTimeout)
+			defer cancel()
+			if err := n.Notify(ctx, event); err != nil {
+				log.Printf("Error sending alert '%s' via %s: %v", event.Rule, n.Name(), err)
+			}
+		}(n)
+	}
+}
@@ -0,0 +1,119 @@
+package alert
+
+import (
+	"database/sql"
+	"slices"
+	"testing"
+	"time"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+func newTestEngine(t *testing.T, exprs ...string) (*Engine, *[]Event) {
+	t.Helper()
+	db, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Every connection would get its own in-memory database
+	db.SetMaxOpenConns(1)
+	t.Cleanup(func() { db.Close() })
+	store, err := NewStore(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var rules []Rule
+	for _, expr := range exprs {
+		rule, err := ParseRule(expr, expr, "warning", testMetrics)
+		if err != nil {
+			t.Fatal(err)
+		}
+		rules = append(rules, rule)
+	}
+	e := NewEngine(rules, store, nil)
+	var events []Event
+	e.AddListener(func(event Event) {
+		events = append(events, event)
+	})
+	return e, &events
+}
+
+func states(events []Event) []State {
+	var s []State
+	for _, e := range events {
+		s = append(s, e.State)
+	}
+	return s
+}
+
+func TestEngineTransitions(t *testing.T) {
+	start := time.Unix(1700000000, 0)
+	tests := []struct {
+		name   string
+		expr   string
+		values []float64 // one sample every 30s
+		want   []State
+	}{
+		{"fires after duration", "cpu_percent > 90 for 1m", []float64{95, 95, 95}, []State{StatePending, StateFiring}},
+		{"not before duration", "cpu_percent > 90 for 1m", []float64{95, 95}, []State{StatePending}},
+		{"pending then clear", "cpu_percent > 90 for 1m", []float64{95, 95, 50}, []State{StatePending}},
+		{"fires and resolves", "cpu_percent > 90 for 1m", []float64{95, 95, 95, 96, 50, 50}, []State{StatePending, StateFiring, StateResolved}},
+		{"restarts after clear", "cpu_percent > 90 for 1m", []float64{95, 95, 50, 95, 95, 95}, []State{StatePending, StatePending, StateFiring}},
+		{"no duration fires at once", "cpu_percent > 90", []float64{95, 95, 50}, []State{StateFiring, StateResolved}},
+		{"never holds", "cpu_percent > 90 for 1m", []float64{10, 20, 90}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e, events := newTestEngine(t, tt.expr)
+			for i, v := range tt.values {
+				e.Observe("cpu_percent", "", v, start.Add(time.Duration(i)*30*time.Second))
+			}
+			if got := states(*events); !slices.Equal(got, tt.want) {
+				t.Errorf("events = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEngineEvent(t *testing.T) {
+	e, events := newTestEngine(t, "down_bps[en0] < 1000 for 1m")
+	start := time.Unix(1700000000, 0)
+	e.Observe("down_bps", "en0", 500, start)
+	// Other series of the metric are ignored
+	e.Observe("down_bps", "en1", 5000, start.Add(30*time.Second))
+	e.Observe("down_bps", "", 5000, start.Add(30*time.Second))
+	e.Observe("down_bps", "en0", 400, start.Add(time.Minute))
+
+	if len(*events) != 2 {
+		t.Fatalf("got %d events, want 2", len(*events))
+	}
+	got := (*events)[1]
+	want := Event{
+		Rule:      "down_bps[en0] < 1000 for 1m",
+		Expr:      "down_bps[en0] < 1000 for 1m",
+		Metric:    "down_bps",
+		Label:     "en0",
+		State:     StateFiring,
+		Severity:  "warning",
+		Value:     400,
+		Threshold: 1000,
+		StartedAt: start.Unix(),
+		Timestamp: start.Add(time.Minute).Unix(),
+	}
+	if got != want {
+		t.Errorf("event = %+v, want %+v", got, want)
+	}
+
+	active := e.Active()
+	if len(active) != 1 || active[0].State != StateFiring || active[0].Value != 400 {
+		t.Errorf("Active() = %+v, want the firing rule", active)
+	}
+	history, err := e.History(10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := states(history); !slices.Equal(got, []State{StateFiring, StatePending}) {
+		t.Errorf("history = %v, want [firing pending]", got)
+	}
+}
@@ -0,0 +1,128 @@
+package alert
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"os/exec"
+	"strconv"
+	"sync"
+)
+
+// Notifier delivers firing and resolved events.
+type Notifier interface {
+	// Name identifies the notifier in logs.
+	Name() string
+	Notify(ctx context.Context, event Event) error
+}
+
+// WebhookNotifier POSTs each event as JSON to a URL.
+type WebhookNotifier struct {
+	URL     string
+	Headers map[string]string
+	Client  *http.Client
+}
+
+// Name implements Notifier.
+func (n *WebhookNotifier) Name() string {
+	return "webhook " + n.URL
+}
+
+// Notify implements Notifier. Any non-2xx response is an error.
+func (n *WebhookNotifier) Notify(ctx context.Context, event Event) error {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, n.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range n.Headers {
+		req.Header.Set(k, v)
+	}
+
+	client := n.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	return nil
+}
+
+// CommandNotifier runs a local command for each event. The event is written
+// to the command's stdin as JSON and its main fields are passed in
+// ALERT_* environment variables.
+type CommandNotifier struct {
+	Command []string
+}
+
+// Name implements Notifier.
+func (n *CommandNotifier) Name() string {
+	return "command " + n.Command[0]
+}
+
+// Notify implements Notifier.
+func (n *CommandNotifier) Notify(ctx context.Context, event Event) error {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	cmd := exec.CommandContext(ctx, n.Command[0], n.Command[1:]...)
+	cmd.Stdin = bytes.NewReader(body)
+	cmd.Env = append(os.Environ(),
+		"ALERT_RULE="+event.Rule,
+		"ALERT_STATE="+string(event.State),
+		"ALERT_SEVERITY="+event.Severity,
+		"ALERT_METRIC="+event.Metric,
+		"ALERT_LABEL="+event.Label,
+		"ALERT_VALUE="+strconv.FormatFloat(event.Value, 'g', -1, 64),
+		"ALERT_THRESHOLD="+strconv.FormatFloat(event.Threshold, 'g', -1, 64),
+	)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("%w: %s", err, bytes.TrimSpace(out))
+	}
+	return nil
+}
+
+// LogFileNotifier appends each event as a JSON line to a file.
+type LogFileNotifier struct {
+	Path string
+
+	mu sync.Mutex
+}
+
+// Name implements Notifier.
+func (n *LogFileNotifier) Name() string {
+	return "log " + n.Path
+}
+
+// Notify implements Notifier.
+func (n *LogFileNotifier) Notify(ctx context.Context, event Event) error {
+	line, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	f, err := os.OpenFile(n.Path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
@@ -0,0 +1,131 @@
+package alert
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+var testEvent = Event{
+	Rule:      "high_cpu",
+	Metric:    "cpu_percent",
+	State:     StateFiring,
+	Severity:  "critical",
+	Value:     95,
+	Threshold: 90,
+	StartedAt: 1700000000,
+	Timestamp: 1700000120,
+}
+
+func TestWebhookNotifier(t *testing.T) {
+	var (
+		gotMethod, gotType, gotAuth string
+		got                         Event
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotMethod = r.Method
+		gotType = r.Header.Get("Content-Type")
+		gotAuth = r.Header.Get("Authorization")
+		if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+			t.Errorf("decoding body: %v", err)
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+
+	n := &WebhookNotifier{URL: srv.URL, Headers: map[string]string{"Authorization": "Bearer secret"}}
+	if err := n.Notify(context.Background(), testEvent); err != nil {
+		t.Fatalf("Notify: %v", err)
+	}
+	if gotMethod != http.MethodPost {
+		t.Errorf("method = %s, want POST", gotMethod)
+	}
+	if gotType != "application/json" {
+		t.Errorf("Content-Type = %q, want application/json", gotType)
+	}
+	if gotAuth != "Bearer secret" {
+		t.Errorf("Authorization = %q, want the configured header", gotAuth)
+	}
+	if got != testEvent {
+		t.Errorf("body = %+v, want %+v", got, testEvent)
+	}
+}
+
+func TestWebhookNotifierStatus(t *testing.T) {
+	for _, status := range []int{http.StatusMovedPermanently, http.StatusBadRequest, http.StatusInternalServerError} {
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(status)
+		}))
+		n := &WebhookNotifier{URL: srv.URL, Client: &http.Client{
+			CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse },
+		}}
+		if err := n.Notify(context.Background(), testEvent); err == nil {
+			t.Errorf("status %d: Notify returned no error", status)
+		}
+		srv.Close()
+	}
+}
+
+func TestLogFileNotifier(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "alerts.log")
+	n := &LogFileNotifier{Path: path}
+	resolved := testEvent
+	resolved.State = StateResolved
+	for _, e := range []Event{testEvent, resolved} {
+		if err := n.Notify(context.Background(), e); err != nil {
+			t.Fatalf("Notify: %v", err)
+		}
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSuffix(string(data), "\n"), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("got %d lines, want 2:\n%s", len(lines), data)
+	}
+	for i, want := range []State{StateFiring, StateResolved} {
+		var e Event
+		if err := json.Unmarshal([]byte(lines[i]), &e); err != nil {
+			t.Fatalf("line %d: %v", i, err)
+		}
+		if e.State != want || e.Rule != testEvent.Rule {
+			t.Errorf("line %d = %+v, want rule %s in state %s", i, e, testEvent.Rule, want)
+		}
+	}
+}
+
+func TestCommandNotifier(t *testing.T) {
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("no sh available")
+	}
+	out := filepath.Join(t.TempDir(), "out")
+	n := &CommandNotifier{Command: []string{"sh", "-c", `echo "$ALERT_RULE $ALERT_STATE $ALERT_VALUE" > "$0"; cat >> "$0"`, out}}
+	if err := n.Notify(context.Background(), testEvent); err != nil {
+		t.Fatalf("Notify: %v", err)
+	}
+	data, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	env, body, _ := strings.Cut(string(data), "\n")
+	if env != "high_cpu firing 95" {
+		t.Errorf("environment = %q, want %q", env, "high_cpu firing 95")
+	}
+	var e Event
+	if err := json.Unmarshal([]byte(body), &e); err != nil || e != testEvent {
+		t.Errorf("stdin = %q, want the event as JSON", body)
+	}
+
+	failing := &CommandNotifier{Command: []string{"sh", "-c", "echo broken >&2; exit 3"}}
+	if err := failing.Notify(context.Background(), testEvent); err == nil || !strings.Contains(err.Error(), "broken") {
+		t.Errorf("Notify = %v, want an error with the output", err)
+	}
+}
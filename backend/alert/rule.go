@@ -0,0 +1,96 @@
+// Package alert evaluates threshold rules against metric samples, tracks
+// the state of each rule, records state changes and sends notifications.
+package alert
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"time"
+)
+
+// Op is a comparison operator of a rule.
+type Op string
+
+const (
+	OpGreater      Op = ">"
+	OpGreaterEqual Op = ">="
+	OpLess         Op = "<"
+	OpLessEqual    Op = "<="
+	OpEqual        Op = "=="
+	OpNotEqual     Op = "!="
+)
+
+func (op Op) compare(value, threshold float64) bool {
+	switch op {
+	case OpGreater:
+		return value > threshold
+	case OpGreaterEqual:
+		return value >= threshold
+	case OpLess:
+		return value < threshold
+	case OpLessEqual:
+		return value <= threshold
+	case OpEqual:
+		return value == threshold
+	case OpNotEqual:
+		return value != threshold
+	}
+	return false
+}
+
+// Rule fires when a metric satisfies a comparison continuously for a
+// duration.
+type Rule struct {
+	Name     string `json:"name"`
+	Expr     string `json:"expr"`
+	Severity string `json:"severity"`
+	Metric   string `json:"metric"`
+	// Label selects a series of the metric, such as an interface or a mount
+	// point. Empty selects the default series.
+	Label     string        `json:"label,omitempty"`
+	Op        Op            `json:"op"`
+	Threshold float64       `json:"threshold"`
+	For       time.Duration `json:"-"`
+	ForSec    float64       `json:"for_sec"`
+}
+
+// ruleExpr matches expressions such as "cpu_percent > 90 for 2m" or
+// "down_bps[en0] < 1000 for 5m".
+var ruleExpr = regexp.MustCompile(`^\s*([a-z0-9_]+)(?:\[([^\]]+)\])?\s*(>=|<=|==|!=|>|<)\s*(-?[0-9.eE+-]+)\s*(?:for\s+(\S+))?\s*$`)
+
+// ParseRule parses a rule expression of the form
+// "<metric>[<label>] <op> <threshold> [for <duration>]". The metric must be
+// one of metrics, the metrics the engine is fed, so a misspelt rule fails
+// instead of never firing.
+func ParseRule(name, expr, severity string, metrics []string) (Rule, error) {
+	m := ruleExpr.FindStringSubmatch(expr)
+	if m == nil {
+		return Rule{}, fmt.Errorf("invalid rule expression '%s'", expr)
+	}
+	if !slices.Contains(metrics, m[1]) {
+		return Rule{}, fmt.Errorf("unknown metric '%s'", m[1])
+	}
+	threshold, err := strconv.ParseFloat(m[4], 64)
+	if err != nil {
+		return Rule{}, fmt.Errorf("invalid threshold in '%s': %w", expr, err)
+	}
+	var forDuration time.Duration
+	if m[5] != "" {
+		if forDuration, err = time.ParseDuration(m[5]); err != nil || forDuration < 0 {
+			return Rule{}, fmt.Errorf("invalid duration in '%s'", expr)
+		}
+	}
+	return Rule{
+		Name:      name,
+		Expr:      expr,
+		Severity:  severity,
+		Metric:    m[1],
+		Label:     m[2],
+		Op:        Op(m[3]),
+		Threshold: threshold,
+		For:       forDuration,
+		ForSec:    forDuration.Seconds(),
+	}, nil
+}
@@ -0,0 +1,56 @@
+package alert
+
+import (
+	"testing"
+	"time"
+)
+
+// testMetrics are the metrics rules may use in tests.
+var testMetrics = []string{"cpu_percent", "down_bps", "disk_percent", "memory_pressure"}
+
+func TestParseRule(t *testing.T) {
+	tests := []struct {
+		expr string
+		want Rule
+	}{
+		{"cpu_percent > 90", Rule{Metric: "cpu_percent", Op: OpGreater, Threshold: 90}},
+		{"cpu_percent>=90.5 for 2m", Rule{Metric: "cpu_percent", Op: OpGreaterEqual, Threshold: 90.5, For: 2 * time.Minute, ForSec: 120}},
+		{"down_bps[en0] < 1000 for 5m", Rule{Metric: "down_bps", Label: "en0", Op: OpLess, Threshold: 1000, For: 5 * time.Minute, ForSec: 300}},
+		{"  disk_percent[/Volumes/Data] != -1e3  ", Rule{Metric: "disk_percent", Label: "/Volumes/Data", Op: OpNotEqual, Threshold: -1000}},
+		{"memory_pressure == 2 for 30s", Rule{Metric: "memory_pressure", Op: OpEqual, Threshold: 2, For: 30 * time.Second, ForSec: 30}},
+	}
+	for _, tt := range tests {
+		got, err := ParseRule("rule", tt.expr, "warning", testMetrics)
+		if err != nil {
+			t.Errorf("ParseRule(%q): %v", tt.expr, err)
+			continue
+		}
+		tt.want.Name, tt.want.Expr, tt.want.Severity = "rule", tt.expr, "warning"
+		if got != tt.want {
+			t.Errorf("ParseRule(%q) = %+v, want %+v", tt.expr, got, tt.want)
+		}
+	}
+}
+
+func TestParseRuleInvalid(t *testing.T) {
+	for _, expr := range []string{
+		"",
+		"cpu_percent",
+		"cpu_percent 90",
+		"cpu_percent => 90",
+		"cpu_percent > ninety",
+		"cpu_percent > 1.2.3",
+		"CPU_percent > 90",
+		"cpu_percent[] > 90",
+		"cpu_percent > 90 for",
+		"cpu_percent > 90 for 2",
+		"cpu_percent > 90 for -1m",
+		"cpu_percent > 90 during 2m",
+		"cpu_precent > 90 for 2m",
+		"up_bps > 1000",
+	} {
+		if r, err := ParseRule("rule", expr, "warning", testMetrics); err == nil {
+			t.Errorf("ParseRule(%q) = %+v, want an error", expr, r)
+		}
+	}
+}
@@ -0,0 +1,68 @@
+package alert
+
+import (
+	"database/sql"
+	"fmt"
+)
+
+const eventsTableStmt = `
+	CREATE TABLE IF NOT EXISTS alert_events (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		rule TEXT NOT NULL,
+		metric TEXT NOT NULL,
+		label TEXT NOT NULL DEFAULT '',
+		state TEXT NOT NULL,
+		severity TEXT NOT NULL,
+		value REAL NOT NULL,
+		threshold REAL NOT NULL,
+		started_at INTEGER NOT NULL,
+		timestamp INTEGER NOT NULL
+	);`
+
+// Store persists the history of alert state changes.
+type Store struct {
+	db *sql.DB
+}
+
+// NewStore creates the alert history table on db if needed.
+func NewStore(db *sql.DB) (*Store, error) {
+	if _, err := db.Exec(eventsTableStmt); err != nil {
+		return nil, fmt.Errorf("failed to create alert events table: %w", err)
+	}
+	return &Store{db: db}, nil
+}
+
+// Record appends a state change to the history.
+func (s *Store) Record(e Event) error {
+	_, err := s.db.Exec(`
+		INSERT INTO alert_events (rule, metric, label, state, severity, value, threshold, started_at, timestamp)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		e.Rule, e.Metric, e.Label, e.State, e.Severity, e.Value, e.Threshold, e.StartedAt, e.Timestamp)
+	if err != nil {
+		return fmt.Errorf("failed to record alert event: %w", err)
+	}
+	return nil
+}
+
+// History returns up to limit state changes, most recent first.
+func (s *Store) History(limit int) ([]Event, error) {
+	rows, err := s.db.Query(`
+		SELECT id, rule, metric, label, state, severity, value, threshold, started_at, timestamp
+		FROM alert_events
+		ORDER BY id DESC
+		LIMIT ?`, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query alert events: %w", err)
+	}
+	defer rows.Close()
+
+	events := []Event{}
+	for rows.Next() {
+		var e Event
+		if err := rows.Scan(&e.ID, &e.Rule, &e.Metric, &e.Label, &e.State, &e.Severity, &e.Value, &e.Threshold, &e.StartedAt, &e.Timestamp); err != nil {
+			return nil, fmt.Errorf("failed to scan alert event: %w", err)
+		}
+		events = append(events, e)
+	}
+	return events, rows.Err()
+}
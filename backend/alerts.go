@@ -0,0 +1,123 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"slices"
+	"strconv"
+	"time"
+
+	"macos-monitor/backend-go/alert"
+	"macos-monitor/backend-go/config"
+	"macos-monitor/backend-go/history"
+	"macos-monitor/backend-go/network"
+)
+
+const (
+	defaultAlertHistoryLimit = 100
+	maxAlertHistoryLimit     = 1000
+	defaultWebhookTimeout    = 5 * time.Second
+)
+
+// Network rate metrics fed to the alert engine by observeRate.
+const (
+	metricDownBPS = "down_bps"
+	metricUpBPS   = "up_bps"
+)
+
+// alertMetrics are the metrics rules may use: those of the system history
+// and the network rates.
+var alertMetrics = append(slices.Clone(history.Metrics), metricDownBPS, metricUpBPS)
+
+// newAlertEngine builds the alert engine from the configured rules and
+// notifiers.
+func newAlertEngine(cfg config.AlertsConfig, db *sql.DB) (*alert.Engine, error) {
+	rules := make([]alert.Rule, 0, len(cfg.Rules))
+	for _, r := range cfg.Rules {
+		rule, err := alert.ParseRule(r.Name, r.Expr, r.Severity, alertMetrics)
+		if err != nil {
+			return nil, fmt.Errorf("rule '%s': %w", r.Name, err)
+		}
+		rules = append(rules, rule)
+	}
+
+	notifiers := make([]alert.Notifier, 0, len(cfg.Notifiers))
+	for _, n := range cfg.Notifiers {
+		switch n.Type {
+		case "webhook":
+			timeout := n.Timeout.Std()
+			if timeout <= 0 {
+				timeout = defaultWebhookTimeout
+			}
+			notifiers = append(notifiers, &alert.WebhookNotifier{
+				URL:     n.URL,
+				Headers: n.Headers,
+				Client:  &http.Client{Timeout: timeout},
+			})
+		case "command":
+			notifiers = append(notifiers, &alert.CommandNotifier{Command: n.Command})
+		case "log":
+			notifiers = append(notifiers, &alert.LogFileNotifier{Path: n.Path})
+		}
+	}
+
+	store, err := alert.NewStore(db)
+	if err != nil {
+		return nil, err
+	}
+	return alert.NewEngine(rules, store, notifiers), nil
+}
+
+// observeRate feeds a network rate into the alert engine as down_bps and
+// up_bps. The aggregate of all interfaces is the default series; single
+// interfaces are selected with a label, e.g. "down_bps[en0]".
+func observeRate(engine *alert.Engine, rate network.RealtimeRate) {
+	label := rate.Interface
+	if label == network.AllInterfaces {
+		label = ""
+	}
+	t := time.Unix(rate.Timestamp, 0)
+	engine.Observe(metricDownBPS, label, rate.DownBPS, t)
+	engine.Observe(metricUpBPS, label, rate.UpBPS, t)
+}
+
+type alertsResponse struct {
+	Rules  []alert.Rule   `json:"rules"`
+	Active []alert.Active `json:"active"`
+}
+
+func alertsHandler(engine *alert.Engine) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(alertsResponse{
+			Rules:  engine.Rules(),
+			Active: engine.Active(),
+		})
+	}
+}
+
+func alertHistoryHandler(engine *alert.Engine) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		limit := defaultAlertHistoryLimit
+		if v := r.URL.Query().Get("limit"); v != "" {
+			n, err := strconv.Atoi(v)
+			if err != nil || n < 1 || n > maxAlertHistoryLimit {
+				http.Error(w, fmt.Sprintf("'limit' must be between 1 and %d", maxAlertHistoryLimit), http.StatusBadRequest)
+				return
+			}
+			limit = n
+		}
+
+		events, err := engine.History(limit)
+		if err != nil {
+			http.Error(w, "Could not retrieve alert history", http.StatusInternalServerError)
+			log.Printf("Error getting alert history: %v", err)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(events)
+	}
+}
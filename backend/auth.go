@@ -0,0 +1,36 @@
+package main
+
+import (
+	"macos-monitor/backend-go/auth"
+	"macos-monitor/backend-go/config"
+)
+
+// newAuthenticator builds the authenticator from the configured tokens,
+// users and client certificates.
+func newAuthenticator(cfg config.AuthConfig) (*auth.Authenticator, error) {
+	var tokens []auth.Token
+	for _, t := range cfg.Tokens {
+		scope, err := auth.ParseScope(t.Scope)
+		if err != nil {
+			return nil, err
+		}
+		tokens = append(tokens, auth.Token{Name: t.Name, Secret: t.Token, Scope: scope})
+	}
+	var users []auth.User
+	for _, u := range cfg.Users {
+		scope, err := auth.ParseScope(u.Scope)
+		if err != nil {
+			return nil, err
+		}
+		users = append(users, auth.User{Name: u.Name, PasswordHash: u.PasswordHash, Scope: scope})
+	}
+	var clientCerts []auth.ClientCert
+	for _, c := range cfg.ClientCerts {
+		scope, err := auth.ParseScope(c.Scope)
+		if err != nil {
+			return nil, err
+		}
+		clientCerts = append(clientCerts, auth.ClientCert{CommonName: c.CommonName, Scope: scope})
+	}
+	return auth.New(tokens, users, clientCerts)
+}
@@ -0,0 +1,221 @@
+// Package auth authenticates API requests with static tokens, HTTP Basic
+// credentials or TLS client certificates, checks their scopes, and restricts
+// cross-origin access.
+package auth
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/gorilla/websocket"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Scope is what an identity may do.
+type Scope string
+
+const (
+	// ScopeRead allows reading every statistic.
+	ScopeRead Scope = "read"
+	// ScopeAdmin additionally allows controlling processes and reading
+	// process environments.
+	ScopeAdmin Scope = "admin"
+)
+
+// ParseScope validates a scope name. An empty name means ScopeRead.
+func ParseScope(s string) (Scope, error) {
+	switch scope := Scope(s); scope {
+	case "":
+		return ScopeRead, nil
+	case ScopeRead, ScopeAdmin:
+		return scope, nil
+	}
+	return "", fmt.Errorf("unknown scope '%s'", s)
+}
+
+// ErrUnauthenticated is returned for requests without valid credentials.
+var ErrUnauthenticated = errors.New("missing or invalid credentials")
+
+// Identity is the authenticated sender of a request.
+type Identity struct {
+	Name  string
+	Scope Scope
+}
+
+// Allows reports whether the identity has scope.
+func (id Identity) Allows(scope Scope) bool {
+	return id.Scope == ScopeAdmin || id.Scope == scope
+}
+
+// anonymous is the identity of every request while no credentials are
+// configured.
+var anonymous = Identity{Name: "anonymous", Scope: ScopeRead}
+
+// Token is a static API token, sent as "Authorization: Bearer <secret>" or,
+// on WebSocket upgrades since browsers cannot set their headers, as the
+// "token" query parameter.
+type Token struct {
+	Name   string
+	Secret string
+	Scope  Scope
+}
+
+// User is an HTTP Basic user with a bcrypt password hash.
+type User struct {
+	Name         string
+	PasswordHash string
+	Scope        Scope
+}
+
+// ClientCert maps the common name of verified TLS client certificates to a
+// scope.
+type ClientCert struct {
+	CommonName string
+	Scope      Scope
+}
+
+type contextKey struct{}
+
+// Authenticator checks the credentials of requests: verified TLS client
+// certificates, HTTP Basic users and tokens, in that order.
+type Authenticator struct {
+	tokens      []Token
+	users       map[string]User
+	clientCerts map[string]Scope
+
+	// SHA-256 of the last password verified per user, so clients sending
+	// Basic credentials with every request do not pay for bcrypt each time
+	mu       sync.Mutex
+	verified map[string][sha256.Size]byte
+}
+
+// New creates an Authenticator. Without any credentials, every request is
+// accepted as an anonymous reader.
+func New(tokens []Token, users []User, clientCerts []ClientCert) (*Authenticator, error) {
+	a := &Authenticator{
+		tokens:      tokens,
+		users:       make(map[string]User),
+		clientCerts: make(map[string]Scope),
+		verified:    make(map[string][sha256.Size]byte),
+	}
+	for _, c := range clientCerts {
+		a.clientCerts[c.CommonName] = c.Scope
+	}
+	for _, u := range users {
+		if _, err := bcrypt.Cost([]byte(u.PasswordHash)); err != nil {
+			return nil, fmt.Errorf("invalid password hash of user '%s': %w", u.Name, err)
+		}
+		a.users[u.Name] = u
+	}
+	return a, nil
+}
+
+// Enabled reports whether any credentials are configured.
+func (a *Authenticator) Enabled() bool {
+	return len(a.tokens) > 0 || len(a.users) > 0 || len(a.clientCerts) > 0
+}
+
+// Authenticate returns the identity of the sender of r, or
+// ErrUnauthenticated.
+func (a *Authenticator) Authenticate(r *http.Request) (Identity, error) {
+	if !a.Enabled() {
+		return anonymous, nil
+	}
+	// The TLS handshake has already verified the chain against the client
+	// CA.
+	if r.TLS != nil && len(r.TLS.VerifiedChains) > 0 {
+		cn := r.TLS.VerifiedChains[0][0].Subject.CommonName
+		if scope, ok := a.clientCerts[cn]; ok {
+			return Identity{Name: cn, Scope: scope}, nil
+		}
+	}
+	if name, password, ok := r.BasicAuth(); ok {
+		return a.checkUser(name, password)
+	}
+	if secret, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer "); ok {
+		return a.checkToken(secret)
+	}
+	// Anywhere else the query would put the secret into access logs and
+	// browser history
+	if websocket.IsWebSocketUpgrade(r) {
+		if secret := r.URL.Query().Get("token"); secret != "" {
+			return a.checkToken(secret)
+		}
+	}
+	return Identity{}, ErrUnauthenticated
+}
+
+func (a *Authenticator) checkToken(secret string) (Identity, error) {
+	// Compare digests so the comparison time does not depend on the length
+	// of the configured secrets.
+	given := sha256.Sum256([]byte(secret))
+	found := -1
+	for i, t := range a.tokens {
+		want := sha256.Sum256([]byte(t.Secret))
+		if subtle.ConstantTimeCompare(given[:], want[:]) == 1 {
+			found = i
+		}
+	}
+	if found < 0 {
+		return Identity{}, ErrUnauthenticated
+	}
+	return Identity{Name: a.tokens[found].Name, Scope: a.tokens[found].Scope}, nil
+}
+
+func (a *Authenticator) checkUser(name, password string) (Identity, error) {
+	u, ok := a.users[name]
+	if !ok {
+		return Identity{}, ErrUnauthenticated
+	}
+	digest := sha256.Sum256([]byte(password))
+
+	a.mu.Lock()
+	cached, ok := a.verified[name]
+	a.mu.Unlock()
+	if !ok || subtle.ConstantTimeCompare(cached[:], digest[:]) != 1 {
+		if bcrypt.CompareHashAndPassword([]byte(u.PasswordHash), []byte(password)) != nil {
+			return Identity{}, ErrUnauthenticated
+		}
+		a.mu.Lock()
+		a.verified[name] = digest
+		a.mu.Unlock()
+	}
+	return Identity{Name: u.Name, Scope: u.Scope}, nil
+}
+
+// Require passes requests whose sender has scope on to next, with the
+// identity in the request context. Others get a 401 without valid
+// credentials, or a 403 without the scope.
+func (a *Authenticator) Require(scope Scope, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id, err := a.Authenticate(r)
+		if err != nil {
+			w.Header().Add("WWW-Authenticate", `Bearer realm="macos-monitor"`)
+			w.Header().Add("WWW-Authenticate", `Basic realm="macos-monitor", charset="UTF-8"`)
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+		if !id.Allows(scope) {
+			msg := fmt.Sprintf("Forbidden: requires the '%s' scope", scope)
+			if !a.Enabled() {
+				msg += "; configure auth tokens or users to enable it"
+			}
+			http.Error(w, msg, http.StatusForbidden)
+			return
+		}
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), contextKey{}, id)))
+	})
+}
+
+// FromContext returns the identity stored by Require, or an identity
+// without any scope.
+func FromContext(ctx context.Context) Identity {
+	id, _ := ctx.Value(contextKey{}).(Identity)
+	return id
+}
@@ -0,0 +1,75 @@
+package auth
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// Origins is an allowlist of browser origins, such as
+// "http://localhost:5173", for CORS and WebSocket upgrades. "*" allows every
+// origin, without credentials.
+type Origins struct {
+	any     bool
+	allowed map[string]bool
+}
+
+// NewOrigins creates an allowlist of origins.
+func NewOrigins(origins []string) *Origins {
+	o := &Origins{allowed: make(map[string]bool)}
+	for _, origin := range origins {
+		if origin == "*" {
+			o.any = true
+		}
+		o.allowed[strings.ToLower(strings.TrimSuffix(origin, "/"))] = true
+	}
+	return o
+}
+
+// Allowed reports whether r may be served to its origin. Requests without
+// an Origin header, which do not come from a browser page, and same-origin
+// requests are always allowed.
+func (o *Origins) Allowed(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" || o.any || o.allowed[strings.ToLower(origin)] {
+		return true
+	}
+	u, err := url.Parse(origin)
+	return err == nil && strings.EqualFold(u.Host, r.Host)
+}
+
+// CORS answers preflight requests and adds the CORS headers for allowed
+// origins. Reads from other origins are passed on without the headers, so
+// browsers do not expose the responses. Other requests from them are
+// refused, since browsers send simple POSTs without a preflight.
+func (o *Origins) CORS(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		origin := r.Header.Get("Origin")
+		allowed := origin != "" && o.Allowed(r)
+		if allowed {
+			if o.any {
+				// Credentials may not be sent to just any origin
+				w.Header().Set("Access-Control-Allow-Origin", "*")
+			} else {
+				w.Header().Set("Access-Control-Allow-Origin", origin)
+				w.Header().Set("Access-Control-Allow-Credentials", "true")
+			}
+			w.Header().Set("Access-Control-Allow-Methods", "GET, POST, OPTIONS")
+			w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
+		}
+		w.Header().Add("Vary", "Origin")
+		if r.Method == "OPTIONS" && r.Header.Get("Access-Control-Request-Method") != "" {
+			if !allowed {
+				http.Error(w, "Origin not allowed", http.StatusForbidden)
+				return
+			}
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		if origin != "" && !allowed && r.Method != http.MethodGet && r.Method != http.MethodHead {
+			http.Error(w, "Origin not allowed", http.StatusForbidden)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
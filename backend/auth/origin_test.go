@@ -0,0 +1,156 @@
+package auth
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestCORS(t *testing.T) {
+	listed := NewOrigins([]string{"HTTP://LocalHost:5173/"})
+	anyOrigin := NewOrigins([]string{"*"})
+
+	tests := []struct {
+		name        string
+		origins     *Origins
+		method      string
+		origin      string
+		preflight   bool
+		code        int
+		allowOrigin string
+		credentials bool
+	}{
+		{
+			name:    "no origin",
+			origins: listed,
+			method:  http.MethodPost,
+			code:    http.StatusOK,
+		},
+		{
+			name:        "listed origin",
+			origins:     listed,
+			method:      http.MethodPost,
+			origin:      "http://localhost:5173",
+			code:        http.StatusOK,
+			allowOrigin: "http://localhost:5173",
+			credentials: true,
+		},
+		{
+			name:        "same origin",
+			origins:     listed,
+			method:      http.MethodPost,
+			origin:      "https://monitor.local:8000",
+			code:        http.StatusOK,
+			allowOrigin: "https://monitor.local:8000",
+			credentials: true,
+		},
+		{
+			name:    "cross-origin GET",
+			origins: listed,
+			method:  http.MethodGet,
+			origin:  "https://evil.example",
+			code:    http.StatusOK,
+		},
+		{
+			name:    "cross-origin POST",
+			origins: listed,
+			method:  http.MethodPost,
+			origin:  "https://evil.example",
+			code:    http.StatusForbidden,
+		},
+		{
+			name:        "preflight",
+			origins:     listed,
+			method:      http.MethodOptions,
+			origin:      "http://localhost:5173",
+			preflight:   true,
+			code:        http.StatusNoContent,
+			allowOrigin: "http://localhost:5173",
+			credentials: true,
+		},
+		{
+			name:      "cross-origin preflight",
+			origins:   listed,
+			method:    http.MethodOptions,
+			origin:    "https://evil.example",
+			preflight: true,
+			code:      http.StatusForbidden,
+		},
+		{
+			name:        "any origin",
+			origins:     anyOrigin,
+			method:      http.MethodPost,
+			origin:      "https://evil.example",
+			code:        http.StatusOK,
+			allowOrigin: "*",
+		},
+		{
+			name:        "any origin preflight",
+			origins:     anyOrigin,
+			method:      http.MethodOptions,
+			origin:      "https://evil.example",
+			preflight:   true,
+			code:        http.StatusNoContent,
+			allowOrigin: "*",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var served bool
+			handler := tt.origins.CORS(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				served = true
+			}))
+			r := httptest.NewRequest(tt.method, "https://monitor.local:8000/api/processes/1/kill", nil)
+			if tt.origin != "" {
+				r.Header.Set("Origin", tt.origin)
+			}
+			if tt.preflight {
+				r.Header.Set("Access-Control-Request-Method", http.MethodPost)
+			}
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, r)
+
+			if w.Code != tt.code {
+				t.Errorf("status = %d, want %d", w.Code, tt.code)
+			}
+			if want := tt.code == http.StatusOK; served != want {
+				t.Errorf("handler called = %v, want %v", served, want)
+			}
+			if got := w.Header().Get("Access-Control-Allow-Origin"); got != tt.allowOrigin {
+				t.Errorf("Access-Control-Allow-Origin = %q, want %q", got, tt.allowOrigin)
+			}
+			if got := w.Header().Get("Access-Control-Allow-Credentials") == "true"; got != tt.credentials {
+				t.Errorf("Access-Control-Allow-Credentials = %q, want it set: %v", w.Header().Get("Access-Control-Allow-Credentials"), tt.credentials)
+			}
+			if got := w.Header().Get("Vary"); got != "Origin" {
+				t.Errorf("Vary = %q, want Origin", got)
+			}
+		})
+	}
+}
+
+func TestOriginsAllowed(t *testing.T) {
+	o := NewOrigins([]string{"http://localhost:5173", "https://Dashboard.example/"})
+	tests := []struct {
+		origin string
+		want   bool
+	}{
+		{"", true},
+		{"http://localhost:5173", true},
+		{"https://dashboard.example", true},
+		{"http://MONITOR.local:8000", true},
+		{"http://localhost:5174", false},
+		{"https://monitor.local:8001", false},
+		{"https://dashboard.example.evil", false},
+		{"null", false},
+	}
+	for _, tt := range tests {
+		r := httptest.NewRequest(http.MethodGet, "http://monitor.local:8000/ws", nil)
+		if tt.origin != "" {
+			r.Header.Set("Origin", tt.origin)
+		}
+		if got := o.Allowed(r); got != tt.want {
+			t.Errorf("Allowed(%q) = %v, want %v", tt.origin, got, tt.want)
+		}
+	}
+}
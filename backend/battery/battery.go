@@ -0,0 +1,53 @@
+// Package battery reads the charge, state and health of the battery and
+// raises an event when it runs low.
+package battery
+
+// State is what the battery is doing.
+type State string
+
+const (
+	StateCharging    State = "charging"
+	StateDischarging State = "discharging"
+	StateFull        State = "full"
+	// StateNotCharging is a battery on AC power that does not charge, e.g.
+	// because of a charge limit.
+	StateNotCharging State = "not_charging"
+	StateUnknown     State = "unknown"
+)
+
+// PowerSource is what the system runs on.
+type PowerSource string
+
+const (
+	PowerSourceAC      PowerSource = "ac"
+	PowerSourceBattery PowerSource = "battery"
+)
+
+// Status is the state of the battery, or of all batteries combined. Fields
+// the platform does not report are zero or left out.
+type Status struct {
+	// Present is false on systems without a battery; nothing else is set
+	// then except the power source.
+	Present     bool        `json:"present"`
+	Percent     float64     `json:"percent"`
+	State       State       `json:"state"`
+	PowerSource PowerSource `json:"power_source"`
+	// TimeRemainingMin is the time until empty while discharging, or until
+	// full while charging, when it can be estimated.
+	TimeRemainingMin *int `json:"time_remaining_min,omitempty"`
+	CycleCount       int  `json:"cycle_count,omitempty"`
+	// HealthPercent is the full charge capacity in percent of the design
+	// capacity.
+	HealthPercent float64 `json:"health_percent,omitempty"`
+}
+
+// Reader reads the battery status.
+type Reader interface {
+	Read() (Status, error)
+}
+
+// minutes returns a pointer to a duration in minutes, for
+// Status.TimeRemainingMin.
+func minutes(m int) *int {
+	return &m
+}
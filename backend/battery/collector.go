@@ -0,0 +1,167 @@
+package battery
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+
+	"macos-monitor/backend-go/history"
+	"macos-monitor/backend-go/hub"
+)
+
+// Level is how low the charge of a discharging battery is.
+type Level string
+
+const (
+	LevelOK       Level = "ok"
+	LevelLow      Level = "low"
+	LevelCritical Level = "critical"
+)
+
+// levelRank orders the levels so only escalations raise an event.
+var levelRank = map[Level]int{LevelOK: 0, LevelLow: 1, LevelCritical: 2}
+
+// Event is published on the alerts topic when a discharging battery drops
+// to the low or the critical level.
+type Event struct {
+	Timestamp int64  `json:"timestamp"`
+	Level     Level  `json:"level"`
+	Status    Status `json:"battery"`
+}
+
+// Options configures a Collector.
+type Options struct {
+	Interval time.Duration
+	// LowPercent and CriticalPercent are the charges at which a
+	// discharging battery is low or critical.
+	LowPercent      float64
+	CriticalPercent float64
+}
+
+// Collector reads the battery every interval, records its charge and
+// health in the system history and publishes it on the hub.
+type Collector struct {
+	reader Reader
+	store  *history.Store
+	hub    *hub.Hub
+	opts   Options
+
+	// Only accessed by the sampling goroutine
+	failing bool
+	level   Level
+
+	// Called with every set of history samples, registered before Run
+	observers []func(time.Time, []history.Sample)
+
+	mu     sync.RWMutex
+	status Status
+}
+
+// NewCollector creates a Collector that reads the battery with reader.
+func NewCollector(reader Reader, store *history.Store, h *hub.Hub, opts Options) *Collector {
+	return &Collector{reader: reader, store: store, hub: h, opts: opts, level: LevelOK}
+}
+
+// AddObserver registers fn to be called from the sampling goroutine with
+// the history samples of every read. It must be called before Run.
+func (c *Collector) AddObserver(fn func(time.Time, []history.Sample)) {
+	c.observers = append(c.observers, fn)
+}
+
+// Run reads the battery right away and then every interval until ctx is
+// done.
+func (c *Collector) Run(ctx context.Context) {
+	ticker := time.NewTicker(c.opts.Interval)
+	defer ticker.Stop()
+
+	c.collect(time.Now())
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			c.collect(now)
+		}
+	}
+}
+
+// Status returns the battery status of the last read.
+func (c *Collector) Status() Status {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.status
+}
+
+func (c *Collector) collect(now time.Time) {
+	status, err := c.reader.Read()
+	if err != nil {
+		// Log only once while the battery cannot be read
+		if !c.failing {
+			log.Printf("Error reading battery status: %v", err)
+		}
+		c.failing = true
+		// Rather than report the last status as current
+		c.clear()
+		return
+	}
+	c.failing = false
+
+	c.mu.Lock()
+	c.status = status
+	c.mu.Unlock()
+
+	if !status.Present {
+		return
+	}
+	samples := []history.Sample{{Metric: history.MetricBatteryPercent, Value: status.Percent}}
+	if status.HealthPercent > 0 {
+		samples = append(samples, history.Sample{Metric: history.MetricBatteryHealth, Value: status.HealthPercent})
+	}
+	if err := c.store.Insert(now, samples); err != nil {
+		log.Printf("Error recording battery samples: %v", err)
+	}
+	for _, fn := range c.observers {
+		fn(now, samples)
+	}
+	c.hub.Publish(hub.TopicBattery, "battery", "", status)
+	if event, ok := c.checkLevel(now, status); ok {
+		log.Printf("Battery is %s at %.0f%%", event.Level, status.Percent)
+		c.hub.Publish(hub.TopicAlerts, "battery", string(event.Level), event)
+	}
+}
+
+// clear drops the status of the last read and publishes the cleared status
+// once.
+func (c *Collector) clear() {
+	c.mu.Lock()
+	cleared := c.status.Present
+	c.status = Status{}
+	c.mu.Unlock()
+
+	if cleared {
+		c.hub.Publish(hub.TopicBattery, "battery", "", Status{})
+	}
+}
+
+// checkLevel returns an Event when a discharging battery drops to a lower
+// level. Levels start over once the battery stops discharging, so the
+// charge fluctuating around a threshold does not repeat the event.
+func (c *Collector) checkLevel(now time.Time, status Status) (Event, bool) {
+	if status.State != StateDischarging {
+		c.level = LevelOK
+		return Event{}, false
+	}
+	level := LevelOK
+	switch {
+	case status.Percent <= c.opts.CriticalPercent:
+		level = LevelCritical
+	case status.Percent <= c.opts.LowPercent:
+		level = LevelLow
+	}
+	if levelRank[level] <= levelRank[c.level] {
+		return Event{}, false
+	}
+	c.level = level
+	return Event{Timestamp: now.Unix(), Level: level, Status: status}, true
+}
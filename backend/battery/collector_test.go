@@ -0,0 +1,109 @@
+package battery
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	_ "github.com/mattn/go-sqlite3"
+	"macos-monitor/backend-go/history"
+	"macos-monitor/backend-go/hub"
+)
+
+// fakeReader returns its status and error, which tests change between
+// reads.
+type fakeReader struct {
+	status Status
+	err    error
+}
+
+func (r *fakeReader) Read() (Status, error) {
+	return r.status, r.err
+}
+
+func newTestCollector(t *testing.T, reader Reader) *Collector {
+	t.Helper()
+	db, err := sql.Open("sqlite3", filepath.Join(t.TempDir(), "history.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	store, err := history.NewStore(db, history.Retention{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	h := hub.NewHub(hub.TopicBattery, hub.TopicAlerts)
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	go h.Run(ctx)
+	return NewCollector(reader, store, h, Options{LowPercent: 20, CriticalPercent: 10})
+}
+
+func TestCollectorReadError(t *testing.T) {
+	reader := &fakeReader{status: Status{Present: true, Percent: 80, State: StateDischarging, PowerSource: PowerSourceBattery}}
+	c := newTestCollector(t, reader)
+	now := time.Unix(1700000000, 0)
+
+	c.collect(now)
+	if got := c.Status(); got != reader.status {
+		t.Fatalf("Status() = %+v, want %+v", got, reader.status)
+	}
+
+	// A failed read must not leave the last status looking current
+	reader.err = errors.New("pmset: exit status 1")
+	for range 2 {
+		c.collect(now.Add(time.Minute))
+		if got := c.Status(); got != (Status{}) {
+			t.Errorf("Status() after a failed read = %+v, want none", got)
+		}
+	}
+
+	reader.err = nil
+	reader.status.Percent = 79
+	c.collect(now.Add(2 * time.Minute))
+	if got := c.Status(); got != reader.status {
+		t.Errorf("Status() after recovering = %+v, want %+v", got, reader.status)
+	}
+}
+
+func TestCheckLevel(t *testing.T) {
+	c := NewCollector(nil, nil, nil, Options{LowPercent: 20, CriticalPercent: 10})
+	now := time.Unix(1700000000, 0)
+	steps := []struct {
+		percent float64
+		state   State
+		want    Level // "" for no event
+	}{
+		{50, StateDischarging, ""},
+		{20, StateDischarging, LevelLow},
+		// The charge fluctuating around the threshold does not repeat it
+		{21, StateDischarging, ""},
+		{19, StateDischarging, ""},
+		{10, StateDischarging, LevelCritical},
+		{9, StateDischarging, ""},
+		// Charging re-arms the events
+		{12, StateCharging, ""},
+		{11, StateDischarging, LevelLow},
+		{12, StateNotCharging, ""},
+		// Dropping straight to critical skips low
+		{8, StateDischarging, LevelCritical},
+		{100, StateFull, ""},
+		{5, StateUnknown, ""},
+	}
+	for i, step := range steps {
+		status := Status{Present: true, Percent: step.percent, State: step.state}
+		event, ok := c.checkLevel(now, status)
+		switch {
+		case step.want == "" && ok:
+			t.Errorf("step %d (%g%% %s): got a %s event, want none", i, step.percent, step.state, event.Level)
+		case step.want != "" && !ok:
+			t.Errorf("step %d (%g%% %s): got no event, want %s", i, step.percent, step.state, step.want)
+		case ok && (event.Level != step.want || event.Status != status || event.Timestamp != now.Unix()):
+			t.Errorf("step %d: event = %+v, want level %s for %+v", i, event, step.want, status)
+		}
+	}
+}
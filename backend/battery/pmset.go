@@ -0,0 +1,111 @@
+package battery
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"macos-monitor/backend-go/platform"
+)
+
+var (
+	// pmsetBattery matches the battery line of "pmset -g batt", e.g.
+	// " -InternalBattery-0 (id=4653155)	82%; discharging; 5:12 remaining present: true"
+	pmsetBattery = regexp.MustCompile(`^\s*-InternalBattery-\d+.*?\t(\d+)%;\s*([^;]+);\s*(.*)$`)
+	pmsetTime    = regexp.MustCompile(`(\d+):(\d{2}) remaining`)
+	// ioregValue matches a numeric property of ioreg, e.g.
+	// `    "CycleCount" = 412`
+	ioregValue = regexp.MustCompile(`^\s*"(\w+)" = (\d+)\s*$`)
+)
+
+// PmsetReader reads the battery on macOS from "pmset -g batt" and the
+// AppleSmartBattery entry of the I/O Kit registry.
+type PmsetReader struct {
+	Run platform.Runner
+}
+
+func (r PmsetReader) Read() (Status, error) {
+	out, err := r.Run("pmset", "-g", "batt")
+	if err != nil {
+		return Status{}, fmt.Errorf("failed to run pmset: %w", err)
+	}
+	status, err := parsePmset(out)
+	if err != nil || !status.Present {
+		return status, err
+	}
+	// Cycle count and health are only in the registry
+	if out, err := r.Run("ioreg", "-r", "-c", "AppleSmartBattery"); err == nil {
+		parseIoreg(out, &status)
+	}
+	return status, nil
+}
+
+// parsePmset parses the output of "pmset -g batt".
+func parsePmset(out []byte) (Status, error) {
+	var status Status
+	scanner := bufio.NewScanner(bytes.NewReader(out))
+	for scanner.Scan() {
+		line := scanner.Text()
+		if source, ok := strings.CutPrefix(line, "Now drawing from "); ok {
+			if strings.Contains(source, "Battery Power") {
+				status.PowerSource = PowerSourceBattery
+			} else {
+				status.PowerSource = PowerSourceAC
+			}
+			continue
+		}
+		match := pmsetBattery.FindStringSubmatch(line)
+		if match == nil {
+			continue
+		}
+		status.Present = true
+		status.Percent, _ = strconv.ParseFloat(match[1], 64)
+		switch strings.TrimSpace(match[2]) {
+		case "charging", "finishing charge":
+			status.State = StateCharging
+		case "discharging":
+			status.State = StateDischarging
+		case "charged":
+			status.State = StateFull
+		case "AC attached":
+			status.State = StateNotCharging
+		default:
+			status.State = StateUnknown
+		}
+		// "(no estimate)" while the estimate is calculated, and 0:00 once
+		// charged
+		if t := pmsetTime.FindStringSubmatch(match[3]); t != nil && status.State != StateFull {
+			hours, _ := strconv.Atoi(t[1])
+			mins, _ := strconv.Atoi(t[2])
+			status.TimeRemainingMin = minutes(hours*60 + mins)
+		}
+	}
+	if status.PowerSource == "" {
+		return Status{}, fmt.Errorf("unexpected pmset output: %q", out)
+	}
+	return status, nil
+}
+
+// parseIoreg adds the cycle count and health from the AppleSmartBattery
+// registry entry. Apple silicon reports MaxCapacity in percent and the
+// capacity in mAh as AppleRawMaxCapacity.
+func parseIoreg(out []byte, status *Status) {
+	values := make(map[string]float64)
+	scanner := bufio.NewScanner(bytes.NewReader(out))
+	for scanner.Scan() {
+		if match := ioregValue.FindStringSubmatch(scanner.Text()); match != nil {
+			values[match[1]], _ = strconv.ParseFloat(match[2], 64)
+		}
+	}
+	status.CycleCount = int(values["CycleCount"])
+	maxCapacity, ok := values["AppleRawMaxCapacity"]
+	if !ok {
+		maxCapacity = values["MaxCapacity"]
+	}
+	if design := values["DesignCapacity"]; design > 0 && maxCapacity > 100 {
+		status.HealthPercent = maxCapacity / design * 100
+	}
+}
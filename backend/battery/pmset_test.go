@@ -0,0 +1,189 @@
+package battery
+
+import (
+	"errors"
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestParsePmset(t *testing.T) {
+	tests := []struct {
+		name      string
+		out       string
+		want      Status
+		remaining int // minutes, -1 for no estimate
+	}{
+		{
+			name: "discharging",
+			out: "Now drawing from 'Battery Power'\n" +
+				" -InternalBattery-0 (id=4653155)\t82%; discharging; 5:12 remaining present: true\n",
+			want:      Status{Present: true, Percent: 82, State: StateDischarging, PowerSource: PowerSourceBattery},
+			remaining: 312,
+		},
+		{
+			name: "charging",
+			out: "Now drawing from 'AC Power'\n" +
+				" -InternalBattery-0 (id=4653155)\t45%; charging; 1:30 remaining present: true\n",
+			want:      Status{Present: true, Percent: 45, State: StateCharging, PowerSource: PowerSourceAC},
+			remaining: 90,
+		},
+		{
+			name: "finishing charge",
+			out: "Now drawing from 'AC Power'\n" +
+				" -InternalBattery-0 (id=4653155)\t99%; finishing charge; 0:05 remaining present: true\n",
+			want:      Status{Present: true, Percent: 99, State: StateCharging, PowerSource: PowerSourceAC},
+			remaining: 5,
+		},
+		{
+			name: "charged",
+			out: "Now drawing from 'AC Power'\n" +
+				" -InternalBattery-0 (id=4653155)\t100%; charged; 0:00 remaining present: true\n",
+			want:      Status{Present: true, Percent: 100, State: StateFull, PowerSource: PowerSourceAC},
+			remaining: -1,
+		},
+		{
+			name: "AC attached",
+			out: "Now drawing from 'AC Power'\n" +
+				" -InternalBattery-0 (id=4653155)\t80%; AC attached; not charging present: true\n",
+			want:      Status{Present: true, Percent: 80, State: StateNotCharging, PowerSource: PowerSourceAC},
+			remaining: -1,
+		},
+		{
+			name: "no estimate",
+			out: "Now drawing from 'Battery Power'\n" +
+				" -InternalBattery-0 (id=4653155)\t97%; discharging; (no estimate) present: true\n",
+			want:      Status{Present: true, Percent: 97, State: StateDischarging, PowerSource: PowerSourceBattery},
+			remaining: -1,
+		},
+		{
+			name:      "no battery",
+			out:       "Now drawing from 'AC Power'\n",
+			want:      Status{PowerSource: PowerSourceAC},
+			remaining: -1,
+		},
+		{
+			name:      "UPS",
+			out:       "Now drawing from 'UPS Power'\n -CP1500PFCLCD (id=123)\t100%; charged; 0:00 remaining present: true\n",
+			want:      Status{PowerSource: PowerSourceAC},
+			remaining: -1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parsePmset([]byte(tt.out))
+			if err != nil {
+				t.Fatalf("parsePmset: %v", err)
+			}
+			checkRemaining(t, got.TimeRemainingMin, tt.remaining)
+			got.TimeRemainingMin = nil
+			if got != tt.want {
+				t.Errorf("parsePmset() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParsePmsetInvalid(t *testing.T) {
+	if _, err := parsePmset([]byte("pmset: command failed\n")); err == nil {
+		t.Error("parsePmset returned no error for unexpected output")
+	}
+}
+
+func checkRemaining(t *testing.T, got *int, want int) {
+	t.Helper()
+	switch {
+	case want < 0 && got != nil:
+		t.Errorf("time remaining = %d, want none", *got)
+	case want >= 0 && got == nil:
+		t.Errorf("time remaining = none, want %d", want)
+	case want >= 0 && *got != want:
+		t.Errorf("time remaining = %d, want %d", *got, want)
+	}
+}
+
+const ioregIntel = `+-o AppleSmartBattery  <class AppleSmartBattery, id 0x100000254, registered, matched, active, busy 0 (0 ms), retain 6>
+    {
+      "TimeRemaining" = 312
+      "AvgTimeToEmpty" = 312
+      "CycleCount" = 412
+      "DesignCapacity" = 6669
+      "MaxCapacity" = 5602
+      "CurrentCapacity" = 4594
+      "IsCharging" = No
+      "DeviceName" = "bq20z451"
+    }
+`
+
+const ioregAppleSilicon = `+-o AppleSmartBattery  <class AppleSmartBattery, id 0x100000b2d, registered, matched, active, busy 0 (0 ms), retain 8>
+    {
+      "AppleRawCurrentCapacity" = 3810
+      "CycleCount" = 87
+      "DesignCapacity" = 4382
+      "MaxCapacity" = 100
+      "AppleRawMaxCapacity" = 4163
+      "CurrentCapacity" = 82
+      "BatteryData" = {"CycleCount"=87,"DesignCapacity"=4382}
+    }
+`
+
+func TestParseIoreg(t *testing.T) {
+	tests := []struct {
+		name   string
+		out    string
+		cycles int
+		health float64
+	}{
+		{"intel", ioregIntel, 412, 5602.0 / 6669 * 100},
+		{"apple silicon", ioregAppleSilicon, 87, 4163.0 / 4382 * 100},
+		{"no capacities", "      \"CycleCount\" = 3\n", 3, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var status Status
+			parseIoreg([]byte(tt.out), &status)
+			if status.CycleCount != tt.cycles {
+				t.Errorf("cycle count = %d, want %d", status.CycleCount, tt.cycles)
+			}
+			if math.Abs(status.HealthPercent-tt.health) > 1e-9 {
+				t.Errorf("health = %g, want %g", status.HealthPercent, tt.health)
+			}
+		})
+	}
+}
+
+// recorded returns recorded output by command name from its run method, a
+// platform.Runner.
+type recorded map[string]string
+
+func (r recorded) run(name string, args ...string) ([]byte, error) {
+	out, ok := r[name]
+	if !ok {
+		return nil, errors.New(name + ": not found")
+	}
+	return []byte(out), nil
+}
+
+func TestPmsetReader(t *testing.T) {
+	pmset := "Now drawing from 'Battery Power'\n" +
+		" -InternalBattery-0 (id=4653155)\t82%; discharging; 5:12 remaining present: true\n"
+
+	status, err := PmsetReader{Run: recorded{"pmset": pmset, "ioreg": ioregAppleSilicon}.run}.Read()
+	if err != nil {
+		t.Fatalf("Read: %v", err)
+	}
+	if !status.Present || status.Percent != 82 || status.CycleCount != 87 || status.HealthPercent < 95 || status.HealthPercent > 95.1 {
+		t.Errorf("Read() = %+v, want the pmset status with the ioreg cycles and health", status)
+	}
+
+	// The registry is optional
+	status, err = PmsetReader{Run: recorded{"pmset": pmset}.run}.Read()
+	if err != nil || !status.Present || status.CycleCount != 0 {
+		t.Errorf("Read() without ioreg = %+v, %v, want the pmset status", status, err)
+	}
+
+	_, err = PmsetReader{Run: recorded{}.run}.Read()
+	if err == nil || !strings.Contains(err.Error(), "pmset") {
+		t.Errorf("Read() without pmset = %v, want an error", err)
+	}
+}
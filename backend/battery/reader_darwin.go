@@ -0,0 +1,10 @@
+//go:build darwin
+
+package battery
+
+import "macos-monitor/backend-go/platform"
+
+// NewReader returns the Reader of the platform.
+func NewReader() Reader {
+	return PmsetReader{Run: platform.ExecRunner}
+}
@@ -0,0 +1,8 @@
+//go:build linux
+
+package battery
+
+// NewReader returns the Reader of the platform.
+func NewReader() Reader {
+	return SysfsReader{Dir: "/sys/class/power_supply"}
+}
@@ -0,0 +1,14 @@
+//go:build !darwin && !linux
+
+package battery
+
+// NewReader returns the Reader of the platform, which reports no battery.
+func NewReader() Reader {
+	return noBattery{}
+}
+
+type noBattery struct{}
+
+func (noBattery) Read() (Status, error) {
+	return Status{PowerSource: PowerSourceAC}, nil
+}
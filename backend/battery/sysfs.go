@@ -0,0 +1,163 @@
+package battery
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// SysfsReader reads the Linux power supply class, usually
+// /sys/class/power_supply. Several batteries are combined into one, and
+// batteries of peripherals such as mice are left out.
+type SysfsReader struct {
+	Dir string
+}
+
+// supply holds the readings of one battery, in its own units: µWh and µW,
+// or µAh and µA.
+type supply struct {
+	capacity           float64
+	status             string
+	now, full, design  float64
+	rate               float64
+	cycleCount         int
+	hasCapacity        bool
+	hasEnergy, hasRate bool
+}
+
+func (r SysfsReader) Read() (Status, error) {
+	entries, err := os.ReadDir(r.Dir)
+	if err != nil {
+		return Status{}, err
+	}
+
+	var batteries []supply
+	onAC, hasMains := false, false
+	for _, entry := range entries {
+		dir := filepath.Join(r.Dir, entry.Name())
+		switch readString(dir, "type") {
+		case "Mains", "USB":
+			hasMains = true
+			if readString(dir, "online") == "1" {
+				onAC = true
+			}
+		case "Battery":
+			if readString(dir, "scope") == "Device" || readString(dir, "present") == "0" {
+				continue
+			}
+			batteries = append(batteries, readSupply(dir))
+		}
+	}
+
+	status := Status{PowerSource: PowerSourceBattery, State: StateUnknown}
+	if onAC {
+		status.PowerSource = PowerSourceAC
+	}
+	if len(batteries) == 0 {
+		status.PowerSource = PowerSourceAC
+		status.State = ""
+		return status, nil
+	}
+	status.Present = true
+	combine(&status, batteries)
+	if !hasMains && status.State != StateDischarging {
+		status.PowerSource = PowerSourceAC
+	}
+	return status, nil
+}
+
+func readSupply(dir string) supply {
+	s := supply{status: readString(dir, "status")}
+	if capacity, err := readFloat(dir, "capacity"); err == nil {
+		s.capacity, s.hasCapacity = capacity, true
+	}
+	if cycles, err := readFloat(dir, "cycle_count"); err == nil {
+		s.cycleCount = int(cycles)
+	}
+	// Batteries report either energy and power, or charge and current
+	for _, names := range [][4]string{
+		{"energy_now", "energy_full", "energy_full_design", "power_now"},
+		{"charge_now", "charge_full", "charge_full_design", "current_now"},
+	} {
+		now, err := readFloat(dir, names[0])
+		if err != nil {
+			continue
+		}
+		s.now, s.hasEnergy = now, true
+		s.full, _ = readFloat(dir, names[1])
+		s.design, _ = readFloat(dir, names[2])
+		if rate, err := readFloat(dir, names[3]); err == nil && rate != 0 {
+			// Some drivers report the discharge rate as negative
+			s.rate, s.hasRate = max(rate, -rate), true
+		}
+		break
+	}
+	return s
+}
+
+// combine sets the status of all batteries together: the charge and health
+// from their summed capacities where known, the state charging or
+// discharging if any battery is.
+func combine(status *Status, batteries []supply) {
+	var now, full, design, rate, capacity float64
+	energyKnown, rateKnown := true, true
+	states := make(map[string]bool)
+	for _, b := range batteries {
+		now += b.now
+		full += b.full
+		design += b.design
+		rate += b.rate
+		capacity += b.capacity
+		energyKnown = energyKnown && b.hasEnergy && b.full > 0
+		rateKnown = rateKnown && b.hasRate
+		status.CycleCount = max(status.CycleCount, b.cycleCount)
+		states[b.status] = true
+	}
+
+	// The driver's own percentage is the most accurate for one battery
+	switch {
+	case len(batteries) == 1 && batteries[0].hasCapacity:
+		status.Percent = batteries[0].capacity
+	case energyKnown:
+		status.Percent = min(100, now/full*100)
+	default:
+		status.Percent = capacity / float64(len(batteries))
+	}
+	if energyKnown && design > 0 {
+		status.HealthPercent = full / design * 100
+	}
+
+	switch {
+	case states["Charging"]:
+		status.State = StateCharging
+		if energyKnown && rateKnown && full > now {
+			status.TimeRemainingMin = minutes(int((full - now) / rate * 60))
+		}
+	case states["Discharging"]:
+		status.State = StateDischarging
+		if energyKnown && rateKnown {
+			status.TimeRemainingMin = minutes(int(now / rate * 60))
+		}
+	case states["Not charging"]:
+		status.State = StateNotCharging
+	case states["Full"] && len(states) == 1:
+		status.State = StateFull
+	}
+}
+
+func readString(dir, name string) string {
+	data, err := os.ReadFile(filepath.Join(dir, name))
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(data))
+}
+
+func readFloat(dir, name string) (float64, error) {
+	data, err := os.ReadFile(filepath.Join(dir, name))
+	if err != nil {
+		return 0, err
+	}
+	return strconv.ParseFloat(strings.TrimSpace(string(data)), 64)
+}
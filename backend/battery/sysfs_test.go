@@ -0,0 +1,103 @@
+package battery
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// writeSupplies creates a power supply class directory with one directory
+// of attribute files per supply.
+func writeSupplies(t *testing.T, supplies map[string]map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for name, attrs := range supplies {
+		if err := os.MkdirAll(filepath.Join(dir, name), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		for attr, value := range attrs {
+			if err := os.WriteFile(filepath.Join(dir, name, attr), []byte(value+"\n"), 0o644); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	return dir
+}
+
+func TestSysfsReader(t *testing.T) {
+	mouse := map[string]string{"type": "Battery", "scope": "Device", "status": "Discharging", "capacity": "5"}
+	tests := []struct {
+		name      string
+		supplies  map[string]map[string]string
+		want      Status
+		remaining int // minutes, -1 for no estimate
+	}{
+		{
+			name: "discharging with energy",
+			supplies: map[string]map[string]string{
+				"AC":   {"type": "Mains", "online": "0"},
+				"BAT0": {"type": "Battery", "status": "Discharging", "capacity": "60", "cycle_count": "120", "energy_now": "30000000", "energy_full": "50000000", "energy_full_design": "62500000", "power_now": "10000000"},
+				// Peripherals are left out
+				"hidpp_battery_0": mouse,
+			},
+			want:      Status{Present: true, Percent: 60, State: StateDischarging, PowerSource: PowerSourceBattery, CycleCount: 120, HealthPercent: 80},
+			remaining: 180,
+		},
+		{
+			name: "charging with charge and negative current",
+			supplies: map[string]map[string]string{
+				"ADP1": {"type": "Mains", "online": "1"},
+				"BAT0": {"type": "Battery", "status": "Charging", "charge_now": "2000000", "charge_full": "4000000", "charge_full_design": "4000000", "current_now": "-1000000"},
+			},
+			want:      Status{Present: true, Percent: 50, State: StateCharging, PowerSource: PowerSourceAC, HealthPercent: 100},
+			remaining: 120,
+		},
+		{
+			name: "two batteries combined",
+			supplies: map[string]map[string]string{
+				"AC":   {"type": "Mains", "online": "1"},
+				"BAT0": {"type": "Battery", "status": "Full", "capacity": "100", "energy_now": "20000000", "energy_full": "20000000", "energy_full_design": "25000000"},
+				"BAT1": {"type": "Battery", "status": "Not charging", "capacity": "50", "energy_now": "10000000", "energy_full": "20000000", "energy_full_design": "25000000", "cycle_count": "7"},
+			},
+			want:      Status{Present: true, Percent: 75, State: StateNotCharging, PowerSource: PowerSourceAC, CycleCount: 7, HealthPercent: 80},
+			remaining: -1,
+		},
+		{
+			name: "full without mains supply",
+			supplies: map[string]map[string]string{
+				"BAT0": {"type": "Battery", "status": "Full", "capacity": "100"},
+			},
+			want:      Status{Present: true, Percent: 100, State: StateFull, PowerSource: PowerSourceAC},
+			remaining: -1,
+		},
+		{
+			name: "no battery",
+			supplies: map[string]map[string]string{
+				"AC":              {"type": "Mains", "online": "1"},
+				"BAT0":            {"type": "Battery", "present": "0"},
+				"hidpp_battery_0": mouse,
+			},
+			want:      Status{PowerSource: PowerSourceAC},
+			remaining: -1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := SysfsReader{Dir: writeSupplies(t, tt.supplies)}.Read()
+			if err != nil {
+				t.Fatalf("Read: %v", err)
+			}
+			checkRemaining(t, got.TimeRemainingMin, tt.remaining)
+			got.TimeRemainingMin = nil
+			if got != tt.want {
+				t.Errorf("Read() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSysfsReaderMissingDir(t *testing.T) {
+	if _, err := (SysfsReader{Dir: filepath.Join(t.TempDir(), "missing")}).Read(); err == nil {
+		t.Error("Read returned no error for a missing directory")
+	}
+}
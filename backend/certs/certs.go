@@ -0,0 +1,239 @@
+// Package certs provides the TLS configuration of the server, including a
+// persisted self-signed certificate for when none is configured.
+package certs
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"net"
+	"os"
+	"slices"
+	"strings"
+	"time"
+)
+
+// selfSignedValidity is the lifetime of generated certificates, the longest
+// macOS accepts for TLS server certificates.
+const selfSignedValidity = 825 * 24 * time.Hour
+
+// renewBefore is how long before expiry a generated certificate is
+// replaced.
+const renewBefore = 30 * 24 * time.Hour
+
+// ClientAuth selects whether clients must present a certificate.
+type ClientAuth string
+
+const (
+	// ClientAuthNone does not ask for client certificates.
+	ClientAuthNone ClientAuth = "none"
+	// ClientAuthRequest verifies client certificates that are presented.
+	ClientAuthRequest ClientAuth = "request"
+	// ClientAuthRequire rejects connections without a valid client
+	// certificate.
+	ClientAuthRequire ClientAuth = "require"
+)
+
+// Options configures the server's TLS.
+type Options struct {
+	// CertFile and KeyFile hold the PEM certificate chain and key. When
+	// SelfSigned is set they are generated if missing or about to expire.
+	CertFile   string
+	KeyFile    string
+	SelfSigned bool
+	// Hosts are extra DNS names or IPs for a generated certificate, on top
+	// of localhost, the hostname and the local addresses.
+	Hosts []string
+
+	// ClientCAFile holds the PEM certificates client certificates are
+	// verified against.
+	ClientCAFile string
+	ClientAuth   ClientAuth
+}
+
+// ServerConfig returns the TLS configuration of the server.
+func ServerConfig(opts Options) (*tls.Config, error) {
+	if opts.SelfSigned {
+		if err := ensureSelfSigned(opts.CertFile, opts.KeyFile, opts.Hosts); err != nil {
+			return nil, err
+		}
+	}
+	cert, err := tls.LoadX509KeyPair(opts.CertFile, opts.KeyFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load TLS certificate: %w", err)
+	}
+
+	cfg := &tls.Config{
+		MinVersion:   tls.VersionTLS12,
+		Certificates: []tls.Certificate{cert},
+	}
+	if opts.ClientCAFile == "" {
+		return cfg, nil
+	}
+
+	pemCerts, err := os.ReadFile(opts.ClientCAFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read client CA file: %w", err)
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(pemCerts) {
+		return nil, fmt.Errorf("no certificates found in client CA file '%s'", opts.ClientCAFile)
+	}
+	cfg.ClientCAs = pool
+	switch opts.ClientAuth {
+	case ClientAuthRequire:
+		cfg.ClientAuth = tls.RequireAndVerifyClientCert
+	case ClientAuthRequest:
+		cfg.ClientAuth = tls.VerifyClientCertIfGiven
+	}
+	return cfg, nil
+}
+
+// Fingerprint returns the SHA-256 fingerprint of the first certificate in
+// certFile, for clients that pin a self-signed certificate.
+func Fingerprint(certFile string) (string, error) {
+	cert, err := readCertificate(certFile)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(cert.Raw)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// ensureSelfSigned generates a self-signed certificate unless certFile
+// already holds one that is not about to expire and covers localhost and
+// hosts. Local addresses are not required, so an address that comes and
+// goes with a network does not replace a certificate clients have trusted.
+func ensureSelfSigned(certFile, keyFile string, hosts []string) error {
+	cert, err := readCertificate(certFile)
+	if err == nil && time.Until(cert.NotAfter) > renewBefore && covers(cert, append(localNames(), hosts...)) {
+		return nil
+	}
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return fmt.Errorf("failed to generate TLS key: %w", err)
+	}
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return fmt.Errorf("failed to generate certificate serial: %w", err)
+	}
+
+	now := time.Now()
+	template := x509.Certificate{
+		SerialNumber:          serial,
+		Subject:               pkix.Name{CommonName: "macos-monitor", Organization: []string{"macos-monitor self-signed"}},
+		NotBefore:             now.Add(-time.Hour),
+		NotAfter:              now.Add(selfSignedValidity),
+		KeyUsage:              x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		BasicConstraintsValid: true,
+	}
+	for _, host := range subjectAltNames(hosts) {
+		if ip := net.ParseIP(host); ip != nil {
+			template.IPAddresses = append(template.IPAddresses, ip)
+		} else {
+			template.DNSNames = append(template.DNSNames, host)
+		}
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, &template, &template, &key.PublicKey, key)
+	if err != nil {
+		return fmt.Errorf("failed to create self-signed certificate: %w", err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		return fmt.Errorf("failed to encode TLS key: %w", err)
+	}
+
+	if err := writePEM(keyFile, "EC PRIVATE KEY", keyDER, 0o600); err != nil {
+		return err
+	}
+	return writePEM(certFile, "CERTIFICATE", der, 0o644)
+}
+
+// localNames are the loopback names every generated certificate is valid
+// for.
+func localNames() []string {
+	return []string{"localhost", "127.0.0.1", "::1"}
+}
+
+// subjectAltNames returns the names a generated certificate is valid for.
+func subjectAltNames(extra []string) []string {
+	names := localNames()
+	if hostname, err := os.Hostname(); err == nil && hostname != "" {
+		names = append(names, hostname)
+	}
+	if addrs, err := net.InterfaceAddrs(); err == nil {
+		for _, addr := range addrs {
+			if ipNet, ok := addr.(*net.IPNet); ok && !ipNet.IP.IsLoopback() && !ipNet.IP.IsLinkLocalUnicast() {
+				names = append(names, ipNet.IP.String())
+			}
+		}
+	}
+	names = append(names, extra...)
+
+	seen := make(map[string]bool)
+	unique := names[:0]
+	for _, name := range names {
+		if !seen[name] {
+			seen[name] = true
+			unique = append(unique, name)
+		}
+	}
+	return unique
+}
+
+// covers reports whether cert is valid for every name of names.
+func covers(cert *x509.Certificate, names []string) bool {
+	for _, name := range names {
+		if ip := net.ParseIP(name); ip != nil {
+			if !slices.ContainsFunc(cert.IPAddresses, ip.Equal) {
+				return false
+			}
+		} else if !slices.ContainsFunc(cert.DNSNames, func(dns string) bool { return strings.EqualFold(dns, name) }) {
+			return false
+		}
+	}
+	return true
+}
+
+func readCertificate(certFile string) (*x509.Certificate, error) {
+	data, err := os.ReadFile(certFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read TLS certificate: %w", err)
+	}
+	block, _ := pem.Decode(data)
+	if block == nil || block.Type != "CERTIFICATE" {
+		return nil, fmt.Errorf("no certificate found in '%s'", certFile)
+	}
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse TLS certificate: %w", err)
+	}
+	return cert, nil
+}
+
+func writePEM(path, blockType string, der []byte, perm os.FileMode) error {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, perm)
+	if err != nil {
+		return fmt.Errorf("failed to create '%s': %w", path, err)
+	}
+	if err := pem.Encode(f, &pem.Block{Type: blockType, Bytes: der}); err != nil {
+		f.Close()
+		return fmt.Errorf("failed to write '%s': %w", path, err)
+	}
+	return f.Close()
+}
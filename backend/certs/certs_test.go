@@ -0,0 +1,39 @@
+package certs
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestEnsureSelfSigned(t *testing.T) {
+	dir := t.TempDir()
+	certFile := filepath.Join(dir, "cert.pem")
+	keyFile := filepath.Join(dir, "key.pem")
+	fingerprint := func(hosts []string) string {
+		t.Helper()
+		if err := ensureSelfSigned(certFile, keyFile, hosts); err != nil {
+			t.Fatal(err)
+		}
+		fp, err := Fingerprint(certFile)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return fp
+	}
+
+	first := fingerprint([]string{"monitor.lan"})
+	if fp := fingerprint([]string{"monitor.lan"}); fp != first {
+		t.Errorf("certificate was regenerated for an unchanged config")
+	}
+	added := fingerprint([]string{"monitor.lan", "10.9.8.7"})
+	if added == first {
+		t.Errorf("certificate was reused without a newly configured host")
+	}
+	cert, err := readCertificate(certFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !covers(cert, []string{"localhost", "::1", "MONITOR.lan", "10.9.8.7"}) {
+		t.Errorf("certificate names = %v %v, want localhost and the hosts", cert.DNSNames, cert.IPAddresses)
+	}
+}
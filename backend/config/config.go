@@ -0,0 +1,781 @@
+// Package config loads the backend configuration from a YAML file,
+// environment variables and command line flags.
+package config
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"os"
+	"path"
+	"slices"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// envPrefix is prepended to every environment variable override, e.g.
+// MACOS_MONITOR_SERVER_LISTEN overrides server.listen.
+const envPrefix = "MACOS_MONITOR"
+
+// minTokenLength is the minimum length of an API token.
+const minTokenLength = 16
+
+// Config is the complete backend configuration.
+type Config struct {
+	Server    ServerConfig    `yaml:"server"`
+	Database  DatabaseConfig  `yaml:"database"`
+	Network   NetworkConfig   `yaml:"network"`
+	History   HistoryConfig   `yaml:"history"`
+	CPU       CPUConfig       `yaml:"cpu"`
+	Memory    MemoryConfig    `yaml:"memory"`
+	Load      LoadConfig      `yaml:"load"`
+	Sensors   SensorsConfig   `yaml:"sensors"`
+	Battery   BatteryConfig   `yaml:"battery"`
+	Disks     DisksConfig     `yaml:"disks"`
+	Processes ProcessesConfig `yaml:"processes"`
+	Auth      AuthConfig      `yaml:"auth"`
+	Control   ControlConfig   `yaml:"control"`
+	Quotas    []QuotaConfig   `yaml:"quotas"`
+	Alerts    AlertsConfig    `yaml:"alerts"`
+}
+
+// ServerConfig configures the HTTP server.
+type ServerConfig struct {
+	Listen string `yaml:"listen"`
+	// PushInterval is how often system and process data are pushed to
+	// WebSocket subscribers.
+	PushInterval Duration  `yaml:"push_interval"`
+	TLS          TLSConfig `yaml:"tls"`
+}
+
+// TLSConfig configures HTTPS. Without a certificate and key, a self-signed
+// certificate is generated next to the database and reused.
+type TLSConfig struct {
+	Enabled  bool   `yaml:"enabled"`
+	CertFile string `yaml:"cert_file"`
+	KeyFile  string `yaml:"key_file"`
+	// Hosts are extra names or IPs the generated certificate is valid for.
+	Hosts []string `yaml:"hosts"`
+	// ClientCAFile enables client certificate verification against the
+	// CAs in the file.
+	ClientCAFile string `yaml:"client_ca_file"`
+	// ClientAuth is "none", "request" (verify certificates that are sent)
+	// or "require". It defaults to "require" with a client CA file.
+	ClientAuth string `yaml:"client_auth"`
+}
+
+// DatabaseConfig configures the SQLite database.
+type DatabaseConfig struct {
+	Path string `yaml:"path"`
+}
+
+// NetworkConfig configures the network monitor.
+type NetworkConfig struct {
+	// Interfaces restricts monitoring to the listed interfaces. When empty,
+	// every non-loopback interface is monitored.
+	Interfaces          []string `yaml:"interfaces"`
+	SampleInterval      Duration `yaml:"sample_interval"`
+	PersistenceInterval Duration `yaml:"persistence_interval"`
+	MaxSleepInterval    Duration `yaml:"max_sleep_interval"`
+	MovingAverageWindow int      `yaml:"moving_average_window"`
+	HourlyPoints        int      `yaml:"hourly_points"`
+	HourlyInterval      Duration `yaml:"hourly_interval"`
+
+	Processes NetworkProcessesConfig `yaml:"processes"`
+}
+
+// NetworkProcessesConfig configures the attribution of network usage to
+// processes.
+type NetworkProcessesConfig struct {
+	SampleInterval Duration `yaml:"sample_interval"`
+}
+
+// HistoryConfig configures the background system sampler and how long each
+// level of its history is kept. A zero retention keeps a level forever.
+type HistoryConfig struct {
+	SampleInterval  Duration `yaml:"sample_interval"`
+	RawRetention    Duration `yaml:"raw_retention"`
+	MinuteRetention Duration `yaml:"minute_retention"`
+	HourRetention   Duration `yaml:"hour_retention"`
+	DayRetention    Duration `yaml:"day_retention"`
+}
+
+// DisksConfig selects the file systems listed by /api/disks and recorded in
+// the history, by glob patterns matched against the mount point, the device
+// and the file system type.
+type DisksConfig struct {
+	// Include, when not empty, lists the only patterns that are monitored.
+	Include []string `yaml:"include"`
+	// Exclude lists patterns that are never monitored. The default leaves
+	// out pseudo file systems and the internal macOS system volumes.
+	Exclude []string     `yaml:"exclude"`
+	IO      DiskIOConfig `yaml:"io"`
+}
+
+// DiskIOConfig configures the disk I/O sampler.
+type DiskIOConfig struct {
+	// Devices restricts sampling to the listed block devices, such as
+	// "disk0" or "nvme0n1". When empty, every whole disk is sampled.
+	Devices             []string `yaml:"devices"`
+	SampleInterval      Duration `yaml:"sample_interval"`
+	MaxSleepInterval    Duration `yaml:"max_sleep_interval"`
+	MovingAverageWindow int      `yaml:"moving_average_window"`
+	HourlyPoints        int      `yaml:"hourly_points"`
+	HourlyInterval      Duration `yaml:"hourly_interval"`
+}
+
+// CPUConfig configures the background CPU sampler.
+type CPUConfig struct {
+	// SampleInterval is the interval over which CPU usage is measured.
+	SampleInterval Duration `yaml:"sample_interval"`
+}
+
+// MemoryConfig configures the background memory sampler.
+type MemoryConfig struct {
+	// SampleInterval is how often memory and swap usage are read; swap
+	// rates are measured over it.
+	SampleInterval Duration `yaml:"sample_interval"`
+}
+
+// LoadConfig configures the load sampler, whose context switch and
+// interrupt rates are smoothed like the network rates.
+type LoadConfig struct {
+	SampleInterval      Duration `yaml:"sample_interval"`
+	MaxSleepInterval    Duration `yaml:"max_sleep_interval"`
+	MovingAverageWindow int      `yaml:"moving_average_window"`
+	HourlyPoints        int      `yaml:"hourly_points"`
+	HourlyInterval      Duration `yaml:"hourly_interval"`
+}
+
+// SensorsConfig configures the temperature sensor collector.
+type SensorsConfig struct {
+	SampleInterval Duration `yaml:"sample_interval"`
+	// HwmonDir reads the sensors from a Linux hwmon tree such as
+	// /sys/class/hwmon instead of through gopsutil, e.g. from a fixture.
+	HwmonDir string `yaml:"hwmon_dir"`
+}
+
+// BatteryConfig configures the battery collector.
+type BatteryConfig struct {
+	SampleInterval Duration `yaml:"sample_interval"`
+	// LowPercent and CriticalPercent are the charges at which a
+	// discharging battery raises an event on the alerts topic.
+	LowPercent      float64 `yaml:"low_percent"`
+	CriticalPercent float64 `yaml:"critical_percent"`
+}
+
+// ProcessesConfig configures the background process collector.
+type ProcessesConfig struct {
+	// SampleInterval is the interval over which process CPU usage is
+	// measured.
+	SampleInterval Duration `yaml:"sample_interval"`
+	// MetricGroups limits the per-process-group metrics to the groups
+	// ranking this high by CPU usage or by resident memory. 0 leaves them
+	// out.
+	MetricGroups int `yaml:"metric_groups"`
+}
+
+// AuthConfig configures who may use the API. Without tokens and users
+// anyone can read, and nobody can control processes. Tokens and users can
+// only be set in the config file.
+type AuthConfig struct {
+	Tokens []TokenConfig `yaml:"tokens"`
+	Users  []UserConfig  `yaml:"users"`
+	// ClientCerts grant scopes to verified TLS client certificates.
+	ClientCerts []ClientCertConfig `yaml:"client_certs"`
+	// AllowedOrigins lists the browser origins allowed to call the API and
+	// open WebSockets, or "*" for every origin.
+	AllowedOrigins []string `yaml:"allowed_origins"`
+}
+
+// TokenConfig is a static API token.
+type TokenConfig struct {
+	Name  string `yaml:"name"`
+	Token string `yaml:"token"`
+	// Scope is "read" (the default) or "admin".
+	Scope string `yaml:"scope"`
+}
+
+// UserConfig is an HTTP Basic user.
+type UserConfig struct {
+	Name string `yaml:"name"`
+	// PasswordHash is a bcrypt hash, e.g. from "htpasswd -nbB user pass".
+	PasswordHash string `yaml:"password_hash"`
+	// Scope is "read" (the default) or "admin".
+	Scope string `yaml:"scope"`
+}
+
+// ClientCertConfig maps the common name of a client certificate to a scope.
+type ClientCertConfig struct {
+	CommonName string `yaml:"common_name"`
+	// Scope is "read" (the default) or "admin".
+	Scope string `yaml:"scope"`
+}
+
+// ControlConfig configures the process control endpoints, which require
+// the admin scope.
+type ControlConfig struct {
+	// Allow, when not empty, lists the only process or app names that can be
+	// controlled.
+	Allow []string `yaml:"allow"`
+	// Deny lists process or app names that are never controlled. PID 1 and
+	// the monitor itself are always protected.
+	Deny []string `yaml:"deny"`
+}
+
+// QuotaConfig defines a data cap per billing cycle. Quotas can only be set in
+// the config file.
+type QuotaConfig struct {
+	Name string `yaml:"name"`
+	// Interface is the interface the quota applies to, "all" by default.
+	Interface string `yaml:"iface"`
+	// Direction is "down", "up" or "total" (the default).
+	Direction string   `yaml:"direction"`
+	Limit     ByteSize `yaml:"limit"`
+	// ResetDay is the day of the month a new billing cycle starts, 1 by
+	// default.
+	ResetDay int `yaml:"reset_day"`
+	// WarnPercent is the share of the limit that raises a warning, 80 by
+	// default.
+	WarnPercent float64 `yaml:"warn_percent"`
+}
+
+// AlertsConfig defines alert rules and where their notifications go. Rules
+// and notifiers can only be set in the config file.
+type AlertsConfig struct {
+	Rules     []AlertRuleConfig `yaml:"rules"`
+	Notifiers []NotifierConfig  `yaml:"notifiers"`
+}
+
+// AlertRuleConfig is a rule such as "cpu_percent > 90 for 2m".
+type AlertRuleConfig struct {
+	Name string `yaml:"name"`
+	Expr string `yaml:"expr"`
+	// Severity is free-form and passed on to notifiers, "warning" by
+	// default.
+	Severity string `yaml:"severity"`
+}
+
+// NotifierConfig configures a notifier of type "webhook" (URL, Headers,
+// Timeout), "command" (Command) or "log" (Path).
+type NotifierConfig struct {
+	Type    string            `yaml:"type"`
+	URL     string            `yaml:"url,omitempty"`
+	Headers map[string]string `yaml:"headers,omitempty"`
+	Timeout Duration          `yaml:"timeout,omitempty"`
+	Command []string          `yaml:"command,omitempty"`
+	Path    string            `yaml:"path,omitempty"`
+}
+
+// Default returns the configuration used when nothing is overridden.
+func Default() Config {
+	return Config{
+		Server: ServerConfig{
+			Listen:       ":8000",
+			PushInterval: Duration(2 * time.Second),
+		},
+		Database: DatabaseConfig{
+			Path: "network_stats.db",
+		},
+		Network: NetworkConfig{
+			SampleInterval:      Duration(1 * time.Second),
+			PersistenceInterval: Duration(1 * time.Minute),
+			MaxSleepInterval:    Duration(10 * time.Second),
+			MovingAverageWindow: 3,
+			HourlyPoints:        60,
+			HourlyInterval:      Duration(1 * time.Minute),
+			Processes: NetworkProcessesConfig{
+				SampleInterval: Duration(5 * time.Second),
+			},
+		},
+		History: HistoryConfig{
+			SampleInterval:  Duration(10 * time.Second),
+			RawRetention:    Duration(24 * time.Hour),
+			MinuteRetention: Duration(7 * 24 * time.Hour),
+			HourRetention:   Duration(400 * 24 * time.Hour),
+		},
+		CPU: CPUConfig{
+			SampleInterval: Duration(1 * time.Second),
+		},
+		Memory: MemoryConfig{
+			SampleInterval: Duration(2 * time.Second),
+		},
+		Load: LoadConfig{
+			SampleInterval:      Duration(2 * time.Second),
+			MaxSleepInterval:    Duration(20 * time.Second),
+			MovingAverageWindow: 3,
+			HourlyPoints:        60,
+			HourlyInterval:      Duration(1 * time.Minute),
+		},
+		Sensors: SensorsConfig{
+			SampleInterval: Duration(10 * time.Second),
+		},
+		Battery: BatteryConfig{
+			SampleInterval:  Duration(30 * time.Second),
+			LowPercent:      20,
+			CriticalPercent: 10,
+		},
+		Disks: DisksConfig{
+			Exclude: []string{
+				"devfs", "autofs", "squashfs", "/snap/*",
+				"/System/Volumes/VM", "/System/Volumes/Preboot", "/System/Volumes/Update",
+				"/System/Volumes/xarts", "/System/Volumes/iSCPreboot", "/System/Volumes/Hardware",
+			},
+			IO: DiskIOConfig{
+				SampleInterval:      Duration(2 * time.Second),
+				MaxSleepInterval:    Duration(20 * time.Second),
+				MovingAverageWindow: 3,
+				HourlyPoints:        60,
+				HourlyInterval:      Duration(1 * time.Minute),
+			},
+		},
+		Processes: ProcessesConfig{
+			SampleInterval: Duration(2 * time.Second),
+			MetricGroups:   10,
+		},
+		Auth: AuthConfig{
+			AllowedOrigins: []string{"http://localhost:5173", "http://127.0.0.1:5173"},
+		},
+		Control: ControlConfig{
+			Deny: []string{"launchd", "kernel_task", "WindowServer", "loginwindow"},
+		},
+	}
+}
+
+// Load builds the configuration from the defaults, the config file, the
+// environment and the command line, in increasing order of precedence. It
+// reports whether --print-config was requested.
+func Load(args []string) (Config, bool, error) {
+	cfg := Default()
+
+	fs := flag.NewFlagSet("macos-monitor", flag.ContinueOnError)
+	configPath := fs.String("config", os.Getenv(envPrefix+"_CONFIG"), "path to a YAML config file")
+	printConfig := fs.Bool("print-config", false, "print the effective configuration and exit")
+	listen := fs.String("listen", "", "HTTP listen address (server.listen)")
+	dbPath := fs.String("db", "", "SQLite database path (database.path)")
+	interfaces := fs.String("interfaces", "", "comma-separated interfaces to monitor (network.interfaces)")
+	sampleInterval := fs.Duration("sample-interval", 0, "network sample interval (network.sample_interval)")
+	persistenceInterval := fs.Duration("persistence-interval", 0, "network persistence interval (network.persistence_interval)")
+	if err := fs.Parse(args); err != nil {
+		return Config{}, false, err
+	}
+
+	if *configPath != "" {
+		if err := loadFile(*configPath, &cfg); err != nil {
+			return Config{}, false, err
+		}
+	}
+
+	if err := applyEnv(&cfg, envPrefix, os.LookupEnv); err != nil {
+		return Config{}, false, err
+	}
+
+	// Only flags given explicitly override the file and the environment.
+	fs.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "listen":
+			cfg.Server.Listen = *listen
+		case "db":
+			cfg.Database.Path = *dbPath
+		case "interfaces":
+			cfg.Network.Interfaces = splitList(*interfaces)
+		case "sample-interval":
+			cfg.Network.SampleInterval = Duration(*sampleInterval)
+		case "persistence-interval":
+			cfg.Network.PersistenceInterval = Duration(*persistenceInterval)
+		}
+	})
+
+	cfg.applyDefaults()
+	if err := cfg.Validate(); err != nil {
+		return Config{}, false, err
+	}
+	return cfg, *printConfig, nil
+}
+
+func loadFile(path string, cfg *Config) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to open config file: %w", err)
+	}
+	defer f.Close()
+
+	dec := yaml.NewDecoder(f)
+	dec.KnownFields(true)
+	if err := dec.Decode(cfg); err != nil && !errors.Is(err, io.EOF) {
+		return fmt.Errorf("failed to parse config file '%s': %w", path, err)
+	}
+	return nil
+}
+
+// applyDefaults fills in the defaults of list entries, which cannot be part
+// of Default.
+func (c *Config) applyDefaults() {
+	for i := range c.Alerts.Rules {
+		if c.Alerts.Rules[i].Severity == "" {
+			c.Alerts.Rules[i].Severity = "warning"
+		}
+	}
+	for i := range c.Auth.Tokens {
+		if c.Auth.Tokens[i].Scope == "" {
+			c.Auth.Tokens[i].Scope = "read"
+		}
+	}
+	for i := range c.Auth.Users {
+		if c.Auth.Users[i].Scope == "" {
+			c.Auth.Users[i].Scope = "read"
+		}
+	}
+	for i := range c.Auth.ClientCerts {
+		if c.Auth.ClientCerts[i].Scope == "" {
+			c.Auth.ClientCerts[i].Scope = "read"
+		}
+	}
+	if t := &c.Server.TLS; t.ClientAuth == "" {
+		t.ClientAuth = "none"
+		if t.ClientCAFile != "" {
+			t.ClientAuth = "require"
+		}
+	}
+	for i := range c.Quotas {
+		q := &c.Quotas[i]
+		if q.Interface == "" {
+			q.Interface = "all"
+		}
+		if q.Direction == "" {
+			q.Direction = "total"
+		}
+		if q.ResetDay == 0 {
+			q.ResetDay = 1
+		}
+		if q.WarnPercent == 0 {
+			q.WarnPercent = 80
+		}
+	}
+}
+
+// Validate checks the configuration for values the backend cannot run with.
+func (c Config) Validate() error {
+	var errs []error
+	if _, _, err := net.SplitHostPort(c.Server.Listen); err != nil {
+		errs = append(errs, fmt.Errorf("server.listen: %w", err))
+	}
+	if c.Server.PushInterval < Duration(time.Second) {
+		errs = append(errs, errors.New("server.push_interval must be at least 1s"))
+	}
+	t := c.Server.TLS
+	if (t.CertFile == "") != (t.KeyFile == "") {
+		errs = append(errs, errors.New("server.tls.cert_file and server.tls.key_file must be set together"))
+	}
+	switch t.ClientAuth {
+	case "none":
+	case "request", "require":
+		if t.ClientCAFile == "" {
+			errs = append(errs, fmt.Errorf("server.tls.client_auth '%s' requires server.tls.client_ca_file", t.ClientAuth))
+		}
+	default:
+		errs = append(errs, errors.New("server.tls.client_auth must be none, request or require"))
+	}
+	if t.ClientCAFile != "" && !t.Enabled {
+		errs = append(errs, errors.New("server.tls.client_ca_file requires server.tls.enabled"))
+	}
+	if c.Database.Path == "" {
+		errs = append(errs, errors.New("database.path must not be empty"))
+	}
+
+	n := c.Network
+	for _, iface := range n.Interfaces {
+		if iface == "" || iface == "all" {
+			errs = append(errs, fmt.Errorf("network.interfaces: invalid interface name '%s'", iface))
+		}
+	}
+	if n.SampleInterval <= 0 {
+		errs = append(errs, errors.New("network.sample_interval must be positive"))
+	}
+	if n.PersistenceInterval < n.SampleInterval {
+		errs = append(errs, errors.New("network.persistence_interval must not be shorter than network.sample_interval"))
+	}
+	if n.MaxSleepInterval <= n.SampleInterval {
+		errs = append(errs, errors.New("network.max_sleep_interval must be longer than network.sample_interval"))
+	}
+	if n.MovingAverageWindow < 1 {
+		errs = append(errs, errors.New("network.moving_average_window must be at least 1"))
+	}
+	if n.HourlyPoints < 1 {
+		errs = append(errs, errors.New("network.hourly_points must be at least 1"))
+	}
+	if n.HourlyInterval < Duration(time.Minute) || n.HourlyInterval%Duration(time.Minute) != 0 {
+		errs = append(errs, errors.New("network.hourly_interval must be a whole number of minutes"))
+	}
+	if n.Processes.SampleInterval < Duration(time.Second) {
+		errs = append(errs, errors.New("network.processes.sample_interval must be at least 1s"))
+	}
+
+	h := c.History
+	if h.SampleInterval <= 0 || h.SampleInterval > Duration(time.Minute) {
+		errs = append(errs, errors.New("history.sample_interval must be between 0 and 1m"))
+	}
+	retentions := []struct {
+		name  string
+		value Duration
+		min   Duration
+	}{
+		{"history.raw_retention", h.RawRetention, Duration(time.Hour)},
+		{"history.minute_retention", h.MinuteRetention, Duration(2 * time.Hour)},
+		{"history.hour_retention", h.HourRetention, Duration(48 * time.Hour)},
+		{"history.day_retention", h.DayRetention, Duration(48 * time.Hour)},
+	}
+	for _, r := range retentions {
+		// Each level must outlive the rollup into the next coarser level.
+		if r.value != 0 && r.value < r.min {
+			errs = append(errs, fmt.Errorf("%s must be 0 (forever) or at least %s", r.name, r.min.Std()))
+		}
+	}
+
+	if s := c.CPU.SampleInterval; s < Duration(250*time.Millisecond) || s > Duration(time.Minute) {
+		errs = append(errs, errors.New("cpu.sample_interval must be between 250ms and 1m"))
+	}
+	if m := c.Memory.SampleInterval; m < Duration(500*time.Millisecond) || m > Duration(time.Minute) {
+		errs = append(errs, errors.New("memory.sample_interval must be between 500ms and 1m"))
+	}
+	l := c.Load
+	if l.SampleInterval < Duration(500*time.Millisecond) {
+		errs = append(errs, errors.New("load.sample_interval must be at least 500ms"))
+	}
+	if l.MaxSleepInterval <= l.SampleInterval {
+		errs = append(errs, errors.New("load.max_sleep_interval must be longer than load.sample_interval"))
+	}
+	if l.MovingAverageWindow < 1 {
+		errs = append(errs, errors.New("load.moving_average_window must be at least 1"))
+	}
+	if l.HourlyPoints < 1 {
+		errs = append(errs, errors.New("load.hourly_points must be at least 1"))
+	}
+	if l.HourlyInterval < Duration(time.Minute) || l.HourlyInterval%Duration(time.Minute) != 0 {
+		errs = append(errs, errors.New("load.hourly_interval must be a whole number of minutes"))
+	}
+	if c.Sensors.SampleInterval < Duration(time.Second) {
+		errs = append(errs, errors.New("sensors.sample_interval must be at least 1s"))
+	}
+	b := c.Battery
+	if b.SampleInterval < Duration(time.Second) {
+		errs = append(errs, errors.New("battery.sample_interval must be at least 1s"))
+	}
+	if b.CriticalPercent < 0 || b.CriticalPercent >= b.LowPercent || b.LowPercent > 100 {
+		errs = append(errs, errors.New("battery.critical_percent and battery.low_percent must satisfy 0 <= critical < low <= 100"))
+	}
+
+	for _, pattern := range slices.Concat(c.Disks.Include, c.Disks.Exclude) {
+		if _, err := path.Match(pattern, ""); err != nil || pattern == "" {
+			errs = append(errs, fmt.Errorf("disks: invalid pattern '%s'", pattern))
+		}
+	}
+	d := c.Disks.IO
+	for _, device := range d.Devices {
+		if device == "" || device == "all" {
+			errs = append(errs, fmt.Errorf("disks.io.devices: invalid device name '%s'", device))
+		}
+	}
+	if d.SampleInterval < Duration(500*time.Millisecond) {
+		errs = append(errs, errors.New("disks.io.sample_interval must be at least 500ms"))
+	}
+	if d.MaxSleepInterval <= d.SampleInterval {
+		errs = append(errs, errors.New("disks.io.max_sleep_interval must be longer than disks.io.sample_interval"))
+	}
+	if d.MovingAverageWindow < 1 {
+		errs = append(errs, errors.New("disks.io.moving_average_window must be at least 1"))
+	}
+	if d.HourlyPoints < 1 {
+		errs = append(errs, errors.New("disks.io.hourly_points must be at least 1"))
+	}
+	if d.HourlyInterval < Duration(time.Minute) || d.HourlyInterval%Duration(time.Minute) != 0 {
+		errs = append(errs, errors.New("disks.io.hourly_interval must be a whole number of minutes"))
+	}
+
+	if p := c.Processes.SampleInterval; p < Duration(500*time.Millisecond) || p > Duration(time.Minute) {
+		errs = append(errs, errors.New("processes.sample_interval must be between 500ms and 1m"))
+	}
+	if c.Processes.MetricGroups < 0 {
+		errs = append(errs, errors.New("processes.metric_groups must not be negative"))
+	}
+
+	// Password hashes are checked by the auth package when the
+	// authenticator is built.
+	credentialNames := make(map[string]bool)
+	for i, t := range c.Auth.Tokens {
+		prefix := fmt.Sprintf("auth.tokens[%d]", i)
+		if t.Name == "" {
+			errs = append(errs, fmt.Errorf("%s.name must not be empty", prefix))
+		} else if credentialNames[t.Name] {
+			errs = append(errs, fmt.Errorf("%s.name '%s' is not unique", prefix, t.Name))
+		}
+		credentialNames[t.Name] = true
+		if len(t.Token) < minTokenLength {
+			errs = append(errs, fmt.Errorf("%s.token must be at least %d characters", prefix, minTokenLength))
+		}
+		if t.Scope != "read" && t.Scope != "admin" {
+			errs = append(errs, fmt.Errorf("%s.scope must be read or admin", prefix))
+		}
+	}
+	for i, u := range c.Auth.Users {
+		prefix := fmt.Sprintf("auth.users[%d]", i)
+		if u.Name == "" || strings.Contains(u.Name, ":") {
+			errs = append(errs, fmt.Errorf("%s.name must be non-empty and not contain ':'", prefix))
+		} else if credentialNames[u.Name] {
+			errs = append(errs, fmt.Errorf("%s.name '%s' is not unique", prefix, u.Name))
+		}
+		credentialNames[u.Name] = true
+		if u.PasswordHash == "" {
+			errs = append(errs, fmt.Errorf("%s.password_hash must not be empty", prefix))
+		}
+		if u.Scope != "read" && u.Scope != "admin" {
+			errs = append(errs, fmt.Errorf("%s.scope must be read or admin", prefix))
+		}
+	}
+	for i, cc := range c.Auth.ClientCerts {
+		prefix := fmt.Sprintf("auth.client_certs[%d]", i)
+		if cc.CommonName == "" {
+			errs = append(errs, fmt.Errorf("%s.common_name must not be empty", prefix))
+		}
+		if cc.Scope != "read" && cc.Scope != "admin" {
+			errs = append(errs, fmt.Errorf("%s.scope must be read or admin", prefix))
+		}
+	}
+	if len(c.Auth.ClientCerts) > 0 && c.Server.TLS.ClientCAFile == "" {
+		errs = append(errs, errors.New("auth.client_certs requires server.tls.client_ca_file"))
+	}
+	credentials := len(c.Auth.Tokens) > 0 || len(c.Auth.Users) > 0 || len(c.Auth.ClientCerts) > 0
+	for _, origin := range c.Auth.AllowedOrigins {
+		if origin == "*" {
+			// Browsers send cached Basic credentials and client certificates
+			// from any page, which could then read the API
+			if credentials {
+				errs = append(errs, errors.New("auth.allowed_origins must list the origins instead of '*' when credentials are configured"))
+			}
+			continue
+		}
+		if u, err := url.Parse(origin); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" || (u.Path != "" && u.Path != "/") {
+			errs = append(errs, fmt.Errorf("auth.allowed_origins entry '%s' must be '*' or scheme://host[:port]", origin))
+		}
+	}
+
+	quotaNames := make(map[string]bool)
+	for i, q := range c.Quotas {
+		prefix := fmt.Sprintf("quotas[%d]", i)
+		if q.Name == "" {
+			errs = append(errs, fmt.Errorf("%s.name must not be empty", prefix))
+		} else if quotaNames[q.Name] {
+			errs = append(errs, fmt.Errorf("%s.name '%s' is not unique", prefix, q.Name))
+		}
+		quotaNames[q.Name] = true
+		switch q.Direction {
+		case "down", "up", "total":
+		default:
+			errs = append(errs, fmt.Errorf("%s.direction must be down, up or total", prefix))
+		}
+		if q.Limit == 0 {
+			errs = append(errs, fmt.Errorf("%s.limit must be positive", prefix))
+		}
+		if q.ResetDay < 1 || q.ResetDay > 31 {
+			errs = append(errs, fmt.Errorf("%s.reset_day must be between 1 and 31", prefix))
+		}
+		if q.WarnPercent <= 0 || q.WarnPercent > 100 {
+			errs = append(errs, fmt.Errorf("%s.warn_percent must be between 0 and 100", prefix))
+		}
+	}
+
+	// Rule expressions are parsed, and thereby validated, by the alert
+	// package when the engine is built.
+	ruleNames := make(map[string]bool)
+	for i, r := range c.Alerts.Rules {
+		prefix := fmt.Sprintf("alerts.rules[%d]", i)
+		if r.Name == "" {
+			errs = append(errs, fmt.Errorf("%s.name must not be empty", prefix))
+		} else if ruleNames[r.Name] {
+			errs = append(errs, fmt.Errorf("%s.name '%s' is not unique", prefix, r.Name))
+		}
+		ruleNames[r.Name] = true
+		if r.Expr == "" {
+			errs = append(errs, fmt.Errorf("%s.expr must not be empty", prefix))
+		}
+	}
+	for i, n := range c.Alerts.Notifiers {
+		prefix := fmt.Sprintf("alerts.notifiers[%d]", i)
+		switch n.Type {
+		case "webhook":
+			if u, err := url.Parse(n.URL); err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+				errs = append(errs, fmt.Errorf("%s.url must be an http or https URL", prefix))
+			}
+		case "command":
+			if len(n.Command) == 0 {
+				errs = append(errs, fmt.Errorf("%s.command must not be empty", prefix))
+			}
+		case "log":
+			if n.Path == "" {
+				errs = append(errs, fmt.Errorf("%s.path must not be empty", prefix))
+			}
+		default:
+			errs = append(errs, fmt.Errorf("%s.type must be webhook, command or log", prefix))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// redacted replaces secrets in the output of YAML.
+const redacted = "<redacted>"
+
+// YAML returns the configuration in config file format, with API tokens,
+// password hashes, and webhook header values and URL paths redacted.
+func (c Config) YAML() ([]byte, error) {
+	c.Auth.Tokens = slices.Clone(c.Auth.Tokens)
+	for i := range c.Auth.Tokens {
+		c.Auth.Tokens[i].Token = redacted
+	}
+	c.Auth.Users = slices.Clone(c.Auth.Users)
+	for i := range c.Auth.Users {
+		c.Auth.Users[i].PasswordHash = redacted
+	}
+	c.Alerts.Notifiers = slices.Clone(c.Alerts.Notifiers)
+	for i, n := range c.Alerts.Notifiers {
+		if n.URL != "" {
+			c.Alerts.Notifiers[i].URL = redactURL(n.URL)
+		}
+		if n.Headers == nil {
+			continue
+		}
+		headers := make(map[string]string, len(n.Headers))
+		for k := range n.Headers {
+			headers[k] = redacted
+		}
+		c.Alerts.Notifiers[i].Headers = headers
+	}
+	return yaml.Marshal(c)
+}
+
+// redactURL keeps the scheme and host of a URL and redacts the rest, as
+// webhook URLs such as Slack's carry their secret in the path.
+func redactURL(raw string) string {
+	u, err := url.Parse(raw)
+	if err != nil || u.Host == "" {
+		return redacted
+	}
+	if u.User == nil && (u.Path == "" || u.Path == "/") && u.RawQuery == "" && u.Fragment == "" {
+		return raw
+	}
+	return u.Scheme + "://" + u.Host + "/" + redacted
+}
+
+func splitList(s string) []string {
+	var result []string
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			result = append(result, item)
+		}
+	}
+	return result
+}
@@ -0,0 +1,95 @@
+package config
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestApplyEnv(t *testing.T) {
+	tests := []struct {
+		name  string
+		env   map[string]string
+		check func(Config) any // returns the field the variable set
+		want  any
+	}{
+		{
+			name:  "string",
+			env:   map[string]string{"X_SERVER_LISTEN": "127.0.0.1:9000"},
+			check: func(c Config) any { return c.Server.Listen },
+			want:  "127.0.0.1:9000",
+		},
+		{
+			name:  "bool",
+			env:   map[string]string{"X_SERVER_TLS_ENABLED": "true"},
+			check: func(c Config) any { return c.Server.TLS.Enabled },
+			want:  true,
+		},
+		{
+			name:  "int",
+			env:   map[string]string{"X_PROCESSES_METRIC_GROUPS": "25"},
+			check: func(c Config) any { return c.Processes.MetricGroups },
+			want:  25,
+		},
+		{
+			name:  "float",
+			env:   map[string]string{"X_BATTERY_LOW_PERCENT": "32.5"},
+			check: func(c Config) any { return c.Battery.LowPercent },
+			want:  32.5,
+		},
+		{
+			name:  "nested duration",
+			env:   map[string]string{"X_NETWORK_PROCESSES_SAMPLE_INTERVAL": "1m30s"},
+			check: func(c Config) any { return c.Network.Processes.SampleInterval },
+			want:  Duration(90 * time.Second),
+		},
+		{
+			name:  "list",
+			env:   map[string]string{"X_NETWORK_INTERFACES": "en0, en1,,utun3"},
+			check: func(c Config) any { return c.Network.Interfaces },
+			want:  []string{"en0", "en1", "utun3"},
+		},
+		{
+			name:  "unset keeps the default",
+			env:   map[string]string{"X_LISTEN": ":1"},
+			check: func(c Config) any { return c.Server.Listen },
+			want:  ":8000",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := Default()
+			if err := applyEnv(&cfg, "X", mapLookup(tt.env)); err != nil {
+				t.Fatalf("applyEnv: %v", err)
+			}
+			if got := tt.check(cfg); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestApplyEnvInvalid(t *testing.T) {
+	for name, value := range map[string]string{
+		"X_SERVER_PUSH_INTERVAL":    "5",
+		"X_SERVER_TLS_ENABLED":      "maybe",
+		"X_PROCESSES_METRIC_GROUPS": "ten",
+		"X_BATTERY_LOW_PERCENT":     "low",
+		// Lists of structs can only be set in the config file
+		"X_QUOTAS": "phone",
+	} {
+		cfg := Default()
+		err := applyEnv(&cfg, "X", mapLookup(map[string]string{name: value}))
+		if err == nil || !strings.Contains(err.Error(), name) {
+			t.Errorf("applyEnv(%s=%s) = %v, want an error naming the variable", name, value, err)
+		}
+	}
+}
+
+func mapLookup(env map[string]string) func(string) (string, bool) {
+	return func(name string) (string, bool) {
+		value, ok := env[name]
+		return value, ok
+	}
+}
@@ -0,0 +1,58 @@
+package config
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ByteSize is a number of bytes that can be written with a unit, such as
+// "50GB" or "1.5GiB". Decimal units are powers of 1000, binary units powers
+// of 1024.
+type ByteSize uint64
+
+var byteUnits = []struct {
+	suffix     string
+	multiplier float64
+}{
+	// Longer suffixes first so "GiB" is not matched as "B".
+	{"KiB", 1 << 10}, {"MiB", 1 << 20}, {"GiB", 1 << 30}, {"TiB", 1 << 40},
+	{"KB", 1e3}, {"MB", 1e6}, {"GB", 1e9}, {"TB", 1e12},
+	{"B", 1},
+}
+
+// ParseByteSize parses a size such as "50GB" or a plain number of bytes.
+func ParseByteSize(s string) (ByteSize, error) {
+	s = strings.TrimSpace(s)
+	multiplier := 1.0
+	for _, unit := range byteUnits {
+		if strings.HasSuffix(strings.ToUpper(s), strings.ToUpper(unit.suffix)) {
+			multiplier = unit.multiplier
+			s = strings.TrimSpace(s[:len(s)-len(unit.suffix)])
+			break
+		}
+	}
+	value, err := strconv.ParseFloat(s, 64)
+	if err != nil || value < 0 || math.IsNaN(value) || math.IsInf(value, 0) {
+		return 0, fmt.Errorf("invalid size '%s'", s)
+	}
+	// Sizes are compared with signed byte counts, and converting a larger
+	// float is implementation-defined
+	if value*multiplier >= math.MaxInt64 {
+		return 0, fmt.Errorf("size '%s' is too large", s)
+	}
+	return ByteSize(value * multiplier), nil
+}
+
+// UnmarshalYAML parses a size with or without a unit.
+func (b *ByteSize) UnmarshalYAML(value *yaml.Node) error {
+	parsed, err := ParseByteSize(value.Value)
+	if err != nil {
+		return fmt.Errorf("line %d: %w", value.Line, err)
+	}
+	*b = parsed
+	return nil
+}
@@ -0,0 +1,31 @@
+package config
+
+import "testing"
+
+func TestParseByteSize(t *testing.T) {
+	tests := []struct {
+		in   string
+		want ByteSize
+	}{
+		{"0", 0},
+		{"1024", 1024},
+		{"50GB", 50e9},
+		{"1.5GiB", 3 << 29},
+		{" 2 mb ", 2e6},
+		{"10kib", 10 << 10},
+		{"7B", 7},
+		{"8000000TB", 8e18},
+	}
+	for _, tt := range tests {
+		got, err := ParseByteSize(tt.in)
+		if err != nil || got != tt.want {
+			t.Errorf("ParseByteSize(%q) = %d, %v, want %d", tt.in, got, err, tt.want)
+		}
+	}
+
+	for _, in := range []string{"", "GB", "-1", "1.5XB", "NaN", "Inf", "-Inf", "infGB", "9223372036854775807", "10000000TB", "1e30"} {
+		if got, err := ParseByteSize(in); err == nil {
+			t.Errorf("ParseByteSize(%q) = %d, want an error", in, got)
+		}
+	}
+}
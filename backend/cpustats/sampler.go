@@ -0,0 +1,167 @@
+// Package cpustats samples CPU usage per core in the background, so every
+// reader sees the usage over the same interval instead of since its own
+// last call.
+package cpustats
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/shirou/gopsutil/v3/cpu"
+	"macos-monitor/backend-go/hub"
+)
+
+// Breakdown splits CPU time by state, in percent of the elapsed time. States
+// a platform does not report stay zero.
+type Breakdown struct {
+	User    float64 `json:"user"`
+	System  float64 `json:"system"`
+	Idle    float64 `json:"idle"`
+	Nice    float64 `json:"nice"`
+	Iowait  float64 `json:"iowait"`
+	Irq     float64 `json:"irq"`
+	Softirq float64 `json:"softirq"`
+	Steal   float64 `json:"steal"`
+}
+
+// Core is the usage of a single logical core.
+type Core struct {
+	Core         int     `json:"core"`
+	UsagePercent float64 `json:"usage_percent"`
+	Breakdown
+}
+
+// Snapshot is the CPU usage over the last interval.
+type Snapshot struct {
+	Timestamp int64 `json:"timestamp"`
+	// UsagePercent is the busy share of all cores, excluding idle and
+	// I/O wait time.
+	UsagePercent float64 `json:"usage_percent"`
+	Breakdown
+	Cores []Core `json:"cores"`
+}
+
+// Sampler computes a Snapshot from the per-core CPU times every interval
+// and publishes it on the hub.
+type Sampler struct {
+	interval time.Duration
+	hub      *hub.Hub
+
+	// CPU times of the previous tick, only accessed by the sampling
+	// goroutine
+	last []cpu.TimesStat
+
+	mu       sync.RWMutex
+	snapshot Snapshot
+}
+
+// NewSampler creates a Sampler that samples every interval and publishes on
+// h.
+func NewSampler(h *hub.Hub, interval time.Duration) *Sampler {
+	s := &Sampler{interval: interval, hub: h, snapshot: Snapshot{Cores: []Core{}}}
+	// Establish a baseline so the first tick yields usage
+	if times, err := cpu.Times(true); err == nil {
+		s.last = times
+	} else {
+		log.Printf("Warning: could not get initial CPU times: %v. Will retry.", err)
+	}
+	return s
+}
+
+// Run samples every interval until ctx is done.
+func (s *Sampler) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			s.performSample(now)
+		}
+	}
+}
+
+// Snapshot returns the usage over the last interval. It is zero until the
+// first interval has passed.
+func (s *Sampler) Snapshot() Snapshot {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	snapshot := s.snapshot
+	snapshot.Cores = append([]Core(nil), s.snapshot.Cores...)
+	return snapshot
+}
+
+func (s *Sampler) performSample(now time.Time) {
+	times, err := cpu.Times(true)
+	if err != nil {
+		log.Printf("Error sampling CPU times: %v", err)
+		return
+	}
+	last := s.last
+	s.last = times
+	// Cores can go offline, which changes the list
+	if len(last) != len(times) {
+		return
+	}
+
+	snapshot := Snapshot{Timestamp: now.Unix(), Cores: make([]Core, 0, len(times))}
+	var prevTotal, curTotal cpu.TimesStat
+	for i, cur := range times {
+		prev := last[i]
+		usage, breakdown := usage(prev, cur)
+		snapshot.Cores = append(snapshot.Cores, Core{Core: i, UsagePercent: usage, Breakdown: breakdown})
+		add(&prevTotal, prev)
+		add(&curTotal, cur)
+	}
+	snapshot.UsagePercent, snapshot.Breakdown = usage(prevTotal, curTotal)
+
+	s.mu.Lock()
+	s.snapshot = snapshot
+	s.mu.Unlock()
+
+	s.hub.Publish(hub.TopicCPU, "cpu", "", snapshot)
+}
+
+// usage returns the busy share and the breakdown of the CPU time elapsed
+// between two samples, in percent.
+func usage(prev, cur cpu.TimesStat) (float64, Breakdown) {
+	elapsed := total(cur) - total(prev)
+	if elapsed <= 0 {
+		return 0, Breakdown{}
+	}
+	share := func(prev, cur float64) float64 {
+		return max(0, cur-prev) / elapsed * 100
+	}
+	b := Breakdown{
+		User:    share(prev.User, cur.User),
+		System:  share(prev.System, cur.System),
+		Idle:    share(prev.Idle, cur.Idle),
+		Nice:    share(prev.Nice, cur.Nice),
+		Iowait:  share(prev.Iowait, cur.Iowait),
+		Irq:     share(prev.Irq, cur.Irq),
+		Softirq: share(prev.Softirq, cur.Softirq),
+		Steal:   share(prev.Steal, cur.Steal),
+	}
+	return min(100, max(0, 100-b.Idle-b.Iowait)), b
+}
+
+// total is the CPU time in every state. Guest time is already part of
+// user and nice time.
+func total(t cpu.TimesStat) float64 {
+	return t.User + t.System + t.Idle + t.Nice + t.Iowait + t.Irq + t.Softirq + t.Steal
+}
+
+func add(sum *cpu.TimesStat, t cpu.TimesStat) {
+	sum.User += t.User
+	sum.System += t.System
+	sum.Idle += t.Idle
+	sum.Nice += t.Nice
+	sum.Iowait += t.Iowait
+	sum.Irq += t.Irq
+	sum.Softirq += t.Softirq
+	sum.Steal += t.Steal
+}
@@ -0,0 +1,70 @@
+package cpustats
+
+import (
+	"testing"
+
+	"github.com/shirou/gopsutil/v3/cpu"
+)
+
+func TestUsage(t *testing.T) {
+	prev := cpu.TimesStat{User: 100, System: 50, Idle: 800, Nice: 10, Iowait: 20, Irq: 5, Softirq: 5, Steal: 10}
+	tests := []struct {
+		name      string
+		prev, cur cpu.TimesStat
+		usage     float64
+		breakdown Breakdown
+	}{
+		{
+			name:      "busy",
+			prev:      prev,
+			cur:       cpu.TimesStat{User: 130, System: 60, Idle: 850, Nice: 10, Iowait: 20, Irq: 5, Softirq: 5, Steal: 20},
+			usage:     50,
+			breakdown: Breakdown{User: 30, System: 10, Idle: 50, Steal: 10},
+		},
+		{
+			name:      "waiting for I/O is not busy",
+			prev:      prev,
+			cur:       cpu.TimesStat{User: 110, System: 50, Idle: 870, Nice: 10, Iowait: 40, Irq: 5, Softirq: 5, Steal: 10},
+			usage:     10,
+			breakdown: Breakdown{User: 10, Idle: 70, Iowait: 20},
+		},
+		{
+			name:      "every state",
+			prev:      cpu.TimesStat{},
+			cur:       cpu.TimesStat{User: 10, System: 20, Idle: 30, Nice: 5, Iowait: 10, Irq: 5, Softirq: 5, Steal: 15},
+			usage:     60,
+			breakdown: Breakdown{User: 10, System: 20, Idle: 30, Nice: 5, Iowait: 10, Irq: 5, Softirq: 5, Steal: 15},
+		},
+		{
+			name: "no time elapsed",
+			prev: prev,
+			cur:  prev,
+		},
+		{
+			name: "total going backwards",
+			prev: prev,
+			cur:  cpu.TimesStat{User: 10, System: 5, Idle: 80},
+		},
+		// Linux may report less I/O wait time than before
+		{
+			name:      "counter going backwards",
+			prev:      cpu.TimesStat{User: 100, Idle: 900, Iowait: 50},
+			cur:       cpu.TimesStat{User: 160, Idle: 990, Iowait: 0},
+			usage:     10,
+			breakdown: Breakdown{User: 60, Idle: 90},
+		},
+		{
+			name:      "usage is not negative",
+			prev:      cpu.TimesStat{User: 100, Idle: 900},
+			cur:       cpu.TimesStat{User: 50, Idle: 1000},
+			usage:     0,
+			breakdown: Breakdown{Idle: 200},
+		},
+	}
+	for _, tt := range tests {
+		usage, breakdown := usage(tt.prev, tt.cur)
+		if usage != tt.usage || breakdown != tt.breakdown {
+			t.Errorf("%s: usage() = %v, %+v, want %v, %+v", tt.name, usage, breakdown, tt.usage, tt.breakdown)
+		}
+	}
+}
@@ -0,0 +1,26 @@
+//go:build linux
+
+package diskio
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// isWholeDisk reports whether name is a disk rather than a partition, or a
+// loop or RAM device.
+func isWholeDisk(name string) bool {
+	if strings.HasPrefix(name, "loop") || strings.HasPrefix(name, "ram") {
+		return false
+	}
+	_, err := os.Stat(filepath.Join("/sys/class/block", name, "partition"))
+	return os.IsNotExist(err)
+}
+
+// isStacked reports whether name is built on other block devices, such as
+// a device-mapper or md RAID volume.
+func isStacked(name string) bool {
+	slaves, err := os.ReadDir(filepath.Join("/sys/class/block", name, "slaves"))
+	return err == nil && len(slaves) > 0
+}
@@ -0,0 +1,15 @@
+//go:build !linux
+
+package diskio
+
+// isWholeDisk reports true, as only whole disks are reported outside
+// Linux.
+func isWholeDisk(name string) bool {
+	return true
+}
+
+// isStacked reports false, as stacked devices are not told apart outside
+// Linux.
+func isStacked(name string) bool {
+	return false
+}
@@ -0,0 +1,352 @@
+// Package diskio samples the throughput, IOPS and busy time of block
+// devices, smooths them like the network rates and keeps the last hour.
+package diskio
+
+import (
+	"context"
+	"errors"
+	"log"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/shirou/gopsutil/v3/disk"
+	"macos-monitor/backend-go/hub"
+	"macos-monitor/backend-go/series"
+)
+
+// AllDevices selects the aggregate of every device that is not stacked on
+// other devices.
+const AllDevices = "all"
+
+// ErrUnknownDevice is returned when rates are requested for a device the
+// sampler has not seen.
+var ErrUnknownDevice = errors.New("unknown device")
+
+// Options configures the sampling and aggregation of a Sampler.
+type Options struct {
+	// Devices restricts sampling to the listed devices. When empty, every
+	// whole disk is sampled, leaving out partitions, loop and RAM devices.
+	Devices        []string
+	SampleInterval time.Duration
+	// MaxSleepInterval is the longest gap between two samples that still
+	// yields a rate; longer gaps are treated as sleep/wake.
+	MaxSleepInterval    time.Duration
+	MovingAverageWindow int
+	HourlyPoints        int
+	HourlyInterval      time.Duration
+}
+
+// Rate is the smoothed I/O of a device.
+type Rate struct {
+	Device    string  `json:"device"`
+	Timestamp int64   `json:"timestamp"`
+	ReadBPS   float64 `json:"read_bps"`
+	WriteBPS  float64 `json:"write_bps"`
+	ReadIOPS  float64 `json:"read_iops"`
+	WriteIOPS float64 `json:"write_iops"`
+	// BusyPercent is the share of time the device had I/O in flight. The
+	// aggregate reports the busiest device.
+	BusyPercent float64 `json:"busy_percent"`
+}
+
+// HourlyPoint is the average I/O of a device over one interval.
+type HourlyPoint struct {
+	OffsetMin   int     `json:"offset_min"`
+	ReadBPS     float64 `json:"read_bps"`
+	WriteBPS    float64 `json:"write_bps"`
+	ReadIOPS    float64 `json:"read_iops"`
+	WriteIOPS   float64 `json:"write_iops"`
+	BusyPercent float64 `json:"busy_percent"`
+}
+
+// HourlyStats is the I/O of a device over the last hour.
+type HourlyStats struct {
+	Device      string        `json:"device"`
+	IntervalMin int           `json:"interval_min"`
+	Points      []HourlyPoint `json:"points"`
+}
+
+// Indexes of the values of a sample, in the order of the hourly series.
+const (
+	readBPS = iota
+	writeBPS
+	readIOPS
+	writeIOPS
+	busyPercent
+	numValues
+)
+
+// deviceState holds the rate calculation state of a single device, or of
+// the aggregate of all devices.
+type deviceState struct {
+	lastSample disk.IOCountersStat
+	lastTime   time.Time
+	// inTotal is false for devices stacked on other devices, such as LVM
+	// or RAID volumes, whose I/O the aggregate already counts.
+	inTotal  bool
+	rate     Rate
+	averages [numValues]*series.MovingAverage
+	hourly   *series.RingBuffer
+	// gone is set while the device is no longer reported, such as an
+	// unplugged USB disk
+	gone bool
+}
+
+func newDeviceState(name string, opts Options) *deviceState {
+	s := &deviceState{
+		rate:   Rate{Device: name},
+		hourly: series.NewRingBuffer(opts.HourlyPoints, opts.HourlyInterval, numValues),
+	}
+	for i := range s.averages {
+		s.averages[i] = series.NewMovingAverage(opts.MovingAverageWindow)
+	}
+	return s
+}
+
+// Sampler tracks the I/O rates of every selected device and of their
+// aggregate under AllDevices, and publishes them on the hub.
+type Sampler struct {
+	opts Options
+	hub  *hub.Hub
+
+	mu      sync.RWMutex
+	devices map[string]*deviceState
+	total   *deviceState
+}
+
+// NewSampler creates a Sampler that publishes rates on h.
+func NewSampler(h *hub.Hub, opts Options) *Sampler {
+	s := &Sampler{
+		opts:    opts,
+		hub:     h,
+		devices: make(map[string]*deviceState),
+		total:   newDeviceState(AllDevices, opts),
+	}
+
+	// Fetch initial counters to establish a baseline
+	counters, err := s.readCounters()
+	if err != nil {
+		log.Printf("Warning: could not get initial disk I/O counters: %v. Will retry.", err)
+	}
+	now := time.Now()
+	for name, c := range counters {
+		state := newDeviceState(name, opts)
+		state.lastSample, state.lastTime = c, now
+		state.inTotal = !isStacked(name)
+		s.devices[name] = state
+	}
+	return s
+}
+
+// Run samples the devices every interval until ctx is done.
+func (s *Sampler) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.opts.SampleInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.performSample()
+		}
+	}
+}
+
+// Rates returns the current rate of every device, sorted by name, followed
+// by the aggregate.
+func (s *Sampler) Rates() []Rate {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	rates := make([]Rate, 0, len(s.devices)+1)
+	for _, state := range s.devices {
+		rates = append(rates, state.rate)
+	}
+	sort.Slice(rates, func(i, j int) bool {
+		return rates[i].Device < rates[j].Device
+	})
+	return append(rates, s.total.rate)
+}
+
+// GetHourlyStats returns the I/O of device over the last hour.
+func (s *Sampler) GetHourlyStats(device string) (HourlyStats, error) {
+	state, err := s.state(device)
+	if err != nil {
+		return HourlyStats{}, err
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	points := state.hourly.Points()
+	stats := HourlyStats{
+		Device:      device,
+		IntervalMin: int(s.opts.HourlyInterval.Minutes()),
+		Points:      make([]HourlyPoint, 0, len(points)),
+	}
+	for _, p := range points {
+		stats.Points = append(stats.Points, HourlyPoint{
+			OffsetMin:   p.OffsetMin,
+			ReadBPS:     p.Values[readBPS],
+			WriteBPS:    p.Values[writeBPS],
+			ReadIOPS:    p.Values[readIOPS],
+			WriteIOPS:   p.Values[writeIOPS],
+			BusyPercent: p.Values[busyPercent],
+		})
+	}
+	return stats, nil
+}
+
+func (s *Sampler) state(device string) (*deviceState, error) {
+	if device == AllDevices {
+		return s.total, nil
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	state, ok := s.devices[device]
+	if !ok {
+		return nil, ErrUnknownDevice
+	}
+	return state, nil
+}
+
+// readCounters returns the counters of the selected devices.
+func (s *Sampler) readCounters() (map[string]disk.IOCountersStat, error) {
+	counters, err := disk.IOCounters()
+	if err != nil {
+		return nil, err
+	}
+	for name := range counters {
+		if len(s.opts.Devices) > 0 {
+			if !slices.Contains(s.opts.Devices, name) {
+				delete(counters, name)
+			}
+		} else if !isWholeDisk(name) {
+			delete(counters, name)
+		}
+	}
+	return counters, nil
+}
+
+func (s *Sampler) performSample() {
+	counters, err := s.readCounters()
+	if err != nil {
+		log.Printf("Error sampling disk I/O: %v", err)
+		return
+	}
+	now := time.Now()
+
+	s.mu.Lock()
+	var (
+		total   [numValues]float64
+		sampled int
+		rates   = make([]Rate, 0, len(counters)+1)
+	)
+	for name, c := range counters {
+		state, ok := s.devices[name]
+		if !ok {
+			log.Printf("Discovered disk '%s'", name)
+			state = newDeviceState(name, s.opts)
+			state.inTotal = !isStacked(name)
+			s.devices[name] = state
+		}
+		state.gone = false
+
+		values, ok := state.sample(c, now, s.opts.MaxSleepInterval)
+		if !ok {
+			continue
+		}
+		if state.inTotal {
+			for i := range total {
+				if i == busyPercent {
+					total[i] = max(total[i], values[i])
+				} else {
+					total[i] += values[i]
+				}
+			}
+			sampled++
+		}
+		rates = append(rates, state.rate)
+	}
+
+	// Devices that went away keep their history but no longer report their
+	// last rate
+	for name, state := range s.devices {
+		if _, ok := counters[name]; ok || state.gone {
+			continue
+		}
+		log.Printf("Disk '%s' went away", name)
+		state.gone = true
+		// Start over from a new baseline if it comes back
+		state.lastSample = disk.IOCountersStat{}
+		state.clearRate(now)
+		rates = append(rates, state.rate)
+	}
+
+	if sampled == 0 {
+		s.total.clearRate(now)
+	} else {
+		s.total.record(total, now)
+	}
+	rates = append(rates, s.total.rate)
+	s.mu.Unlock()
+
+	for _, rate := range rates {
+		s.hub.Publish(hub.TopicDiskIO, "rate", rate.Device, rate)
+	}
+}
+
+// sample calculates the raw values of a device from its current counters.
+// It reports false when no rate could be calculated for this sample.
+func (s *deviceState) sample(c disk.IOCountersStat, now time.Time, maxSleepInterval time.Duration) (values [numValues]float64, ok bool) {
+	deltaT := now.Sub(s.lastTime).Seconds()
+	last := s.lastSample
+	s.lastSample, s.lastTime = c, now
+	// Check for sleep/wake or initial sample
+	if last.Name == "" || deltaT <= 0 || deltaT > maxSleepInterval.Seconds() {
+		// Reset moving averages to avoid a spike on the next valid sample
+		for _, ma := range s.averages {
+			ma.Reset()
+		}
+		return values, false
+	}
+
+	values[readBPS] = series.CounterRate(last.ReadBytes, c.ReadBytes, deltaT)
+	values[writeBPS] = series.CounterRate(last.WriteBytes, c.WriteBytes, deltaT)
+	values[readIOPS] = series.CounterRate(last.ReadCount, c.ReadCount, deltaT)
+	values[writeIOPS] = series.CounterRate(last.WriteCount, c.WriteCount, deltaT)
+	// IoTime is in milliseconds. On macOS it adds up read and write time,
+	// which can exceed the elapsed time with concurrent requests.
+	values[busyPercent] = min(100, series.CounterRate(last.IoTime, c.IoTime, deltaT)/10)
+
+	s.record(values, now)
+	return values, true
+}
+
+// clearRate zeroes the rate and resets the moving averages.
+func (s *deviceState) clearRate(now time.Time) {
+	for _, ma := range s.averages {
+		ma.Reset()
+	}
+	s.rate = Rate{Device: s.rate.Device, Timestamp: now.Unix()}
+}
+
+// record feeds raw values into the moving averages and the hourly ring
+// buffer.
+func (s *deviceState) record(values [numValues]float64, now time.Time) {
+	var smooth [numValues]float64
+	for i, v := range values {
+		smooth[i] = s.averages[i].Add(v)
+	}
+	s.rate = Rate{
+		Device:      s.rate.Device,
+		Timestamp:   now.Unix(),
+		ReadBPS:     smooth[readBPS],
+		WriteBPS:    smooth[writeBPS],
+		ReadIOPS:    smooth[readIOPS],
+		WriteIOPS:   smooth[writeIOPS],
+		BusyPercent: smooth[busyPercent],
+	}
+	s.hourly.Add(values[:]...)
+}
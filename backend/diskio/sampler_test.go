@@ -0,0 +1,102 @@
+package diskio
+
+import (
+	"testing"
+	"time"
+
+	"github.com/shirou/gopsutil/v3/disk"
+)
+
+func TestDeviceStateSample(t *testing.T) {
+	opts := Options{MaxSleepInterval: 10 * time.Second, MovingAverageWindow: 2, HourlyPoints: 60, HourlyInterval: time.Minute}
+	s := newDeviceState("sda", opts)
+	start := time.Date(2026, 3, 14, 9, 0, 0, 0, time.UTC)
+	counters := func(read, write, reads, writes, ioTime uint64) disk.IOCountersStat {
+		return disk.IOCountersStat{Name: "sda", ReadBytes: read, WriteBytes: write, ReadCount: reads, WriteCount: writes, IoTime: ioTime}
+	}
+
+	steps := []struct {
+		name     string
+		at       time.Duration
+		counters disk.IOCountersStat
+		ok       bool
+		values   [numValues]float64
+		readBPS  float64 // smoothed
+	}{
+		{
+			name:     "first sample",
+			counters: counters(1000, 1000, 10, 10, 0),
+		},
+		{
+			name:     "rates",
+			at:       2 * time.Second,
+			counters: counters(5000, 2000, 30, 14, 500),
+			ok:       true,
+			values:   [numValues]float64{2000, 500, 10, 2, 25},
+			readBPS:  2000,
+		},
+		{
+			name:     "smoothed",
+			at:       3 * time.Second,
+			counters: counters(9000, 2000, 30, 14, 500),
+			ok:       true,
+			values:   [numValues]float64{4000, 0, 0, 0, 0},
+			readBPS:  3000,
+		},
+		{
+			name:     "counters going backwards",
+			at:       4 * time.Second,
+			counters: counters(100, 2500, 1, 15, 0),
+			ok:       true,
+			values:   [numValues]float64{0, 500, 0, 1, 0},
+			readBPS:  2000,
+		},
+		{
+			name:     "no time elapsed",
+			at:       4 * time.Second,
+			counters: counters(200, 2500, 1, 15, 0),
+		},
+		{
+			name:     "gap over the max sleep interval",
+			at:       15 * time.Second,
+			counters: counters(10000, 2500, 1, 15, 0),
+		},
+		{
+			name:     "after the gap",
+			at:       16 * time.Second,
+			counters: counters(11000, 2500, 1, 15, 0),
+			ok:       true,
+			values:   [numValues]float64{1000, 0, 0, 0, 0},
+			readBPS:  1000,
+		},
+		{
+			name:     "busy time is capped",
+			at:       17 * time.Second,
+			counters: counters(11000, 2500, 1, 15, 2000),
+			ok:       true,
+			values:   [numValues]float64{0, 0, 0, 0, 100},
+			readBPS:  500,
+		},
+	}
+	for _, step := range steps {
+		now := start.Add(step.at)
+		values, ok := s.sample(step.counters, now, opts.MaxSleepInterval)
+		if ok != step.ok || values != step.values {
+			t.Errorf("%s: sample() = %v, %v, want %v, %v", step.name, values, ok, step.values, step.ok)
+		}
+		if step.ok && (s.rate.ReadBPS != step.readBPS || s.rate.Timestamp != now.Unix()) {
+			t.Errorf("%s: rate = %+v, want %v bytes read per second at %d", step.name, s.rate, step.readBPS, now.Unix())
+		}
+	}
+
+	now := start.Add(20 * time.Second)
+	s.clearRate(now)
+	if want := (Rate{Device: "sda", Timestamp: now.Unix()}); s.rate != want {
+		t.Errorf("rate after clearRate() = %+v, want %+v", s.rate, want)
+	}
+	// The averages start over
+	s.sample(counters(13000, 2500, 1, 15, 2000), now.Add(time.Second), opts.MaxSleepInterval)
+	if s.rate.ReadBPS != 500 || s.rate.BusyPercent != 0 {
+		t.Errorf("rate after clearRate() and a sample = %+v, want only the new sample", s.rate)
+	}
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+
+	"macos-monitor/backend-go/diskio"
+	"macos-monitor/backend-go/disks"
+)
+
+// disksHandler lists the usage of the file systems selected by filter.
+func disksHandler(filter disks.Filter) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		mounted, err := disks.List(filter)
+		if err != nil {
+			http.Error(w, "Could not list disks", http.StatusInternalServerError)
+			log.Printf("Error listing disks: %v", err)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(mounted)
+	}
+}
+
+// diskIOHandler serves the current I/O rates of every sampled device and
+// their aggregate.
+func diskIOHandler(s *diskio.Sampler) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(s.Rates())
+	}
+}
+
+// diskIOHourlyHandler serves the I/O of the last hour of the device
+// selected by the "device" parameter, the aggregate by default.
+func diskIOHourlyHandler(s *diskio.Sampler) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		device := r.URL.Query().Get("device")
+		if device == "" {
+			device = diskio.AllDevices
+		}
+		stats, err := s.GetHourlyStats(device)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("Unknown disk device '%s'", device), http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(stats)
+	}
+}
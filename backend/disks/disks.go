@@ -0,0 +1,110 @@
+// Package disks lists the mounted file systems with their space and inode
+// usage, selected by include and exclude rules.
+package disks
+
+import (
+	"fmt"
+	"path"
+	"sort"
+
+	"github.com/shirou/gopsutil/v3/disk"
+)
+
+// Disk is a mounted file system and its usage.
+type Disk struct {
+	Device      string   `json:"device"`
+	Mountpoint  string   `json:"mountpoint"`
+	Fstype      string   `json:"fstype"`
+	Opts        []string `json:"opts"`
+	Total       uint64   `json:"total"`
+	Used        uint64   `json:"used"`
+	Free        uint64   `json:"free"`
+	UsedPercent float64  `json:"used_percent"`
+	// Inode counts are zero on file systems without a fixed number of
+	// inodes.
+	InodesTotal       uint64  `json:"inodes_total"`
+	InodesUsed        uint64  `json:"inodes_used"`
+	InodesFree        uint64  `json:"inodes_free"`
+	InodesUsedPercent float64 `json:"inodes_used_percent"`
+}
+
+// Filter selects file systems by glob patterns, such as "/Volumes/*",
+// "/dev/sd*" or "tmpfs", matched against the mount point, the device and
+// the file system type.
+type Filter struct {
+	// Include, when not empty, lists the only patterns that are monitored.
+	Include []string
+	// Exclude lists patterns that are never monitored.
+	Exclude []string
+}
+
+// Match reports whether the filter selects p.
+func (f Filter) Match(p disk.PartitionStat) bool {
+	if len(f.Include) > 0 && !matchAny(f.Include, p) {
+		return false
+	}
+	return !matchAny(f.Exclude, p)
+}
+
+func matchAny(patterns []string, p disk.PartitionStat) bool {
+	for _, pattern := range patterns {
+		for _, name := range []string{p.Mountpoint, p.Device, p.Fstype} {
+			if ok, _ := path.Match(pattern, name); ok {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// List returns the usage of the file systems selected by f, sorted by mount
+// point. A device mounted more than once, such as through bind mounts, is
+// listed under its shortest mount point. File systems whose usage cannot be
+// read are left out.
+func List(f Filter) ([]Disk, error) {
+	partitions, err := disk.Partitions(false)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list partitions: %w", err)
+	}
+
+	byDevice := make(map[string]disk.PartitionStat)
+	for _, p := range partitions {
+		if !f.Match(p) {
+			continue
+		}
+		if seen, ok := byDevice[p.Device]; ok && len(seen.Mountpoint) <= len(p.Mountpoint) {
+			continue
+		}
+		byDevice[p.Device] = p
+	}
+
+	disks := make([]Disk, 0, len(byDevice))
+	for _, p := range byDevice {
+		usage, err := disk.Usage(p.Mountpoint)
+		if err != nil {
+			continue
+		}
+		opts := p.Opts
+		if opts == nil {
+			opts = []string{}
+		}
+		disks = append(disks, Disk{
+			Device:            p.Device,
+			Mountpoint:        p.Mountpoint,
+			Fstype:            p.Fstype,
+			Opts:              opts,
+			Total:             usage.Total,
+			Used:              usage.Used,
+			Free:              usage.Free,
+			UsedPercent:       usage.UsedPercent,
+			InodesTotal:       usage.InodesTotal,
+			InodesUsed:        usage.InodesUsed,
+			InodesFree:        usage.InodesFree,
+			InodesUsedPercent: usage.InodesUsedPercent,
+		})
+	}
+	sort.Slice(disks, func(i, j int) bool {
+		return disks[i].Mountpoint < disks[j].Mountpoint
+	})
+	return disks, nil
+}
@@ -0,0 +1,170 @@
+package history
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/shirou/gopsutil/v3/disk"
+	"github.com/shirou/gopsutil/v3/load"
+	"macos-monitor/backend-go/cpustats"
+	"macos-monitor/backend-go/disks"
+	"macos-monitor/backend-go/memstats"
+)
+
+// Names of the metrics recorded by the Sampler.
+const (
+	MetricCPUPercent      = "cpu_percent"
+	MetricMemoryPercent   = "memory_percent"
+	MetricMemoryUsed      = "memory_used"
+	MetricMemoryAvailable = "memory_available"
+	// The pressure level from 0 (normal) to 2 (critical).
+	MetricMemoryPressure = "memory_pressure"
+	MetricSwapPercent    = "swap_percent"
+	MetricSwapUsed       = "swap_used"
+	MetricSwapInBPS      = "swap_in_bps"
+	MetricSwapOutBPS     = "swap_out_bps"
+	MetricDiskPercent    = "disk_percent"
+	MetricDiskUsed       = "disk_used"
+	// Recorded per mount point only.
+	MetricDiskInodesPercent = "disk_inodes_percent"
+	MetricLoad1             = "load1"
+	MetricLoad5             = "load5"
+	MetricLoad15            = "load15"
+	// Recorded by the battery collector, on systems with a battery.
+	MetricBatteryPercent = "battery_percent"
+	MetricBatteryHealth  = "battery_health_percent"
+)
+
+// Metrics lists every metric recorded by the Sampler.
+var Metrics = []string{
+	MetricCPUPercent,
+	MetricMemoryPercent,
+	MetricMemoryUsed,
+	MetricMemoryAvailable,
+	MetricMemoryPressure,
+	MetricSwapPercent,
+	MetricSwapUsed,
+	MetricSwapInBPS,
+	MetricSwapOutBPS,
+	MetricDiskPercent,
+	MetricDiskUsed,
+	MetricDiskInodesPercent,
+	MetricLoad1,
+	MetricLoad5,
+	MetricLoad15,
+	MetricBatteryPercent,
+	MetricBatteryHealth,
+}
+
+const rollupInterval = 1 * time.Minute
+
+// Sampler periodically records CPU, memory, swap, disk and load samples
+// into a Store and rolls them up.
+type Sampler struct {
+	store    *Store
+	interval time.Duration
+	diskPath string
+	disks    disks.Filter
+	cpu      *cpustats.Sampler
+	memory   *memstats.Sampler
+
+	// Called with every set of samples, registered before Run
+	observers []func(time.Time, []Sample)
+}
+
+// NewSampler creates a Sampler that records a sample every interval. The
+// unlabeled disk metrics are those of the file system mounted at diskPath,
+// and every file system selected by diskFilter is recorded with its mount
+// point as label. CPU usage is recorded from the latest sample of cpu, and
+// memory and swap from the latest sample of memory.
+func NewSampler(store *Store, interval time.Duration, diskPath string, diskFilter disks.Filter, cpu *cpustats.Sampler, memory *memstats.Sampler) *Sampler {
+	return &Sampler{store: store, interval: interval, diskPath: diskPath, disks: diskFilter, cpu: cpu, memory: memory}
+}
+
+// AddObserver registers fn to be called from the sampling goroutine with
+// every set of samples. It must be called before Run.
+func (s *Sampler) AddObserver(fn func(time.Time, []Sample)) {
+	s.observers = append(s.observers, fn)
+}
+
+// Run records samples and rolls them up until ctx is done.
+func (s *Sampler) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+	rollupTicker := time.NewTicker(rollupInterval)
+	defer rollupTicker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			samples := s.collect()
+			if err := s.store.Insert(now, samples); err != nil {
+				log.Printf("Error recording system samples: %v", err)
+			}
+			for _, fn := range s.observers {
+				fn(now, samples)
+			}
+		case now := <-rollupTicker.C:
+			// Samples of the last interval may still be on their way, from
+			// this loop or from other collectors writing to the store
+			if err := s.store.Rollup(now, s.interval); err != nil {
+				log.Printf("Error rolling up system samples: %v", err)
+			}
+		}
+	}
+}
+
+// collect gathers the current samples. Metrics that cannot be read are
+// left out rather than recorded as zero.
+func (s *Sampler) collect() []Sample {
+	var samples []Sample
+
+	if c := s.cpu.Snapshot(); c.Timestamp != 0 {
+		samples = append(samples, Sample{Metric: MetricCPUPercent, Value: c.UsagePercent})
+	}
+
+	if m := s.memory.Snapshot(); m.Timestamp != 0 {
+		samples = append(samples,
+			Sample{Metric: MetricMemoryPercent, Value: m.Memory.UsedPercent},
+			Sample{Metric: MetricMemoryUsed, Value: float64(m.Memory.Used)},
+			Sample{Metric: MetricMemoryAvailable, Value: float64(m.Memory.Available)},
+			Sample{Metric: MetricMemoryPressure, Value: m.Pressure.Value()},
+			Sample{Metric: MetricSwapPercent, Value: m.Swap.UsedPercent},
+			Sample{Metric: MetricSwapUsed, Value: float64(m.Swap.Used)},
+			Sample{Metric: MetricSwapInBPS, Value: m.Swap.InBPS},
+			Sample{Metric: MetricSwapOutBPS, Value: m.Swap.OutBPS},
+		)
+	}
+
+	if diskInfo, err := disk.Usage(s.diskPath); err == nil {
+		samples = append(samples,
+			Sample{Metric: MetricDiskPercent, Value: diskInfo.UsedPercent},
+			Sample{Metric: MetricDiskUsed, Value: float64(diskInfo.Used)},
+		)
+	}
+
+	if mounted, err := disks.List(s.disks); err == nil {
+		for _, d := range mounted {
+			samples = append(samples,
+				Sample{Metric: MetricDiskPercent, Label: d.Mountpoint, Value: d.UsedPercent},
+				Sample{Metric: MetricDiskUsed, Label: d.Mountpoint, Value: float64(d.Used)},
+				Sample{Metric: MetricDiskInodesPercent, Label: d.Mountpoint, Value: d.InodesUsedPercent},
+			)
+		}
+	} else {
+		log.Printf("Could not list disks: %v", err)
+	}
+
+	if avg, err := load.Avg(); err == nil {
+		samples = append(samples,
+			Sample{Metric: MetricLoad1, Value: avg.Load1},
+			Sample{Metric: MetricLoad5, Value: avg.Load5},
+			Sample{Metric: MetricLoad15, Value: avg.Load15},
+		)
+	}
+
+	return samples
+}
@@ -0,0 +1,309 @@
+// Package history samples system metrics in the background and keeps them in
+// SQLite, downsampled from raw samples to 1 minute, 1 hour and 1 day rollups.
+package history
+
+import (
+	"database/sql"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+)
+
+const (
+	samplesTableStmt = `
+	CREATE TABLE IF NOT EXISTS system_samples (
+		ts INTEGER NOT NULL,
+		metric TEXT NOT NULL,
+		label TEXT NOT NULL DEFAULT '',
+		value REAL NOT NULL
+	);
+	CREATE INDEX IF NOT EXISTS system_samples_metric_ts ON system_samples (metric, label, ts);`
+
+	rollupsTableStmt = `
+	CREATE TABLE IF NOT EXISTS system_rollups (
+		resolution INTEGER NOT NULL,
+		ts INTEGER NOT NULL,
+		metric TEXT NOT NULL,
+		label TEXT NOT NULL DEFAULT '',
+		avg REAL NOT NULL,
+		min REAL NOT NULL,
+		max REAL NOT NULL,
+		count INTEGER NOT NULL,
+		PRIMARY KEY (resolution, metric, label, ts)
+	);`
+)
+
+// Resolution is the bucket width of a storage level in seconds. Raw samples
+// have a resolution of zero.
+type Resolution int64
+
+const (
+	Raw    Resolution = 0
+	Minute Resolution = 60
+	Hour   Resolution = 3600
+	Day    Resolution = 86400
+)
+
+// levels lists the storage levels from finest to coarsest. Each rollup level
+// is built from the level before it.
+var levels = []Resolution{Raw, Minute, Hour, Day}
+
+// Retention configures how long each storage level is kept. A zero retention
+// keeps the level forever.
+type Retention struct {
+	Raw    time.Duration
+	Minute time.Duration
+	Hour   time.Duration
+	Day    time.Duration
+}
+
+func (r Retention) of(res Resolution) time.Duration {
+	switch res {
+	case Raw:
+		return r.Raw
+	case Minute:
+		return r.Minute
+	case Hour:
+		return r.Hour
+	default:
+		return r.Day
+	}
+}
+
+// Sample is a single value of a metric. Label distinguishes series of the
+// same metric, e.g. the mount point of a disk; it is empty for system-wide
+// metrics.
+type Sample struct {
+	Metric string
+	Label  string
+	Value  float64
+}
+
+// Point is a bucket of a queried series.
+type Point struct {
+	Timestamp int64   `json:"timestamp"`
+	Avg       float64 `json:"avg"`
+	Min       float64 `json:"min"`
+	Max       float64 `json:"max"`
+}
+
+// Store persists samples and their rollups.
+type Store struct {
+	db        *sql.DB
+	retention Retention
+}
+
+// NewStore creates the history tables on db if needed.
+func NewStore(db *sql.DB, retention Retention) (*Store, error) {
+	if _, err := db.Exec(samplesTableStmt); err != nil {
+		return nil, fmt.Errorf("failed to create samples table: %w", err)
+	}
+	if _, err := db.Exec(rollupsTableStmt); err != nil {
+		return nil, fmt.Errorf("failed to create rollups table: %w", err)
+	}
+	return &Store{db: db, retention: retention}, nil
+}
+
+// Insert records raw samples taken at t.
+func (s *Store) Insert(t time.Time, samples []Sample) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.Prepare("INSERT INTO system_samples (ts, metric, label, value) VALUES (?, ?, ?, ?)")
+	if err != nil {
+		return fmt.Errorf("failed to prepare insert statement: %w", err)
+	}
+	defer stmt.Close()
+
+	for _, sample := range samples {
+		if _, err := stmt.Exec(t.Unix(), sample.Metric, sample.Label, sample.Value); err != nil {
+			return fmt.Errorf("failed to insert sample '%s': %w", sample.Metric, err)
+		}
+	}
+	return tx.Commit()
+}
+
+// Rollup aggregates every bucket that ended at least lag before now into the
+// next coarser level, then drops data older than the retention of its level.
+// Buckets are written only once, so lag must cover how late a sample can be
+// inserted after the time it was taken at.
+func (s *Store) Rollup(now time.Time, lag time.Duration) error {
+	for i := 1; i < len(levels); i++ {
+		if err := s.rollupLevel(levels[i-1], levels[i], now.Add(-lag)); err != nil {
+			return fmt.Errorf("failed to roll up to %ds: %w", levels[i], err)
+		}
+	}
+	return s.prune(now)
+}
+
+// rollupLevel aggregates the buckets of from that are not rolled up yet and
+// end by until into buckets of to.
+func (s *Store) rollupLevel(from, to Resolution, until time.Time) error {
+	// Buckets are only written once complete, so everything after the last
+	// written bucket still needs to be rolled up.
+	var last sql.NullInt64
+	err := s.db.QueryRow("SELECT MAX(ts) FROM system_rollups WHERE resolution = ?", to).Scan(&last)
+	if err != nil {
+		return err
+	}
+	start := int64(0)
+	if last.Valid {
+		start = last.Int64 + int64(to)
+	}
+	end := until.Unix() / int64(to) * int64(to)
+	if end <= start {
+		return nil
+	}
+
+	var query string
+	var args []any
+	if from == Raw {
+		query = `
+		INSERT OR REPLACE INTO system_rollups (resolution, ts, metric, label, avg, min, max, count)
+		SELECT ?, ts / ? * ?, metric, label, AVG(value), MIN(value), MAX(value), COUNT(*)
+		FROM system_samples
+		WHERE ts >= ? AND ts < ?
+		GROUP BY ts / ?, metric, label`
+		args = []any{to, to, to, start, end, to}
+	} else {
+		query = `
+		INSERT OR REPLACE INTO system_rollups (resolution, ts, metric, label, avg, min, max, count)
+		SELECT ?, ts / ? * ?, metric, label, SUM(avg * count) / SUM(count), MIN(min), MAX(max), SUM(count)
+		FROM system_rollups
+		WHERE resolution = ? AND ts >= ? AND ts < ?
+		GROUP BY ts / ?, metric, label`
+		args = []any{to, to, to, from, start, end, to}
+	}
+	_, err = s.db.Exec(query, args...)
+	return err
+}
+
+func (s *Store) prune(now time.Time) error {
+	for _, res := range levels {
+		retention := s.retention.of(res)
+		if retention <= 0 {
+			continue
+		}
+		cutoff := now.Add(-retention).Unix()
+		var err error
+		if res == Raw {
+			_, err = s.db.Exec("DELETE FROM system_samples WHERE ts < ?", cutoff)
+		} else {
+			_, err = s.db.Exec("DELETE FROM system_rollups WHERE resolution = ? AND ts < ?", res, cutoff)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to prune %ds level: %w", res, err)
+		}
+	}
+	return nil
+}
+
+// source picks the storage level to answer a query with the given step:
+// the coarsest level no coarser than the step, or a coarser one if that
+// level no longer holds data as old as from.
+func (s *Store) source(from time.Time, step time.Duration, now time.Time) Resolution {
+	idx := 0
+	for i, res := range levels {
+		if time.Duration(res)*time.Second <= step {
+			idx = i
+		}
+	}
+	for idx < len(levels)-1 {
+		retention := s.retention.of(levels[idx])
+		if retention <= 0 || !from.Before(now.Add(-retention)) {
+			break
+		}
+		idx++
+	}
+	return levels[idx]
+}
+
+// Query returns the series of metric and label between from and to,
+// bucketed by step. It also returns the storage level the series was read
+// from. Buckets of that level that are not rolled up yet are filled in from
+// the finer levels, so the series reaches up to the latest sample.
+func (s *Store) Query(metric, label string, from, to time.Time, step time.Duration) ([]Point, Resolution, error) {
+	res := s.source(from, step, time.Now())
+	stepSec := int64(step / time.Second)
+	if stepSec < 1 {
+		stepSec = 1
+	}
+
+	rolled, err := s.rolledUntil()
+	if err != nil {
+		return nil, res, err
+	}
+
+	// Each level covers the time from where the coarser level stops to
+	// where its own rollups stop, and raw samples the rest
+	var parts []string
+	var args []any
+	lo := from.Unix()
+	for i := slices.Index(levels, res); i >= 0; i-- {
+		level := levels[i]
+		if level == Raw {
+			parts = append(parts, `
+			SELECT ts, value AS avg, value AS min, value AS max, 1 AS count
+			FROM system_samples
+			WHERE metric = ? AND label = ? AND ts >= ? AND ts <= ?`)
+			args = append(args, metric, label, lo, to.Unix())
+			break
+		}
+		hi := min(rolled[level], to.Unix()+1)
+		if hi > lo {
+			parts = append(parts, `
+			SELECT ts, avg, min, max, count
+			FROM system_rollups
+			WHERE resolution = ? AND metric = ? AND label = ? AND ts >= ? AND ts < ?`)
+			args = append(args, level, metric, label, lo, hi)
+		}
+		lo = max(lo, hi)
+	}
+
+	query := `
+		SELECT ts / ? * ?, SUM(avg * count) / SUM(count), MIN(min), MAX(max)
+		FROM (` + strings.Join(parts, " UNION ALL ") + `)
+		GROUP BY ts / ?
+		ORDER BY 1`
+	args = append([]any{stepSec, stepSec}, append(args, stepSec)...)
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return nil, res, fmt.Errorf("failed to query history: %w", err)
+	}
+	defer rows.Close()
+
+	points := []Point{}
+	for rows.Next() {
+		var p Point
+		if err := rows.Scan(&p.Timestamp, &p.Avg, &p.Min, &p.Max); err != nil {
+			return nil, res, fmt.Errorf("failed to scan history row: %w", err)
+		}
+		points = append(points, p)
+	}
+	return points, res, rows.Err()
+}
+
+// rolledUntil returns, for each rollup level, the end of its last written
+// bucket in unix seconds. Levels without rollups are left out, i.e. zero.
+func (s *Store) rolledUntil() (map[Resolution]int64, error) {
+	rows, err := s.db.Query("SELECT resolution, MAX(ts) FROM system_rollups GROUP BY resolution")
+	if err != nil {
+		return nil, fmt.Errorf("failed to query rollup progress: %w", err)
+	}
+	defer rows.Close()
+
+	rolled := make(map[Resolution]int64)
+	for rows.Next() {
+		var res Resolution
+		var last int64
+		if err := rows.Scan(&res, &last); err != nil {
+			return nil, fmt.Errorf("failed to scan rollup progress: %w", err)
+		}
+		rolled[res] = last + int64(res)
+	}
+	return rolled, rows.Err()
+}
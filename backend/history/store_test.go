@@ -0,0 +1,146 @@
+package history
+
+import (
+	"database/sql"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// t0 starts a day, so it starts a bucket of every level.
+var t0 = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+func newTestStore(t *testing.T) *Store {
+	t.Helper()
+	db, err := sql.Open("sqlite3", filepath.Join(t.TempDir(), "history.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	store, err := NewStore(db, Retention{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return store
+}
+
+func insert(t *testing.T, s *Store, at time.Duration, value float64) {
+	t.Helper()
+	if err := s.Insert(t0.Add(at), []Sample{{Metric: MetricCPUPercent, Value: value}}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func rollup(t *testing.T, s *Store, at, lag time.Duration) {
+	t.Helper()
+	if err := s.Rollup(t0.Add(at), lag); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// dropSamples deletes the raw samples, so queries can only be answered from
+// the rollups.
+func dropSamples(t *testing.T, s *Store) {
+	t.Helper()
+	if _, err := s.db.Exec("DELETE FROM system_samples"); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func query(t *testing.T, s *Store, to, step time.Duration) ([]Point, Resolution) {
+	t.Helper()
+	points, res, err := s.Query(MetricCPUPercent, "", t0, t0.Add(to), step)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return points, res
+}
+
+func point(at time.Duration, avg, min, max float64) Point {
+	return Point{Timestamp: t0.Add(at).Unix(), Avg: avg, Min: min, Max: max}
+}
+
+func TestRollup(t *testing.T) {
+	s := newTestStore(t)
+	insert(t, s, 0, 10)
+	insert(t, s, 30*time.Second, 20)
+	insert(t, s, 90*time.Second, 60)
+	insert(t, s, 2*time.Hour, 40)
+	insert(t, s, 25*time.Hour, 100)
+	rollup(t, s, 48*time.Hour+time.Minute, 10*time.Second)
+	dropSamples(t, s)
+
+	tests := []struct {
+		step time.Duration
+		res  Resolution
+		want []Point
+	}{
+		{time.Minute, Minute, []Point{
+			point(0, 15, 10, 20),
+			point(time.Minute, 60, 60, 60),
+			point(2*time.Hour, 40, 40, 40),
+			point(25*time.Hour, 100, 100, 100),
+		}},
+		{time.Hour, Hour, []Point{
+			point(0, 30, 10, 60),
+			point(2*time.Hour, 40, 40, 40),
+			point(25*time.Hour, 100, 100, 100),
+		}},
+		// Averages are weighted by the number of samples in each bucket
+		{24 * time.Hour, Day, []Point{
+			point(0, 32.5, 10, 60),
+			point(24*time.Hour, 100, 100, 100),
+		}},
+	}
+	for _, tt := range tests {
+		points, res := query(t, s, 48*time.Hour, tt.step)
+		if res != tt.res {
+			t.Errorf("step %v: resolution = %d, want %d", tt.step, res, tt.res)
+		}
+		if !slices.Equal(points, tt.want) {
+			t.Errorf("step %v: points = %+v, want %+v", tt.step, points, tt.want)
+		}
+	}
+}
+
+func TestRollupLateSample(t *testing.T) {
+	s := newTestStore(t)
+	insert(t, s, 59*time.Second, 10)
+	// The minute has ended, but not a lag ago
+	rollup(t, s, 61*time.Second, 10*time.Second)
+	// A sample taken before the minute ended arrives after the rollup
+	insert(t, s, 59*time.Second, 30)
+	rollup(t, s, 71*time.Second, 10*time.Second)
+	dropSamples(t, s)
+
+	points, _ := query(t, s, time.Minute, time.Minute)
+	if want := []Point{point(0, 20, 10, 30)}; !slices.Equal(points, want) {
+		t.Errorf("points = %+v, want %+v", points, want)
+	}
+}
+
+func TestQueryFillsUnrolledBuckets(t *testing.T) {
+	s := newTestStore(t)
+	insert(t, s, 10*time.Minute, 10)
+	insert(t, s, 70*time.Minute, 20)
+	rollup(t, s, 72*time.Minute, 0)
+	// Only in the raw samples
+	insert(t, s, 75*time.Minute, 40)
+
+	// The first hour comes from its rollup, the second from the minute
+	// rollups and the raw samples after them, each counted once
+	points, res := query(t, s, 2*time.Hour, time.Hour)
+	if res != Hour {
+		t.Errorf("resolution = %d, want %d", res, Hour)
+	}
+	want := []Point{
+		point(0, 10, 10, 10),
+		point(time.Hour, 30, 20, 40),
+	}
+	if !slices.Equal(points, want) {
+		t.Errorf("points = %+v, want %+v", points, want)
+	}
+}
@@ -0,0 +1,218 @@
+package hub
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+const (
+	// Time allowed to write a message to the peer.
+	writeWait = 10 * time.Second
+
+	// Time allowed to read the next pong message from the peer.
+	pongWait = 60 * time.Second
+
+	// Send pings to peer with this period. Must be less than pongWait.
+	pingPeriod = (pongWait * 9) / 10
+
+	// Maximum message size allowed from peer.
+	maxMessageSize = 4096
+)
+
+// Options configures a client connection.
+type Options struct {
+	// Subscriptions the client starts with.
+	Subscriptions []Subscription
+
+	// Raw sends only the Data of each envelope, for clients that predate
+	// topics. Replies to requests are not sent to raw clients.
+	Raw bool
+
+	// CheckOrigin decides whether the upgrade request's origin is allowed.
+	// When nil, only same-origin requests are.
+	CheckOrigin func(r *http.Request) bool
+}
+
+// Client is a middleman between the websocket connection and the hub.
+type Client struct {
+	hub *Hub
+
+	// The websocket connection.
+	conn *websocket.Conn
+
+	// Subscribed keys by topic; an empty set means every key. Only
+	// accessed by the hub goroutine once the client is registered.
+	subscriptions map[string]map[string]bool
+
+	raw bool
+
+	// Buffered channel of outbound messages.
+	send chan Envelope
+
+	// Set by the hub before closing send when the server shuts down.
+	shutdown bool
+}
+
+// ServeWs handles websocket requests from the peer.
+func ServeWs(hub *Hub, w http.ResponseWriter, r *http.Request, opts Options) {
+	upgrader := websocket.Upgrader{CheckOrigin: opts.CheckOrigin}
+	conn, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		log.Println(err)
+		return
+	}
+	client := &Client{
+		hub:           hub,
+		conn:          conn,
+		subscriptions: make(map[string]map[string]bool),
+		raw:           opts.Raw,
+		send:          make(chan Envelope, 256),
+	}
+	for _, sub := range opts.Subscriptions {
+		if !hub.HasTopic(sub.Topic) {
+			continue
+		}
+		keys := client.subscriptions[sub.Topic]
+		if keys == nil {
+			keys = make(map[string]bool)
+			client.subscriptions[sub.Topic] = keys
+		}
+		if sub.Key != "" {
+			keys[sub.Key] = true
+		}
+	}
+	select {
+	case client.hub.register <- client:
+	case <-client.hub.done:
+		conn.WriteControl(websocket.CloseMessage,
+			websocket.FormatCloseMessage(websocket.CloseGoingAway, "server shutting down"),
+			time.Now().Add(writeWait))
+		conn.Close()
+		return
+	}
+
+	// Allow collection of memory referenced by the caller by doing all work in
+	// new goroutines.
+	go client.writePump()
+	go client.readPump()
+}
+
+// wants reports whether the client is subscribed to the topic and key of
+// message.
+func (c *Client) wants(message Envelope) bool {
+	keys, ok := c.subscriptions[message.Topic]
+	if !ok {
+		return false
+	}
+	return len(keys) == 0 || keys[message.Key]
+}
+
+// reply queues a control message for the client. It must only be called by
+// the hub goroutine.
+func (c *Client) reply(typ string, data interface{}) {
+	select {
+	case c.send <- Envelope{Topic: topicControl, Type: typ, Timestamp: time.Now().Unix(), Data: data}:
+	default:
+	}
+}
+
+// subscriptionList returns the client's subscriptions, sorted by topic.
+func (c *Client) subscriptionList() []Subscription {
+	subs := []Subscription{}
+	for topic, keys := range c.subscriptions {
+		if len(keys) == 0 {
+			subs = append(subs, Subscription{Topic: topic})
+		}
+		for key := range keys {
+			subs = append(subs, Subscription{Topic: topic, Key: key})
+		}
+	}
+	sort.Slice(subs, func(i, j int) bool {
+		if subs[i].Topic != subs[j].Topic {
+			return subs[i].Topic < subs[j].Topic
+		}
+		return subs[i].Key < subs[j].Key
+	})
+	return subs
+}
+
+// readPump forwards subscription requests from the peer to the hub until the
+// connection fails.
+func (c *Client) readPump() {
+	defer func() {
+		select {
+		case c.hub.unregister <- c:
+		case <-c.hub.done:
+		}
+		c.conn.Close()
+	}()
+	c.conn.SetReadLimit(maxMessageSize)
+	c.conn.SetReadDeadline(time.Now().Add(pongWait))
+	c.conn.SetPongHandler(func(string) error {
+		c.conn.SetReadDeadline(time.Now().Add(pongWait))
+		return nil
+	})
+	for {
+		_, data, err := c.conn.ReadMessage()
+		if err != nil {
+			if websocket.IsUnexpectedCloseError(err, websocket.CloseGoingAway, websocket.CloseNormalClosure) {
+				log.Printf("error: %v", err)
+			}
+			return
+		}
+		req := request{client: c}
+		if err := json.Unmarshal(data, &req); err != nil {
+			req.err = "invalid request: " + err.Error()
+		}
+		select {
+		case c.hub.requests <- req:
+		case <-c.hub.done:
+			return
+		}
+	}
+}
+
+func (c *Client) writePump() {
+	ticker := time.NewTicker(pingPeriod)
+	defer func() {
+		ticker.Stop()
+		c.conn.Close()
+	}()
+	for {
+		select {
+		case message, ok := <-c.send:
+			c.conn.SetWriteDeadline(time.Now().Add(writeWait))
+			if !ok {
+				// The hub closed the channel.
+				var closeMessage []byte
+				if c.shutdown {
+					closeMessage = websocket.FormatCloseMessage(websocket.CloseGoingAway, "server shutting down")
+				}
+				c.conn.WriteMessage(websocket.CloseMessage, closeMessage)
+				return
+			}
+
+			var payload interface{} = message
+			if c.raw {
+				if message.Topic == topicControl {
+					continue
+				}
+				payload = message.Data
+			}
+			if err := c.conn.WriteJSON(payload); err != nil {
+				log.Printf("error: %v", err)
+				return
+			}
+		case <-ticker.C:
+			c.conn.SetWriteDeadline(time.Now().Add(writeWait))
+			if err := c.conn.WriteMessage(websocket.PingMessage, nil); err != nil {
+				return
+			}
+		}
+	}
+}
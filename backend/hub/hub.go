@@ -0,0 +1,231 @@
+// Package hub streams typed messages on named topics to WebSocket clients.
+// Clients subscribe and unsubscribe by sending JSON messages.
+package hub
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Topics published by the backend.
+const (
+	TopicNetworkRate   = "network.rate"
+	TopicSystemDynamic = "system.dynamic"
+	TopicProcessesTop  = "processes.top"
+	TopicAlerts        = "alerts"
+	TopicDiskIO        = "disk.io"
+	TopicCPU           = "cpu"
+	TopicMemory        = "memory"
+	TopicLoad          = "load"
+	TopicSensors       = "sensors"
+	TopicBattery       = "battery"
+)
+
+// topicControl carries replies to client requests. Clients receive it
+// without subscribing.
+const topicControl = "control"
+
+// Envelope is a message on a topic. Type identifies the shape of Data, and
+// Key the series it belongs to, such as an interface name.
+type Envelope struct {
+	Topic     string      `json:"topic"`
+	Type      string      `json:"type"`
+	Key       string      `json:"key,omitempty"`
+	Timestamp int64       `json:"timestamp"`
+	Data      interface{} `json:"data"`
+}
+
+// Subscription selects the messages of a topic. An empty Key selects every
+// key of the topic.
+type Subscription struct {
+	Topic string `json:"topic"`
+	Key   string `json:"key,omitempty"`
+}
+
+// request is a message read from a client, such as
+// {"action": "subscribe", "topic": "network.rate", "key": "en0"}.
+type request struct {
+	client *Client
+	err    string
+	Action string `json:"action"`
+	Subscription
+}
+
+// Hub maintains the set of active clients and routes published messages to
+// the clients subscribed to their topic.
+type Hub struct {
+	topics map[string]bool
+
+	// Registered clients.
+	clients map[*Client]bool
+
+	// Messages to route to the clients.
+	broadcast chan Envelope
+
+	// Register requests from the clients.
+	register chan *Client
+
+	// Unregister requests from clients.
+	unregister chan *Client
+
+	// Subscribe and unsubscribe requests from clients.
+	requests chan request
+
+	// Closed when Run returns, so clients and publishers stop waiting for
+	// the hub.
+	done chan struct{}
+
+	// Number of clients subscribed to each topic, readable by publishers.
+	mu          sync.RWMutex
+	subscribers map[string]int
+}
+
+// NewHub creates a Hub that accepts subscriptions to the given topics.
+func NewHub(topics ...string) *Hub {
+	h := &Hub{
+		topics:      make(map[string]bool),
+		clients:     make(map[*Client]bool),
+		broadcast:   make(chan Envelope, 256),
+		register:    make(chan *Client),
+		unregister:  make(chan *Client),
+		requests:    make(chan request),
+		done:        make(chan struct{}),
+		subscribers: make(map[string]int),
+	}
+	for _, topic := range topics {
+		h.topics[topic] = true
+	}
+	return h
+}
+
+// HasTopic reports whether clients can subscribe to topic.
+func (h *Hub) HasTopic(topic string) bool {
+	return h.topics[topic]
+}
+
+// HasSubscribers reports whether any client is subscribed to topic, so
+// publishers can skip collecting data nobody receives.
+func (h *Hub) HasSubscribers(topic string) bool {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return h.subscribers[topic] > 0
+}
+
+// Publish sends data to the clients subscribed to topic and key. Messages
+// published after Run returned are dropped.
+func (h *Hub) Publish(topic, typ, key string, data interface{}) {
+	message := Envelope{
+		Topic:     topic,
+		Type:      typ,
+		Key:       key,
+		Timestamp: time.Now().Unix(),
+		Data:      data,
+	}
+	select {
+	case h.broadcast <- message:
+	case <-h.done:
+	}
+}
+
+// Run routes messages until ctx is done, then closes every client
+// connection with a going-away close frame.
+func (h *Hub) Run(ctx context.Context) {
+	defer close(h.done)
+	for {
+		select {
+		case <-ctx.Done():
+			for client := range h.clients {
+				client.shutdown = true
+				h.remove(client)
+			}
+			return
+		case client := <-h.register:
+			h.clients[client] = true
+			for topic := range client.subscriptions {
+				h.countSubscriber(topic, 1)
+			}
+		case client := <-h.unregister:
+			if _, ok := h.clients[client]; ok {
+				h.remove(client)
+			}
+		case req := <-h.requests:
+			if _, ok := h.clients[req.client]; ok {
+				h.handleRequest(req)
+			}
+		case message := <-h.broadcast:
+			for client := range h.clients {
+				if !client.wants(message) {
+					continue
+				}
+				select {
+				case client.send <- message:
+				default:
+					h.remove(client)
+				}
+			}
+		}
+	}
+}
+
+func (h *Hub) remove(client *Client) {
+	for topic := range client.subscriptions {
+		h.countSubscriber(topic, -1)
+	}
+	delete(h.clients, client)
+	close(client.send)
+}
+
+func (h *Hub) handleRequest(req request) {
+	c, sub := req.client, req.Subscription
+	if req.err != "" {
+		c.reply("error", req.err)
+		return
+	}
+	if req.Action != "subscribe" && req.Action != "unsubscribe" {
+		c.reply("error", fmt.Sprintf("unknown action '%s'", req.Action))
+		return
+	}
+	if !h.topics[sub.Topic] {
+		c.reply("error", fmt.Sprintf("unknown topic '%s'", sub.Topic))
+		return
+	}
+
+	keys, subscribed := c.subscriptions[sub.Topic]
+	switch {
+	case req.Action == "subscribe":
+		if !subscribed {
+			keys = make(map[string]bool)
+			c.subscriptions[sub.Topic] = keys
+			h.countSubscriber(sub.Topic, 1)
+		}
+		if sub.Key != "" && (!subscribed || len(keys) > 0) {
+			keys[sub.Key] = true
+		} else {
+			// Widen to every key of the topic.
+			clear(keys)
+		}
+	case !subscribed:
+		// Nothing to unsubscribe from.
+	case sub.Key != "" && len(keys) > 0:
+		delete(keys, sub.Key)
+		if len(keys) == 0 {
+			delete(c.subscriptions, sub.Topic)
+			h.countSubscriber(sub.Topic, -1)
+		}
+	default:
+		// Unsubscribing without a key, or a key of a subscription to every
+		// key, drops the whole topic.
+		delete(c.subscriptions, sub.Topic)
+		h.countSubscriber(sub.Topic, -1)
+	}
+
+	c.reply("subscriptions", c.subscriptionList())
+}
+
+func (h *Hub) countSubscriber(topic string, delta int) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.subscribers[topic] += delta
+}
@@ -0,0 +1,241 @@
+package hub
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// message is a received envelope with its data left encoded.
+type message struct {
+	Topic string          `json:"topic"`
+	Type  string          `json:"type"`
+	Key   string          `json:"key"`
+	Data  json.RawMessage `json:"data"`
+}
+
+func (m message) String() string {
+	return m.Topic + " " + m.Type + " " + m.Key + " " + string(m.Data)
+}
+
+func startHub(t *testing.T, topics ...string) (*Hub, context.CancelFunc) {
+	t.Helper()
+	h := NewHub(topics...)
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	go h.Run(ctx)
+	return h, cancel
+}
+
+// dial connects a client to h with opts.
+func dial(t *testing.T, h *Hub, opts Options) *websocket.Conn {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ServeWs(h, w, r, opts)
+	}))
+	t.Cleanup(srv.Close)
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(srv.URL, "http"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return conn
+}
+
+// receive reads the next message, which the test expects within a second.
+func receive(t *testing.T, conn *websocket.Conn) []byte {
+	t.Helper()
+	conn.SetReadDeadline(time.Now().Add(time.Second))
+	_, data, err := conn.ReadMessage()
+	if err != nil {
+		t.Fatalf("reading message: %v", err)
+	}
+	return data
+}
+
+func receiveMessages(t *testing.T, conn *websocket.Conn, n int) []string {
+	t.Helper()
+	var got []string
+	for range n {
+		var m message
+		if err := json.Unmarshal(receive(t, conn), &m); err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, m.String())
+	}
+	return got
+}
+
+// waitForSubscribers waits until the hub registered a client subscribed to
+// topic.
+func waitForSubscribers(t *testing.T, h *Hub, topic string) {
+	t.Helper()
+	for deadline := time.Now().Add(time.Second); !h.HasSubscribers(topic); {
+		if time.Now().After(deadline) {
+			t.Fatalf("no subscribers to %s", topic)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestRequests(t *testing.T) {
+	h, _ := startHub(t, TopicNetworkRate, TopicCPU)
+	conn := dial(t, h, Options{})
+
+	steps := []struct {
+		request string
+		reply   string
+	}{
+		{
+			`{"action": "subscribe", "topic": "network.rate", "key": "en0"}`,
+			`control subscriptions  [{"topic":"network.rate","key":"en0"}]`,
+		},
+		{
+			`{"action": "subscribe", "topic": "network.rate", "key": "en1"}`,
+			`control subscriptions  [{"topic":"network.rate","key":"en0"},{"topic":"network.rate","key":"en1"}]`,
+		},
+		{
+			`{"action": "unsubscribe", "topic": "network.rate", "key": "en0"}`,
+			`control subscriptions  [{"topic":"network.rate","key":"en1"}]`,
+		},
+		{
+			`{"action": "subscribe", "topic": "cpu"}`,
+			`control subscriptions  [{"topic":"cpu"},{"topic":"network.rate","key":"en1"}]`,
+		},
+		// A key of a subscription to every key neither narrows nor drops
+		// it on subscribe, but drops it on unsubscribe
+		{
+			`{"action": "subscribe", "topic": "cpu", "key": "core0"}`,
+			`control subscriptions  [{"topic":"cpu"},{"topic":"network.rate","key":"en1"}]`,
+		},
+		{
+			`{"action": "unsubscribe", "topic": "cpu", "key": "core0"}`,
+			`control subscriptions  [{"topic":"network.rate","key":"en1"}]`,
+		},
+		// Subscribing without a key widens to every key
+		{
+			`{"action": "subscribe", "topic": "network.rate"}`,
+			`control subscriptions  [{"topic":"network.rate"}]`,
+		},
+		{
+			`{"action": "unsubscribe", "topic": "network.rate"}`,
+			`control subscriptions  []`,
+		},
+		{
+			`{"action": "unsubscribe", "topic": "cpu"}`,
+			`control subscriptions  []`,
+		},
+		{
+			`{"action": "subscribe", "topic": "gpu"}`,
+			`control error  "unknown topic 'gpu'"`,
+		},
+		{
+			`{"action": "subscribe", "topic": "control"}`,
+			`control error  "unknown topic 'control'"`,
+		},
+		{
+			`{"action": "join", "topic": "cpu"}`,
+			`control error  "unknown action 'join'"`,
+		},
+		{
+			`subscribe cpu`,
+			`control error  "invalid request: invalid character 's' looking for beginning of value"`,
+		},
+	}
+	for _, step := range steps {
+		if err := conn.WriteMessage(websocket.TextMessage, []byte(step.request)); err != nil {
+			t.Fatal(err)
+		}
+		if got := receiveMessages(t, conn, 1)[0]; got != step.reply {
+			t.Errorf("reply to %s = %s, want %s", step.request, got, step.reply)
+		}
+	}
+	if h.HasSubscribers(TopicNetworkRate) || h.HasSubscribers(TopicCPU) {
+		t.Error("HasSubscribers() after unsubscribing = true, want false")
+	}
+}
+
+func TestDelivery(t *testing.T) {
+	h, _ := startHub(t, TopicNetworkRate, TopicCPU, TopicMemory, TopicDiskIO, TopicLoad)
+
+	keyed := dial(t, h, Options{Subscriptions: []Subscription{{Topic: TopicNetworkRate, Key: "en0"}, {Topic: TopicCPU}}})
+	waitForSubscribers(t, h, TopicCPU)
+	// Unknown topics are ignored
+	raw := dial(t, h, Options{Subscriptions: []Subscription{{Topic: TopicMemory}, {Topic: "gpu"}}, Raw: true})
+	waitForSubscribers(t, h, TopicMemory)
+	requested := dial(t, h, Options{})
+	for _, request := range []string{
+		`{"action": "subscribe", "topic": "disk.io", "key": "sda"}`,
+		`{"action": "subscribe", "topic": "network.rate"}`,
+	} {
+		if err := requested.WriteMessage(websocket.TextMessage, []byte(request)); err != nil {
+			t.Fatal(err)
+		}
+		receiveMessages(t, requested, 1)
+	}
+	if err := raw.WriteMessage(websocket.TextMessage, []byte(`{"action": "subscribe", "topic": "gpu"}`)); err != nil {
+		t.Fatal(err)
+	}
+
+	// Every client's last message comes after all the ones it must skip
+	for _, m := range []struct{ topic, key, data string }{
+		{TopicNetworkRate, "en1", "a"},
+		{TopicNetworkRate, "en0", "b"},
+		{TopicMemory, "", "c"},
+		{TopicDiskIO, "sdb", "d"},
+		{TopicCPU, "", "e"},
+		{TopicLoad, "", "f"},
+		{TopicDiskIO, "sda", "g"},
+		{TopicCPU, "", "h"},
+		{TopicMemory, "", "i"},
+	} {
+		h.Publish(m.topic, "test", m.key, m.data)
+	}
+
+	want := []string{`network.rate test en0 "b"`, `cpu test  "e"`, `cpu test  "h"`}
+	if got := receiveMessages(t, keyed, len(want)); !slices.Equal(got, want) {
+		t.Errorf("keyed client received %q, want %q", got, want)
+	}
+	want = []string{`network.rate test en1 "a"`, `network.rate test en0 "b"`, `disk.io test sda "g"`}
+	if got := receiveMessages(t, requested, len(want)); !slices.Equal(got, want) {
+		t.Errorf("client subscribed by request received %q, want %q", got, want)
+	}
+	// Raw clients only receive the data, and no replies
+	var got []string
+	for range 2 {
+		got = append(got, string(receive(t, raw)))
+	}
+	if want := []string{`"c"` + "\n", `"i"` + "\n"}; !slices.Equal(got, want) {
+		t.Errorf("raw client received %q, want %q", got, want)
+	}
+}
+
+func TestShutdown(t *testing.T) {
+	h, cancel := startHub(t, TopicCPU)
+	conn := dial(t, h, Options{Subscriptions: []Subscription{{Topic: TopicCPU}}})
+	waitForSubscribers(t, h, TopicCPU)
+
+	cancel()
+	<-h.done
+	for name, conn := range map[string]*websocket.Conn{
+		"connected":        conn,
+		"connecting after": dial(t, h, Options{}),
+	} {
+		conn.SetReadDeadline(time.Now().Add(time.Second))
+		_, _, err := conn.ReadMessage()
+		var closeErr *websocket.CloseError
+		if !errors.As(err, &closeErr) || closeErr.Code != websocket.CloseGoingAway {
+			t.Errorf("%s client: read error = %v, want a going-away close", name, err)
+		}
+	}
+	// Publishing after shutdown must not block
+	h.Publish(TopicCPU, "test", "", nil)
+}
@@ -0,0 +1,228 @@
+// Package loadstats samples the load average, the number of running and
+// blocked processes and the context switch and interrupt rates, smooths the
+// rates like the network rates and keeps the last hour.
+package loadstats
+
+import (
+	"context"
+	"errors"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/shirou/gopsutil/v3/load"
+	"macos-monitor/backend-go/hub"
+	"macos-monitor/backend-go/platform"
+	"macos-monitor/backend-go/series"
+)
+
+// Options configures the sampling and aggregation of a Sampler.
+type Options struct {
+	SampleInterval time.Duration
+	// MaxSleepInterval is the longest gap between two samples that still
+	// yields a rate; longer gaps are treated as sleep/wake.
+	MaxSleepInterval    time.Duration
+	MovingAverageWindow int
+	HourlyPoints        int
+	HourlyInterval      time.Duration
+	// SchedStats reads the context switch and interrupt counters.
+	SchedStats func() (platform.SchedStats, error)
+}
+
+// Stats is the current load. The context switch and interrupt rates are
+// smoothed, and zero where the platform does not report them.
+type Stats struct {
+	Timestamp    int64   `json:"timestamp"`
+	Load1        float64 `json:"load1"`
+	Load5        float64 `json:"load5"`
+	Load15       float64 `json:"load15"`
+	ProcsTotal   int     `json:"procs_total"`
+	ProcsRunning int     `json:"procs_running"`
+	ProcsBlocked int     `json:"procs_blocked"`
+	// ContextSwitchesPS and InterruptsPS are per second.
+	ContextSwitchesPS float64 `json:"context_switches_ps"`
+	InterruptsPS      float64 `json:"interrupts_ps"`
+}
+
+// HourlyPoint is the average load over one interval.
+type HourlyPoint struct {
+	OffsetMin         int     `json:"offset_min"`
+	Load1             float64 `json:"load1"`
+	ProcsRunning      float64 `json:"procs_running"`
+	ProcsBlocked      float64 `json:"procs_blocked"`
+	ContextSwitchesPS float64 `json:"context_switches_ps"`
+	InterruptsPS      float64 `json:"interrupts_ps"`
+}
+
+// HourlyStats is the load over the last hour.
+type HourlyStats struct {
+	IntervalMin int           `json:"interval_min"`
+	Points      []HourlyPoint `json:"points"`
+}
+
+// Indexes of the values of a sample, in the order of the hourly series.
+const (
+	load1 = iota
+	procsRunning
+	procsBlocked
+	contextSwitchesPS
+	interruptsPS
+	numValues
+)
+
+// Sampler tracks the load and publishes it on the hub.
+type Sampler struct {
+	opts Options
+	hub  *hub.Hub
+
+	// Counters of the previous sample, only accessed by the sampling
+	// goroutine after NewSampler
+	lastSched   platform.SchedStats
+	lastTime    time.Time
+	unsupported bool
+
+	mu              sync.RWMutex
+	stats           Stats
+	contextSwitches *series.MovingAverage
+	interrupts      *series.MovingAverage
+	hourly          *series.RingBuffer
+}
+
+// NewSampler creates a Sampler that publishes the load on h.
+func NewSampler(h *hub.Hub, opts Options) *Sampler {
+	s := &Sampler{
+		opts:            opts,
+		hub:             h,
+		contextSwitches: series.NewMovingAverage(opts.MovingAverageWindow),
+		interrupts:      series.NewMovingAverage(opts.MovingAverageWindow),
+		hourly:          series.NewRingBuffer(opts.HourlyPoints, opts.HourlyInterval, numValues),
+	}
+
+	// Fetch initial counters to establish a baseline
+	sched, err := opts.SchedStats()
+	switch {
+	case errors.Is(err, errors.ErrUnsupported):
+		s.unsupported = true
+	case err != nil:
+		log.Printf("Warning: could not get initial scheduler counters: %v. Will retry.", err)
+	default:
+		s.lastSched, s.lastTime = sched, time.Now()
+	}
+	return s
+}
+
+// Run samples every interval until ctx is done.
+func (s *Sampler) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.opts.SampleInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.performSample()
+		}
+	}
+}
+
+// Stats returns the current load.
+func (s *Sampler) Stats() Stats {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.stats
+}
+
+// GetHourlyStats returns the load over the last hour.
+func (s *Sampler) GetHourlyStats() HourlyStats {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	points := s.hourly.Points()
+	stats := HourlyStats{
+		IntervalMin: int(s.opts.HourlyInterval.Minutes()),
+		Points:      make([]HourlyPoint, 0, len(points)),
+	}
+	for _, p := range points {
+		stats.Points = append(stats.Points, HourlyPoint{
+			OffsetMin:         p.OffsetMin,
+			Load1:             p.Values[load1],
+			ProcsRunning:      p.Values[procsRunning],
+			ProcsBlocked:      p.Values[procsBlocked],
+			ContextSwitchesPS: p.Values[contextSwitchesPS],
+			InterruptsPS:      p.Values[interruptsPS],
+		})
+	}
+	return stats
+}
+
+func (s *Sampler) performSample() {
+	avg, err := load.Avg()
+	if err != nil {
+		log.Printf("Error sampling load average: %v", err)
+		return
+	}
+	misc, err := load.Misc()
+	if err != nil {
+		log.Printf("Error sampling process counts: %v", err)
+		return
+	}
+	now := time.Now()
+
+	var values [numValues]float64
+	values[load1] = avg.Load1
+	values[procsRunning] = float64(misc.ProcsRunning)
+	values[procsBlocked] = float64(misc.ProcsBlocked)
+	ratesOK := s.sampleRates(&values, now)
+
+	s.mu.Lock()
+	s.stats = Stats{
+		Timestamp:    now.Unix(),
+		Load1:        avg.Load1,
+		Load5:        avg.Load5,
+		Load15:       avg.Load15,
+		ProcsTotal:   misc.ProcsTotal,
+		ProcsRunning: misc.ProcsRunning,
+		ProcsBlocked: misc.ProcsBlocked,
+	}
+	if ratesOK {
+		s.stats.ContextSwitchesPS = s.contextSwitches.Add(values[contextSwitchesPS])
+		s.stats.InterruptsPS = s.interrupts.Add(values[interruptsPS])
+	} else {
+		// Reset moving averages to avoid a spike on the next valid sample
+		s.contextSwitches.Reset()
+		s.interrupts.Reset()
+	}
+	// Samples after a gap would count zero rates into the hour
+	if ratesOK || s.unsupported {
+		s.hourly.Add(values[:]...)
+	}
+	stats := s.stats
+	s.mu.Unlock()
+
+	s.hub.Publish(hub.TopicLoad, "load", "", stats)
+}
+
+// sampleRates stores the context switch and interrupt rates since the
+// previous sample in values. It reports false when no rate could be
+// calculated for this sample.
+func (s *Sampler) sampleRates(values *[numValues]float64, now time.Time) bool {
+	if s.unsupported {
+		return false
+	}
+	sched, err := s.opts.SchedStats()
+	if err != nil {
+		log.Printf("Error sampling scheduler counters: %v", err)
+		return false
+	}
+	deltaT := now.Sub(s.lastTime).Seconds()
+	last, lastTime := s.lastSched, s.lastTime
+	s.lastSched, s.lastTime = sched, now
+	// Check for sleep/wake or initial sample
+	if lastTime.IsZero() || deltaT <= 0 || deltaT > s.opts.MaxSleepInterval.Seconds() {
+		return false
+	}
+	values[contextSwitchesPS] = series.CounterRate(last.ContextSwitches, sched.ContextSwitches, deltaT)
+	values[interruptsPS] = series.CounterRate(last.Interrupts, sched.Interrupts, deltaT)
+	return true
+}
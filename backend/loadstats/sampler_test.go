@@ -0,0 +1,73 @@
+package loadstats
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"macos-monitor/backend-go/platform"
+)
+
+func TestSampleRates(t *testing.T) {
+	var (
+		sched platform.SchedStats
+		err   = errors.New("not read yet")
+	)
+	s := NewSampler(nil, Options{
+		MaxSleepInterval:    10 * time.Second,
+		MovingAverageWindow: 2,
+		HourlyPoints:        60,
+		HourlyInterval:      time.Minute,
+		SchedStats:          func() (platform.SchedStats, error) { return sched, err },
+	})
+	start := time.Date(2026, 3, 14, 9, 0, 0, 0, time.UTC)
+
+	steps := []struct {
+		name                    string
+		at                      time.Duration
+		sched                   platform.SchedStats
+		err                     error
+		ok                      bool
+		switchesPS, interruptPS float64
+	}{
+		{"first sample", 0, platform.SchedStats{ContextSwitches: 1000, Interrupts: 500}, nil, false, 0, 0},
+		{"rates", 2 * time.Second, platform.SchedStats{ContextSwitches: 3000, Interrupts: 1500}, nil, true, 1000, 500},
+		{"counter going backwards", 3 * time.Second, platform.SchedStats{ContextSwitches: 100, Interrupts: 2000}, nil, true, 0, 500},
+		{"no time elapsed", 3 * time.Second, platform.SchedStats{ContextSwitches: 200, Interrupts: 2100}, nil, false, 0, 0},
+		{"gap over the max sleep interval", 14 * time.Second, platform.SchedStats{ContextSwitches: 900, Interrupts: 9000}, nil, false, 0, 0},
+		{"after the gap", 15 * time.Second, platform.SchedStats{ContextSwitches: 1900, Interrupts: 9100}, nil, true, 1000, 100},
+		{"unreadable counters", 16 * time.Second, platform.SchedStats{}, errors.New("permission denied"), false, 0, 0},
+		// Rates continue from the last counters read
+		{"after an error", 17 * time.Second, platform.SchedStats{ContextSwitches: 2900, Interrupts: 9300}, nil, true, 500, 100},
+	}
+	for _, step := range steps {
+		sched, err = step.sched, step.err
+		var values [numValues]float64
+		ok := s.sampleRates(&values, start.Add(step.at))
+		if ok != step.ok || values[contextSwitchesPS] != step.switchesPS || values[interruptsPS] != step.interruptPS {
+			t.Errorf("%s: sampleRates() = %v with %v, %v per second, want %v with %v, %v", step.name,
+				ok, values[contextSwitchesPS], values[interruptsPS], step.ok, step.switchesPS, step.interruptPS)
+		}
+	}
+}
+
+func TestSampleRatesUnsupported(t *testing.T) {
+	var reads int
+	s := NewSampler(nil, Options{
+		MovingAverageWindow: 2,
+		HourlyPoints:        60,
+		HourlyInterval:      time.Minute,
+		SchedStats: func() (platform.SchedStats, error) {
+			reads++
+			return platform.SchedStats{}, fmt.Errorf("context switch and interrupt counters: %w", errors.ErrUnsupported)
+		},
+	})
+	var values [numValues]float64
+	if s.sampleRates(&values, time.Now()) || s.sampleRates(&values, time.Now().Add(time.Second)) {
+		t.Error("sampleRates() on an unsupported platform = true, want false")
+	}
+	if reads != 1 {
+		t.Errorf("counters read %d times, want only once by NewSampler", reads)
+	}
+}
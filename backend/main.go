@@ -1,81 +1,372 @@
 package main
 
 import (
+	"context"
+	"crypto/tls"
 	"encoding/json"
+	"errors"
+	"flag"
 	"fmt"
 	"log"
 	"net"
 	"net/http"
-	"os/exec"
-	"sort"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strconv"
 	"strings"
+	"sync"
+	"syscall"
 	"time"
 
 	"github.com/shirou/gopsutil/v3/cpu"
 	"github.com/shirou/gopsutil/v3/disk"
 	"github.com/shirou/gopsutil/v3/host"
 	"github.com/shirou/gopsutil/v3/mem"
-	"github.com/shirou/gopsutil/v3/process"
+	"macos-monitor/backend-go/alert"
+	"macos-monitor/backend-go/auth"
+	"macos-monitor/backend-go/battery"
+	"macos-monitor/backend-go/certs"
+	"macos-monitor/backend-go/config"
+	"macos-monitor/backend-go/cpustats"
+	"macos-monitor/backend-go/diskio"
+	"macos-monitor/backend-go/disks"
+	"macos-monitor/backend-go/history"
+	"macos-monitor/backend-go/hub"
+	"macos-monitor/backend-go/loadstats"
+	"macos-monitor/backend-go/memstats"
+	"macos-monitor/backend-go/netprocs"
 	"macos-monitor/backend-go/network"
+	"macos-monitor/backend-go/platform"
+	"macos-monitor/backend-go/procs"
+	"macos-monitor/backend-go/sensors"
+	"macos-monitor/backend-go/storage"
+)
+
+// topProcessCount is the number of process groups in the dynamic system info.
+const topProcessCount = 5
+
+// shutdownTimeout bounds how long in-flight requests may take to finish on
+// shutdown.
+const shutdownTimeout = 10 * time.Second
+
+// Files of the generated self-signed certificate, next to the database.
+const (
+	selfSignedCertFile = "tls_cert.pem"
+	selfSignedKeyFile  = "tls_key.pem"
 )
 
 func main() {
+	cfg, printConfig, err := config.Load(os.Args[1:])
+	if err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return
+		}
+		log.Fatalf("Invalid configuration: %v", err)
+	}
+	if printConfig {
+		out, err := cfg.YAML()
+		if err != nil {
+			log.Fatalf("Failed to print configuration: %v", err)
+		}
+		os.Stdout.Write(out)
+		return
+	}
+
+	db, err := storage.Open(cfg.Database.Path)
+	if err != nil {
+		log.Fatalf("Failed to open database: %v", err)
+	}
+
+	netDB, err := network.NewDBManager(db)
+	if err != nil {
+		log.Fatalf("Failed to initialize database manager: %v", err)
+	}
+
+	// WebSocket clients subscribe to topics on the hub
+	wsHub := hub.NewHub(hub.TopicNetworkRate, hub.TopicSystemDynamic, hub.TopicProcessesTop, hub.TopicAlerts, hub.TopicDiskIO, hub.TopicCPU, hub.TopicMemory, hub.TopicLoad, hub.TopicSensors, hub.TopicBattery)
+
 	// Initialize the network monitor
-	netMonitor, err := network.NewMonitor()
+	netMonitor, err := network.NewMonitor(netDB, wsHub, network.Options{
+		Interfaces:          cfg.Network.Interfaces,
+		SampleInterval:      cfg.Network.SampleInterval.Std(),
+		PersistenceInterval: cfg.Network.PersistenceInterval.Std(),
+		MaxSleepInterval:    cfg.Network.MaxSleepInterval.Std(),
+		MovingAverageWindow: cfg.Network.MovingAverageWindow,
+		HourlyPoints:        cfg.Network.HourlyPoints,
+		HourlyInterval:      cfg.Network.HourlyInterval.Std(),
+		Quotas:              quotas(cfg.Quotas),
+	})
 	if err != nil {
 		log.Fatalf("Failed to initialize network monitor: %v", err)
 	}
-	netMonitor.Start()
-	defer netMonitor.Close()
-
-	// Setup CORS
-	corsHandler := func(h http.Handler) http.Handler {
-		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			w.Header().Set("Access-Control-Allow-Origin", "*")
-			w.Header().Set("Access-Control-Allow-Methods", "GET, POST, OPTIONS")
-			w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
-			if r.Method == "OPTIONS" {
-				w.WriteHeader(http.StatusOK)
-				return
-			}
-			h.ServeHTTP(w, r)
-		})
+
+	// Sample CPU usage in the background so every client sees the same
+	// interval
+	cpuSampler := cpustats.NewSampler(wsHub, cfg.CPU.SampleInterval.Std())
+	memSampler := memstats.NewSampler(wsHub, cfg.Memory.SampleInterval.Std(), platform.Current().VMStats)
+
+	// Sample the load and scheduler rates like network rates
+	loadSampler := loadstats.NewSampler(wsHub, loadstats.Options{
+		SampleInterval:      cfg.Load.SampleInterval.Std(),
+		MaxSleepInterval:    cfg.Load.MaxSleepInterval.Std(),
+		MovingAverageWindow: cfg.Load.MovingAverageWindow,
+		HourlyPoints:        cfg.Load.HourlyPoints,
+		HourlyInterval:      cfg.Load.HourlyInterval.Std(),
+		SchedStats:          platform.Current().SchedStats,
+	})
+
+	// Sample disk throughput like network rates
+	diskIO := diskio.NewSampler(wsHub, diskio.Options{
+		Devices:             cfg.Disks.IO.Devices,
+		SampleInterval:      cfg.Disks.IO.SampleInterval.Std(),
+		MaxSleepInterval:    cfg.Disks.IO.MaxSleepInterval.Std(),
+		MovingAverageWindow: cfg.Disks.IO.MovingAverageWindow,
+		HourlyPoints:        cfg.Disks.IO.HourlyPoints,
+		HourlyInterval:      cfg.Disks.IO.HourlyInterval.Std(),
+	})
+
+	// Record CPU, memory, swap, disk and load history in the background
+	historyStore, err := history.NewStore(db, history.Retention{
+		Raw:    cfg.History.RawRetention.Std(),
+		Minute: cfg.History.MinuteRetention.Std(),
+		Hour:   cfg.History.HourRetention.Std(),
+		Day:    cfg.History.DayRetention.Std(),
+	})
+	if err != nil {
+		log.Fatalf("Failed to initialize system history: %v", err)
+	}
+	diskFilter := disks.Filter{Include: cfg.Disks.Include, Exclude: cfg.Disks.Exclude}
+	historySampler := history.NewSampler(historyStore, cfg.History.SampleInterval.Std(), platform.Current().PrimaryDisk(), diskFilter, cpuSampler, memSampler)
+
+	// Read temperature sensors and record their daily range
+	sensorStore, err := sensors.NewStore(db)
+	if err != nil {
+		log.Fatalf("Failed to initialize sensor store: %v", err)
 	}
+	var sensorSource sensors.Source = sensors.HostSource{}
+	if cfg.Sensors.HwmonDir != "" {
+		sensorSource = sensors.HwmonSource{Dir: cfg.Sensors.HwmonDir}
+	}
+	sensorCollector := sensors.NewCollector(sensorSource, sensorStore, wsHub, cfg.Sensors.SampleInterval.Std())
 
-	http.HandleFunc("/api/system/static", staticSystemInfoHandler)
-	http.HandleFunc("/api/system/dynamic", dynamicSystemInfoHandler)
+	// Read the battery and record its charge in the history
+	batteryCollector := battery.NewCollector(battery.NewReader(), historyStore, wsHub, battery.Options{
+		Interval:        cfg.Battery.SampleInterval.Std(),
+		LowPercent:      cfg.Battery.LowPercent,
+		CriticalPercent: cfg.Battery.CriticalPercent,
+	})
 
-	// New network handlers
-	http.HandleFunc("/api/network/daily", networkDailyHandler(netMonitor))
-	http.HandleFunc("/api/network/hourly", networkHourlyHandler(netMonitor))
-	http.HandleFunc("/ws/network/realtime", func(w http.ResponseWriter, r *http.Request) {
-		network.ServeWs(netMonitor.Hub(), w, r)
+	// Sample processes in the background so CPU usage covers a whole interval
+	procCollector := procs.NewCollector(procs.Options{
+		Interval: cfg.Processes.SampleInterval.Std(),
+		Identify: platform.Current().Identify,
 	})
 
+	// Attribute network usage to processes; where only connections can be
+	// counted, the total rate of all interfaces is split by them
+	netProcs := netprocs.NewSampler(netprocs.NewSource(), netprocs.Options{
+		Interval: cfg.Network.Processes.SampleInterval.Std(),
+		Identify: platform.Current().ProcessGroup,
+		TotalRate: func() (float64, float64) {
+			rate, _ := netMonitor.GetRealtimeRate(network.AllInterfaces)
+			return rate.DownBPS, rate.UpBPS
+		},
+	})
 
-	fmt.Println("Server starting on :8000")
-	log.Fatal(http.ListenAndServe(":8000", corsHandler(http.DefaultServeMux)))
+	auditStore, err := procs.NewAuditStore(db)
+	if err != nil {
+		log.Fatalf("Failed to initialize process audit log: %v", err)
+	}
+	procController := procs.NewController(procCollector, auditStore, procs.Policy{
+		Allow: cfg.Control.Allow,
+		Deny:  cfg.Control.Deny,
+	})
+
+	// Evaluate alert rules against the system, battery and network samples
+	alertEngine, err := newAlertEngine(cfg.Alerts, db)
+	if err != nil {
+		log.Fatalf("Failed to initialize alerting: %v", err)
+	}
+	observeSamples := func(t time.Time, samples []history.Sample) {
+		for _, s := range samples {
+			alertEngine.Observe(s.Metric, s.Label, s.Value, t)
+		}
+	}
+	historySampler.AddObserver(observeSamples)
+	batteryCollector.AddObserver(observeSamples)
+	netMonitor.AddRateObserver(func(rate network.RealtimeRate) {
+		observeRate(alertEngine, rate)
+	})
+	alertEngine.AddListener(func(event alert.Event) {
+		wsHub.Publish(hub.TopicAlerts, "alert", event.Rule, event)
+	})
+
+	authn, err := newAuthenticator(cfg.Auth)
+	if err != nil {
+		log.Fatalf("Failed to initialize authentication: %v", err)
+	}
+	if !authn.Enabled() {
+		log.Printf("Warning: no API tokens or users are configured, so anyone who can reach %s can read the API; process control is disabled", cfg.Server.Listen)
+	}
+	origins := auth.NewOrigins(cfg.Auth.AllowedOrigins)
+
+	server := &http.Server{
+		Addr:    cfg.Server.Listen,
+		Handler: origins.CORS(http.DefaultServeMux),
+	}
+	if cfg.Server.TLS.Enabled {
+		if server.TLSConfig, err = tlsConfig(cfg); err != nil {
+			log.Fatalf("Failed to configure TLS: %v", err)
+		}
+	}
+
+	// Everything runs until SIGINT or SIGTERM
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	var loops sync.WaitGroup
+	run := func(fn func(context.Context)) {
+		loops.Add(1)
+		go func() {
+			defer loops.Done()
+			fn(ctx)
+		}()
+	}
+	run(wsHub.Run)
+	run(netMonitor.Run)
+	run(cpuSampler.Run)
+	run(memSampler.Run)
+	run(loadSampler.Run)
+	run(sensorCollector.Run)
+	run(batteryCollector.Run)
+	run(diskIO.Run)
+	run(historySampler.Run)
+	run(procCollector.Run)
+	run(netProcs.Run)
+	run(func(ctx context.Context) {
+		publishLoop(ctx, wsHub, cpuSampler, memSampler, procCollector, cfg.Server.PushInterval.Std())
+	})
+
+	read := func(h http.HandlerFunc) http.Handler {
+		return authn.Require(auth.ScopeRead, h)
+	}
+	admin := func(h http.HandlerFunc) http.Handler {
+		return authn.Require(auth.ScopeAdmin, h)
+	}
+
+	http.Handle("/api/system/static", read(staticSystemInfoHandler))
+	http.Handle("/api/system/dynamic", read(dynamicSystemInfoHandler(cpuSampler, memSampler, procCollector)))
+	http.Handle("/api/system/history", read(systemHistoryHandler(historyStore, cfg.History.SampleInterval.Std())))
+	http.Handle("/api/cpu", read(cpuHandler(cpuSampler)))
+	http.Handle("/api/memory", read(memoryHandler(memSampler)))
+	http.Handle("/api/load", read(loadHandler(loadSampler)))
+	http.Handle("/api/load/hourly", read(loadHourlyHandler(loadSampler)))
+	http.Handle("/api/sensors", read(sensorsHandler(sensorCollector)))
+	http.Handle("/api/sensors/daily", read(sensorsDailyHandler(sensorCollector)))
+	http.Handle("/api/battery", read(batteryHandler(batteryCollector)))
+	http.Handle("/api/disks", read(disksHandler(diskFilter)))
+	http.Handle("/api/disks/io", read(diskIOHandler(diskIO)))
+	http.Handle("/api/disks/io/hourly", read(diskIOHourlyHandler(diskIO)))
+
+	// New network handlers
+	http.Handle("/api/network/daily", read(networkDailyHandler(netMonitor)))
+	http.Handle("/api/network/hourly", read(networkHourlyHandler(netMonitor)))
+	http.Handle("/api/network/interfaces", read(networkInterfacesHandler(netMonitor)))
+	http.Handle("/api/network/quota", read(networkQuotaHandler(netMonitor)))
+	http.Handle("/api/network/processes", read(networkProcessesHandler(netProcs)))
+	http.Handle("/api/processes", read(processesHandler(procCollector)))
+	http.Handle("/api/processes/events", read(processEventsHandler(procCollector)))
+	http.Handle("/api/processes/{pid}", read(processDetailHandler(procCollector)))
+	http.Handle("/api/processes/audit", admin(processAuditHandler(procController)))
+	http.Handle("POST /api/processes/{pid}/signal", admin(processControlHandler(procController, parseSignal)))
+	http.Handle("POST /api/processes/{pid}/renice", admin(processControlHandler(procController, parseRenice)))
+	http.Handle("POST /api/processes/groups/{name}/signal", admin(processGroupControlHandler(procController, parseSignal)))
+	http.Handle("POST /api/processes/groups/{name}/renice", admin(processGroupControlHandler(procController, parseRenice)))
+	http.Handle("/api/alerts", read(alertsHandler(alertEngine)))
+	http.Handle("/api/alerts/history", read(alertHistoryHandler(alertEngine)))
+	http.Handle("/metrics", read(metricsHandler(netMonitor, cpuSampler, memSampler, loadSampler, sensorCollector, batteryCollector, procCollector, diskFilter, cfg.Processes.MetricGroups)))
+	http.Handle("/ws", read(func(w http.ResponseWriter, r *http.Request) {
+		var subs []hub.Subscription
+		for _, topic := range strings.Split(r.URL.Query().Get("topics"), ",") {
+			if topic != "" {
+				subs = append(subs, hub.Subscription{Topic: topic})
+			}
+		}
+		hub.ServeWs(wsHub, w, r, hub.Options{Subscriptions: subs, CheckOrigin: origins.Allowed})
+	}))
+	// Streams the bare rates of one interface, as before topics existed
+	http.Handle("/ws/network/realtime", read(func(w http.ResponseWriter, r *http.Request) {
+		iface, ok := interfaceParam(netMonitor, w, r)
+		if !ok {
+			return
+		}
+		hub.ServeWs(wsHub, w, r, hub.Options{
+			Subscriptions: []hub.Subscription{{Topic: hub.TopicNetworkRate, Key: iface}},
+			Raw:           true,
+			CheckOrigin:   origins.Allowed,
+		})
+	}))
+
+	serverErr := make(chan error, 1)
+	go func() {
+		if cfg.Server.TLS.Enabled {
+			fmt.Printf("Server starting on %s with TLS\n", cfg.Server.Listen)
+			serverErr <- server.ListenAndServeTLS("", "")
+		} else {
+			fmt.Printf("Server starting on %s\n", cfg.Server.Listen)
+			serverErr <- server.ListenAndServe()
+		}
+	}()
+
+	exitCode := 0
+	select {
+	case <-ctx.Done():
+		log.Printf("Shutting down")
+	case err := <-serverErr:
+		log.Printf("Server failed: %v", err)
+		exitCode = 1
+	}
+	// A second signal terminates immediately.
+	stop()
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		log.Printf("Error shutting down server: %v", err)
+	}
+	cancel()
+	loops.Wait()
+	if err := db.Close(); err != nil {
+		log.Printf("Error closing database: %v", err)
+	}
+	os.Exit(exitCode)
 }
 
 type staticSystemInfo struct {
-	OSVersion       string    `json:"os_version"`
-	CPUInfo         string    `json:"cpu_info"`
-	CPUCores        int       `json:"cpu_cores"`
-	CPULogicalCores int       `json:"cpu_logical_cores"`
-	TotalMemory     uint64    `json:"total_memory"`
-	TotalDisk       uint64    `json:"total_disk"`
-	LocalIP         string    `json:"local_ip"`
-	BootTime        time.Time `json:"boot_time"`
-	UptimeSeconds   float64   `json:"uptime_seconds"`
+	OSVersion        string    `json:"os_version"`
+	CPUInfo          string    `json:"cpu_info"`
+	CPUCores         int       `json:"cpu_cores"`
+	CPULogicalCores  int       `json:"cpu_logical_cores"`
+	TotalMemory      uint64    `json:"total_memory"`
+	TotalDisk        uint64    `json:"total_disk"`
+	LocalIP          string    `json:"local_ip"`
+	DefaultInterface string    `json:"default_interface"`
+	BootTime         time.Time `json:"boot_time"`
+	UptimeSeconds    float64   `json:"uptime_seconds"`
 }
 
 type dynamicSystemInfo struct {
-	CPUPercent    float64     `json:"cpu_percent"`
-	MemoryPercent float64     `json:"memory_percent"`
-	MemoryUsed    uint64      `json:"memory_used"`
-	DiskPercent   float64     `json:"disk_percent"`
-	DiskUsed      uint64      `json:"disk_used"`
-	Processes     []procInfo  `json:"processes"`
+	CPUPercent    float64            `json:"cpu_percent"`
+	CPUBreakdown  cpustats.Breakdown `json:"cpu_breakdown"`
+	CPUCores      []cpustats.Core    `json:"cpu_cores"`
+	MemoryPercent float64            `json:"memory_percent"`
+	MemoryUsed    uint64             `json:"memory_used"`
+	Memory        memstats.Memory    `json:"memory"`
+	Swap          memstats.Swap      `json:"swap"`
+	Pressure      memstats.Pressure  `json:"memory_pressure"`
+	DiskPercent   float64            `json:"disk_percent"`
+	DiskUsed      uint64             `json:"disk_used"`
+	Processes     []procInfo         `json:"processes"`
 }
 
 type procInfo struct {
@@ -85,14 +376,6 @@ type procInfo struct {
 	MemoryRss  uint64  `json:"memory_rss"`
 }
 
-func getMacOSVersion() string {
-	productVersion, err := exec.Command("sw_vers", "-productVersion").Output()
-	if err != nil {
-		return "N/A"
-	}
-	return strings.TrimSpace(string(productVersion))
-}
-
 func getLocalIP() string {
 	conn, err := net.Dial("udp", "8.8.8.8:80")
 	if err != nil {
@@ -106,9 +389,10 @@ func getLocalIP() string {
 func staticSystemInfoHandler(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 
+	plat := platform.Current()
 	cpuInfo, _ := cpu.Info()
 	memInfo, _ := mem.VirtualMemory()
-	diskInfo, _ := disk.Usage("/")
+	diskInfo, _ := disk.Usage(plat.PrimaryDisk())
 	hostInfo, _ := host.Info()
 	bootTime := time.Unix(int64(hostInfo.BootTime), 0)
 	uptime := time.Since(bootTime).Seconds()
@@ -117,135 +401,275 @@ func staticSystemInfoHandler(w http.ResponseWriter, r *http.Request) {
 	logicalCores, _ := cpu.Counts(true)
 
 	info := staticSystemInfo{
-		OSVersion:       getMacOSVersion(),
-		CPUInfo:         cpuInfo[0].ModelName,
-		CPUCores:        physicalCores,
-		CPULogicalCores: logicalCores,
-		TotalMemory:     memInfo.Total,
-		TotalDisk:       diskInfo.Total,
-		LocalIP:         getLocalIP(),
-		BootTime:        bootTime,
-		UptimeSeconds:   uptime,
+		OSVersion:        plat.OSVersion(),
+		CPUInfo:          cpuInfo[0].ModelName,
+		CPUCores:         physicalCores,
+		CPULogicalCores:  logicalCores,
+		TotalMemory:      memInfo.Total,
+		TotalDisk:        diskInfo.Total,
+		LocalIP:          getLocalIP(),
+		DefaultInterface: plat.DefaultInterface(),
+		BootTime:         bootTime,
+		UptimeSeconds:    uptime,
 	}
 
 	json.NewEncoder(w).Encode(info)
 }
 
-// getProcessIdentifier aggregates processes by their parent .app bundle if applicable.
-func getProcessIdentifier(p *process.Process) string {
-	exe, err := p.Exe()
-	if err != nil {
-		// Fallback to name on error
-		name, _ := p.Name()
-		return name
+// collectDynamicSystemInfo samples disk usage along with the latest CPU
+// usage of cs, memory usage of ms and processes of pc grouped by by, sorted
+// by CPU usage.
+func collectDynamicSystemInfo(cs *cpustats.Sampler, ms *memstats.Sampler, pc *procs.Collector, by procs.GroupBy) dynamicSystemInfo {
+	cpuUsage := cs.Snapshot()
+	memUsage := ms.Snapshot()
+	diskInfo, _ := disk.Usage(platform.Current().PrimaryDisk())
+
+	groups := pc.Groups(by)
+	processes := make([]procInfo, 0, len(groups))
+	for _, g := range groups {
+		processes = append(processes, procInfo{
+			Pid:        g.Pid,
+			Name:       g.Name,
+			CPUPercent: g.CPUPercent,
+			MemoryRss:  g.MemoryRss,
+		})
 	}
 
-	// Check if the process is part of a macOS .app bundle
-	idx := strings.Index(exe, ".app/")
-	if idx == -1 {
-		// Not in a bundle, use the process name
-		name, _ := p.Name()
-		return name
+	return dynamicSystemInfo{
+		CPUPercent:    cpuUsage.UsagePercent,
+		CPUBreakdown:  cpuUsage.Breakdown,
+		CPUCores:      cpuUsage.Cores,
+		MemoryPercent: memUsage.Memory.UsedPercent,
+		MemoryUsed:    memUsage.Memory.Used,
+		Memory:        memUsage.Memory,
+		Swap:          memUsage.Swap,
+		Pressure:      memUsage.Pressure,
+		DiskPercent:   diskInfo.UsedPercent,
+		DiskUsed:      diskInfo.Used,
+		Processes:     processes,
 	}
+}
 
-	// Full path to the app bundle, e.g., "/Applications/Google Chrome.app"
-	bundlePath := exe[:idx+4]
+// dynamicSystemInfoHandler serves the current usage with the top process
+// groups, grouped by the "group_by" parameter (app, cgroup, user, container
+// or none; app by default).
+func dynamicSystemInfoHandler(cs *cpustats.Sampler, ms *memstats.Sampler, pc *procs.Collector) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		by, err := procs.ParseGroupBy(r.URL.Query().Get("group_by"))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+
+		info := collectDynamicSystemInfo(cs, ms, pc, by)
+		if len(info.Processes) > topProcessCount {
+			info.Processes = info.Processes[:topProcessCount]
+		}
 
-	// Get the base name, e.g., "Google Chrome.app"
-	lastSlash := strings.LastIndex(bundlePath, "/")
-	if lastSlash == -1 {
-		// Fallback for unexpected paths
-		return strings.TrimSuffix(bundlePath, ".app")
+		json.NewEncoder(w).Encode(info)
 	}
-	baseName := bundlePath[lastSlash+1:]
+}
 
-	// Trim the .app suffix to get "Google Chrome"
-	return strings.TrimSuffix(baseName, ".app")
+// interfaceParam returns the interface selected by the "iface" query
+// parameter, defaulting to the aggregate of all interfaces. It writes a 404
+// and reports false if the interface is not monitored.
+func interfaceParam(m *network.Monitor, w http.ResponseWriter, r *http.Request) (string, bool) {
+	iface := r.URL.Query().Get("iface")
+	if iface == "" {
+		iface = network.AllInterfaces
+	}
+	if !m.HasInterface(iface) {
+		http.Error(w, fmt.Sprintf("Unknown network interface '%s'", iface), http.StatusNotFound)
+		return "", false
+	}
+	return iface, true
 }
 
-func dynamicSystemInfoHandler(w http.ResponseWriter, r *http.Request) {
-	w.Header().Set("Content-Type", "application/json")
+// maxTrafficRangeDays bounds the date range of a daily traffic query.
+const maxTrafficRangeDays = 3660
 
-	cpuPercent, err := cpu.Percent(0, false)
-	if err != nil || len(cpuPercent) == 0 {
-		log.Printf("Could not fetch CPU percentage: %v", err)
-		http.Error(w, "Could not fetch CPU percentage", http.StatusInternalServerError)
-		return
-	}
-	memInfo, _ := mem.VirtualMemory()
-	diskInfo, _ := disk.Usage("/")
+// networkDailyHandler serves the last 7 days of traffic or, when any of the
+// "from", "to" (YYYY-MM-DD) or "group" (day, week, month, year) parameters
+// is given, the traffic of that date range. The range defaults to the 7
+// days ending today.
+func networkDailyHandler(m *network.Monitor) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		iface, ok := interfaceParam(m, w, r)
+		if !ok {
+			return
+		}
 
-	procs, _ := process.Processes()
+		q := r.URL.Query()
+		if !q.Has("from") && !q.Has("to") && !q.Has("group") {
+			w.Header().Set("Content-Type", "application/json")
+			stats, err := m.GetStats(iface)
+			if err != nil {
+				http.Error(w, "Could not retrieve network stats", http.StatusInternalServerError)
+				log.Printf("Error getting network stats: %v", err)
+				return
+			}
+			json.NewEncoder(w).Encode(stats)
+			return
+		}
 
-	// Aggregate processes by app bundle
-	aggregatedProcs := make(map[string]procInfo)
-	for _, p := range procs {
-		// Get CPU and memory info first
-		procCpuPercent, err := p.CPUPercent()
+		group, err := network.ParseGrouping(q.Get("group"))
 		if err != nil {
-			continue
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
 		}
-		procMemInfo, err := p.MemoryInfo()
-		if err != nil {
-			continue
+		to := time.Now()
+		if v := q.Get("to"); v != "" {
+			if to, err = time.ParseInLocation("2006-01-02", v, time.Local); err != nil {
+				http.Error(w, "Invalid 'to', expected YYYY-MM-DD", http.StatusBadRequest)
+				return
+			}
+		}
+		from := to.AddDate(0, 0, -6)
+		if v := q.Get("from"); v != "" {
+			if from, err = time.ParseInLocation("2006-01-02", v, time.Local); err != nil {
+				http.Error(w, "Invalid 'from', expected YYYY-MM-DD", http.StatusBadRequest)
+				return
+			}
+		}
+		if from.Format("2006-01-02") > to.Format("2006-01-02") {
+			http.Error(w, "'from' must not be after 'to'", http.StatusBadRequest)
+			return
+		}
+		if to.Sub(from) > maxTrafficRangeDays*24*time.Hour {
+			http.Error(w, fmt.Sprintf("Date range must not exceed %d days", maxTrafficRangeDays), http.StatusBadRequest)
+			return
 		}
 
-		// Determine the aggregation key (app bundle or process name)
-		key := getProcessIdentifier(p)
-
-		// Aggregate the stats
-		data := aggregatedProcs[key]
-		data.Pid = p.Pid // Use the PID of the most recently seen process in the group
-		data.Name = key
-		data.CPUPercent += procCpuPercent
-		data.MemoryRss += procMemInfo.RSS
-		aggregatedProcs[key] = data
-	}
+		trafficRange, err := m.GetTrafficRange(iface, from, to, group)
+		if err != nil {
+			http.Error(w, "Could not retrieve network stats", http.StatusInternalServerError)
+			log.Printf("Error getting network traffic range: %v", err)
+			return
+		}
+		sinceBoot, err := m.GetSinceBootTraffic(iface)
+		if err != nil {
+			http.Error(w, "Could not retrieve network stats", http.StatusInternalServerError)
+			log.Printf("Error getting since-boot traffic: %v", err)
+			return
+		}
 
-	// Convert map to slice for sorting
-	processes := make([]procInfo, 0, len(aggregatedProcs))
-	for _, pi := range aggregatedProcs {
-		processes = append(processes, pi)
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(network.Stats{
+			Interface: iface,
+			Range:     &trafficRange,
+			SinceBoot: sinceBoot,
+		})
 	}
+}
 
-	sort.Slice(processes, func(i, j int) bool {
-		return processes[i].CPUPercent > processes[j].CPUPercent
-	})
-
-	topProcesses := processes
-	if len(processes) > 5 {
-		topProcesses = processes[:5]
+func networkHourlyHandler(m *network.Monitor) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		iface, ok := interfaceParam(m, w, r)
+		if !ok {
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		stats, err := m.GetHourlyStats(iface)
+		if err != nil {
+			http.Error(w, "Could not retrieve hourly network stats", http.StatusInternalServerError)
+			log.Printf("Error getting hourly network stats: %v", err)
+			return
+		}
+		json.NewEncoder(w).Encode(stats)
 	}
+}
 
-	info := dynamicSystemInfo{
-		CPUPercent:    cpuPercent[0],
-		MemoryPercent: memInfo.UsedPercent,
-		MemoryUsed:    memInfo.Used,
-		DiskPercent:   diskInfo.UsedPercent,
-		DiskUsed:      diskInfo.Used,
-		Processes:     topProcesses,
+func networkInterfacesHandler(m *network.Monitor) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(m.Interfaces())
 	}
-
-	json.NewEncoder(w).Encode(info)
 }
 
-func networkDailyHandler(m *network.Monitor) http.HandlerFunc {
+func networkQuotaHandler(m *network.Monitor) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		w.Header().Set("Content-Type", "application/json")
-		stats, err := m.GetStats()
+		statuses, err := m.GetQuotaStatuses()
 		if err != nil {
-			http.Error(w, "Could not retrieve network stats", http.StatusInternalServerError)
-			log.Printf("Error getting network stats: %v", err)
+			http.Error(w, "Could not retrieve quota status", http.StatusInternalServerError)
+			log.Printf("Error getting quota status: %v", err)
 			return
 		}
-		json.NewEncoder(w).Encode(stats)
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(statuses)
 	}
 }
 
-func networkHourlyHandler(m *network.Monitor) http.HandlerFunc {
+func networkProcessesHandler(s *netprocs.Sampler) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		grouped := false
+		if v := q.Get("grouped"); v != "" {
+			var err error
+			if grouped, err = strconv.ParseBool(v); err != nil {
+				http.Error(w, "Invalid 'grouped', expected true or false", http.StatusBadRequest)
+				return
+			}
+		}
+		limit, ok := intParam(w, q.Get("limit"), "limit")
+		if !ok {
+			return
+		}
+
+		snapshot := s.Snapshot()
+		if grouped {
+			snapshot = s.Grouped()
+		}
+		snapshot.Processes = page(snapshot.Processes, 0, limit)
 		w.Header().Set("Content-Type", "application/json")
-		stats := m.GetHourlyStats()
-		json.NewEncoder(w).Encode(stats)
+		json.NewEncoder(w).Encode(snapshot)
+	}
+}
+
+// tlsConfig builds the server's TLS configuration. Without a configured
+// certificate, a self-signed one is kept next to the database.
+func tlsConfig(cfg config.Config) (*tls.Config, error) {
+	t := cfg.Server.TLS
+	opts := certs.Options{
+		CertFile:     t.CertFile,
+		KeyFile:      t.KeyFile,
+		Hosts:        t.Hosts,
+		ClientCAFile: t.ClientCAFile,
+		ClientAuth:   certs.ClientAuth(t.ClientAuth),
+	}
+	if opts.CertFile == "" {
+		dir := filepath.Dir(cfg.Database.Path)
+		opts.CertFile = filepath.Join(dir, selfSignedCertFile)
+		opts.KeyFile = filepath.Join(dir, selfSignedKeyFile)
+		opts.SelfSigned = true
+	}
+
+	tlsCfg, err := certs.ServerConfig(opts)
+	if err != nil {
+		return nil, err
+	}
+	if opts.SelfSigned {
+		fingerprint, err := certs.Fingerprint(opts.CertFile)
+		if err != nil {
+			return nil, err
+		}
+		log.Printf("Using self-signed certificate %s (SHA-256 %s)", opts.CertFile, fingerprint)
+	}
+	return tlsCfg, nil
+}
+
+// quotas converts the configured quotas for the network monitor.
+func quotas(configured []config.QuotaConfig) []network.Quota {
+	result := make([]network.Quota, 0, len(configured))
+	for _, q := range configured {
+		result = append(result, network.Quota{
+			Name:        q.Name,
+			Interface:   q.Interface,
+			Direction:   network.Direction(q.Direction),
+			LimitBytes:  uint64(q.Limit),
+			ResetDay:    q.ResetDay,
+			WarnPercent: q.WarnPercent,
+		})
 	}
+	return result
 }
@@ -0,0 +1,205 @@
+// Package memstats samples memory and swap usage in the background and
+// derives a memory pressure level from them.
+package memstats
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/shirou/gopsutil/v3/mem"
+	"macos-monitor/backend-go/hub"
+	"macos-monitor/backend-go/platform"
+	"macos-monitor/backend-go/series"
+)
+
+// Pressure is how hard the system is pressed for memory.
+type Pressure string
+
+const (
+	// PressureNormal leaves enough memory available.
+	PressureNormal Pressure = "normal"
+	// PressureWarning is little available memory or sustained swapping.
+	PressureWarning Pressure = "warning"
+	// PressureCritical is almost no available memory, or little while
+	// swapping.
+	PressureCritical Pressure = "critical"
+)
+
+// Value ranks the level from 0 (normal) to 2 (critical), for recording it
+// as a metric.
+func (p Pressure) Value() float64 {
+	switch p {
+	case PressureWarning:
+		return 1
+	case PressureCritical:
+		return 2
+	}
+	return 0
+}
+
+// Thresholds of the pressure level, in percent of the total memory that is
+// available and in bytes swapped out per second.
+const (
+	warningAvailablePercent  = 15
+	criticalAvailablePercent = 5
+	swappingAvailablePercent = 10
+	swappingOutBPS           = 1 << 20
+)
+
+// Memory is the physical memory usage in bytes. Active, inactive and wired
+// memory are only reported on macOS and BSD, buffers and cached memory only
+// on Linux; the others are zero.
+type Memory struct {
+	Total       uint64  `json:"total"`
+	Available   uint64  `json:"available"`
+	Used        uint64  `json:"used"`
+	UsedPercent float64 `json:"used_percent"`
+	Free        uint64  `json:"free"`
+	Active      uint64  `json:"active"`
+	Inactive    uint64  `json:"inactive"`
+	Wired       uint64  `json:"wired"`
+	Buffers     uint64  `json:"buffers"`
+	Cached      uint64  `json:"cached"`
+	// Compressed is left out where memory is not compressed.
+	Compressed *uint64 `json:"compressed,omitempty"`
+}
+
+// Swap is the swap usage in bytes and the swap rates in bytes per second
+// over the last interval.
+type Swap struct {
+	Total       uint64  `json:"total"`
+	Used        uint64  `json:"used"`
+	Free        uint64  `json:"free"`
+	UsedPercent float64 `json:"used_percent"`
+	InBPS       float64 `json:"in_bps"`
+	OutBPS      float64 `json:"out_bps"`
+}
+
+// Snapshot is the memory usage as of the last sample.
+type Snapshot struct {
+	Timestamp int64    `json:"timestamp"`
+	Memory    Memory   `json:"memory"`
+	Swap      Swap     `json:"swap"`
+	Pressure  Pressure `json:"pressure"`
+}
+
+// Sampler reads the memory usage every interval and publishes it on the
+// hub.
+type Sampler struct {
+	interval time.Duration
+	hub      *hub.Hub
+	vmStats  func() (platform.VMStats, error)
+
+	// Swap counters of the previous sample, only accessed by the sampling
+	// goroutine after NewSampler
+	last     platform.VMStats
+	lastTime time.Time
+
+	mu       sync.RWMutex
+	snapshot Snapshot
+}
+
+// NewSampler creates a Sampler that samples every interval and publishes on
+// h. vmStats reads the compression and swap counters. The first sample is
+// taken right away, without swap rates.
+func NewSampler(h *hub.Hub, interval time.Duration, vmStats func() (platform.VMStats, error)) *Sampler {
+	s := &Sampler{interval: interval, hub: h, vmStats: vmStats, snapshot: Snapshot{Pressure: PressureNormal}}
+	s.performSample(time.Now())
+	return s
+}
+
+// Run samples every interval until ctx is done.
+func (s *Sampler) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			s.performSample(now)
+		}
+	}
+}
+
+// Snapshot returns the memory usage of the last sample.
+func (s *Sampler) Snapshot() Snapshot {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.snapshot
+}
+
+func (s *Sampler) performSample(now time.Time) {
+	vm, err := mem.VirtualMemory()
+	if err != nil {
+		log.Printf("Error sampling memory usage: %v", err)
+		return
+	}
+	snapshot := Snapshot{
+		Timestamp: now.Unix(),
+		Memory: Memory{
+			Total:       vm.Total,
+			Available:   vm.Available,
+			Used:        vm.Used,
+			UsedPercent: vm.UsedPercent,
+			Free:        vm.Free,
+			Active:      vm.Active,
+			Inactive:    vm.Inactive,
+			Wired:       vm.Wired,
+			Buffers:     vm.Buffers,
+			Cached:      vm.Cached,
+		},
+	}
+
+	if swap, err := mem.SwapMemory(); err == nil {
+		snapshot.Swap = Swap{Total: swap.Total, Used: swap.Used, Free: swap.Free, UsedPercent: swap.UsedPercent}
+	} else {
+		log.Printf("Error sampling swap usage: %v", err)
+	}
+
+	if stats, err := s.vmStats(); err == nil {
+		if stats.Compression {
+			compressed := stats.Compressed
+			snapshot.Memory.Compressed = &compressed
+		}
+		if !s.lastTime.IsZero() {
+			if dt := now.Sub(s.lastTime).Seconds(); dt > 0 {
+				snapshot.Swap.InBPS = series.CounterRate(s.last.SwapIn, stats.SwapIn, dt)
+				snapshot.Swap.OutBPS = series.CounterRate(s.last.SwapOut, stats.SwapOut, dt)
+			}
+		}
+		s.last, s.lastTime = stats, now
+	} else {
+		log.Printf("Error sampling swap counters: %v", err)
+	}
+
+	snapshot.Pressure = pressure(snapshot.Memory, snapshot.Swap)
+
+	s.mu.Lock()
+	s.snapshot = snapshot
+	s.mu.Unlock()
+
+	s.hub.Publish(hub.TopicMemory, "memory", "", snapshot)
+}
+
+// pressure derives the pressure level from the share of available memory
+// and whether memory is being swapped out: little available memory raises
+// a warning, and becomes critical when almost none is left or the system
+// has to swap to keep it free.
+func pressure(m Memory, s Swap) Pressure {
+	if m.Total == 0 {
+		return PressureNormal
+	}
+	available := float64(m.Available) / float64(m.Total) * 100
+	swapping := s.OutBPS >= swappingOutBPS
+	switch {
+	case available < criticalAvailablePercent, available < swappingAvailablePercent && swapping:
+		return PressureCritical
+	case available < warningAvailablePercent, swapping:
+		return PressureWarning
+	}
+	return PressureNormal
+}
@@ -0,0 +1,80 @@
+package memstats
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"macos-monitor/backend-go/hub"
+	"macos-monitor/backend-go/platform"
+)
+
+func TestPressure(t *testing.T) {
+	tests := []struct {
+		name      string
+		available uint64 // of 1000 bytes
+		outBPS    float64
+		want      Pressure
+	}{
+		{"plenty available", 500, 0, PressureNormal},
+		{"warning boundary", 150, 0, PressureNormal},
+		{"little available", 149, 0, PressureWarning},
+		{"critical boundary", 50, 0, PressureWarning},
+		{"almost none available", 49, 0, PressureCritical},
+		{"nothing available", 0, 0, PressureCritical},
+		{"swapping", 500, swappingOutBPS, PressureWarning},
+		{"swapping below the rate", 500, swappingOutBPS - 1, PressureNormal},
+		{"swapping boundary", 100, swappingOutBPS, PressureWarning},
+		{"swapping with little available", 99, swappingOutBPS, PressureCritical},
+		{"little available without swapping", 99, swappingOutBPS - 1, PressureWarning},
+	}
+	for _, tt := range tests {
+		got := pressure(Memory{Total: 1000, Available: tt.available}, Swap{OutBPS: tt.outBPS})
+		if got != tt.want {
+			t.Errorf("%s: pressure() = %s, want %s", tt.name, got, tt.want)
+		}
+	}
+
+	if got := pressure(Memory{}, Swap{OutBPS: swappingOutBPS}); got != PressureNormal {
+		t.Errorf("pressure() without memory = %s, want %s", got, PressureNormal)
+	}
+}
+
+func TestSwapRates(t *testing.T) {
+	h := hub.NewHub(hub.TopicMemory)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go h.Run(ctx)
+
+	var (
+		stats platform.VMStats
+		err   error
+	)
+	s := NewSampler(h, time.Second, func() (platform.VMStats, error) { return stats, err })
+	start := time.Now().Add(time.Minute)
+	stats = platform.VMStats{SwapIn: 1000, SwapOut: 1000}
+	s.performSample(start)
+
+	steps := []struct {
+		name          string
+		at            time.Duration
+		stats         platform.VMStats
+		err           error
+		inBPS, outBPS float64
+	}{
+		{"rates", 2 * time.Second, platform.VMStats{SwapIn: 3000, SwapOut: 5000}, nil, 1000, 2000},
+		{"counter going backwards", 4 * time.Second, platform.VMStats{SwapIn: 0, SwapOut: 7000}, nil, 0, 1000},
+		{"no time elapsed", 4 * time.Second, platform.VMStats{SwapIn: 100, SwapOut: 8000}, nil, 0, 0},
+		{"unreadable counters", 5 * time.Second, platform.VMStats{}, errors.New("vm_stat failed"), 0, 0},
+		// Rates continue from the last counters read
+		{"after an error", 6 * time.Second, platform.VMStats{SwapIn: 300, SwapOut: 8000}, nil, 100, 0},
+	}
+	for _, step := range steps {
+		stats, err = step.stats, step.err
+		s.performSample(start.Add(step.at))
+		if swap := s.Snapshot().Swap; swap.InBPS != step.inBPS || swap.OutBPS != step.outBPS {
+			t.Errorf("%s: swap rates = %v in, %v out, want %v, %v", step.name, swap.InBPS, swap.OutBPS, step.inBPS, step.outBPS)
+		}
+	}
+}
@@ -0,0 +1,241 @@
+package main
+
+import (
+	"cmp"
+	"log"
+	"maps"
+	"net/http"
+	"slices"
+	"strconv"
+
+	"macos-monitor/backend-go/battery"
+	"macos-monitor/backend-go/cpustats"
+	"macos-monitor/backend-go/disks"
+	"macos-monitor/backend-go/loadstats"
+	"macos-monitor/backend-go/memstats"
+	"macos-monitor/backend-go/metrics"
+	"macos-monitor/backend-go/network"
+	"macos-monitor/backend-go/platform"
+	"macos-monitor/backend-go/procs"
+	"macos-monitor/backend-go/sensors"
+)
+
+const metricsNamespace = "macos_monitor_"
+
+// metricsHandler exports the system, process, file system and network
+// statistics in the Prometheus text exposition format.
+func metricsHandler(m *network.Monitor, cs *cpustats.Sampler, ms *memstats.Sampler, ls *loadstats.Sampler, sc *sensors.Collector, bc *battery.Collector, pc *procs.Collector, diskFilter disks.Filter, metricGroups int) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		info := collectDynamicSystemInfo(cs, ms, pc, procs.GroupByApp)
+		info.Processes = topGroups(info.Processes, metricGroups)
+		families := systemMetrics(info)
+		families = append(families, loadMetrics(ls.Stats())...)
+		families = append(families, sensorMetrics(sc.Sensors()))
+		if status := bc.Status(); status.Present {
+			families = append(families, batteryMetrics(status)...)
+		}
+		if mounted, err := disks.List(diskFilter); err != nil {
+			log.Printf("Could not list disks for metrics: %v", err)
+		} else {
+			families = append(families, diskMetrics(mounted)...)
+		}
+		_, started, exited := pc.Events()
+		families = append(families,
+			metrics.NewCounter(metricsNamespace+"processes_started_total", "Processes started since the monitor started.").
+				Add(float64(started), nil),
+			metrics.NewCounter(metricsNamespace+"processes_exited_total", "Processes exited since the monitor started.").
+				Add(float64(exited), nil),
+		)
+
+		today, err := m.GetTodayTraffic()
+		if err != nil {
+			log.Printf("Could not fetch today's traffic for metrics: %v", err)
+		}
+		families = append(families, networkMetrics(m.GetSnapshots(), today)...)
+
+		w.Header().Set("Content-Type", metrics.ContentType)
+		if err := metrics.Write(w, families...); err != nil {
+			log.Printf("Error writing metrics: %v", err)
+		}
+	}
+}
+
+func diskMetrics(mounted []disks.Disk) []*metrics.Family {
+	size := metrics.NewGauge(metricsNamespace+"filesystem_size_bytes", "Size of a file system in bytes.")
+	used := metrics.NewGauge(metricsNamespace+"filesystem_used_bytes", "Used space of a file system in bytes.")
+	inodes := metrics.NewGauge(metricsNamespace+"filesystem_inodes", "Number of inodes of a file system.")
+	inodesUsed := metrics.NewGauge(metricsNamespace+"filesystem_inodes_used", "Used inodes of a file system.")
+	for _, d := range mounted {
+		labels := metrics.Labels{"mountpoint": d.Mountpoint, "device": d.Device, "fstype": d.Fstype}
+		size.Add(float64(d.Total), labels)
+		used.Add(float64(d.Used), labels)
+		inodes.Add(float64(d.InodesTotal), labels)
+		inodesUsed.Add(float64(d.InodesUsed), labels)
+	}
+	return []*metrics.Family{size, used, inodes, inodesUsed}
+}
+
+func systemMetrics(info dynamicSystemInfo) []*metrics.Family {
+	rootDisk := metrics.Labels{"mountpoint": platform.Current().PrimaryDisk()}
+	families := []*metrics.Family{
+		metrics.NewGauge(metricsNamespace+"cpu_usage_percent", "Total CPU usage in percent.").
+			Add(info.CPUPercent, nil),
+		cpuModeMetrics(info.CPUBreakdown),
+		metrics.NewGauge(metricsNamespace+"memory_usage_percent", "Used memory in percent of total memory.").
+			Add(info.MemoryPercent, nil),
+		metrics.NewGauge(metricsNamespace+"memory_used_bytes", "Used memory in bytes.").
+			Add(float64(info.MemoryUsed), nil),
+		memoryMetrics(info.Memory),
+		metrics.NewGauge(metricsNamespace+"memory_pressure_level", "Memory pressure from 0 (normal) to 2 (critical).").
+			Add(info.Pressure.Value(), nil),
+		metrics.NewGauge(metricsNamespace+"swap_used_bytes", "Used swap space in bytes.").
+			Add(float64(info.Swap.Used), nil),
+		metrics.NewGauge(metricsNamespace+"swap_total_bytes", "Size of the swap space in bytes.").
+			Add(float64(info.Swap.Total), nil),
+		metrics.NewGauge(metricsNamespace+"swap_rate_bytes_per_second", "Bytes swapped in and out per second.").
+			Add(info.Swap.InBPS, metrics.Labels{"direction": "in"}).
+			Add(info.Swap.OutBPS, metrics.Labels{"direction": "out"}),
+		metrics.NewGauge(metricsNamespace+"disk_usage_percent", "Used disk space in percent of the disk size.").
+			Add(info.DiskPercent, rootDisk),
+		metrics.NewGauge(metricsNamespace+"disk_used_bytes", "Used disk space in bytes.").
+			Add(float64(info.DiskUsed), rootDisk),
+	}
+
+	coreCPU := metrics.NewGauge(metricsNamespace+"cpu_core_usage_percent", "CPU usage of a logical core in percent.")
+	for _, c := range info.CPUCores {
+		coreCPU.Add(c.UsagePercent, metrics.Labels{"core": strconv.Itoa(c.Core)})
+	}
+	families = append(families, coreCPU)
+
+	procCPU := metrics.NewGauge(metricsNamespace+"process_cpu_percent", "CPU usage of a process group in percent of one core.")
+	procRSS := metrics.NewGauge(metricsNamespace+"process_resident_memory_bytes", "Resident memory of a process group in bytes.")
+	for _, p := range info.Processes {
+		labels := metrics.Labels{"name": p.Name}
+		procCPU.Add(p.CPUPercent, labels)
+		procRSS.Add(float64(p.MemoryRss), labels)
+	}
+	return append(families, procCPU, procRSS)
+}
+
+// topGroups keeps the process groups among the top n by CPU usage or by
+// resident memory, to bound the number of series on busy hosts. The
+// processes are sorted by CPU usage.
+func topGroups(processes []procInfo, n int) []procInfo {
+	if len(processes) <= n {
+		return processes
+	}
+	byRSS := slices.SortedFunc(slices.Values(processes), func(a, b procInfo) int {
+		return cmp.Compare(b.MemoryRss, a.MemoryRss)
+	})
+	keep := make(map[string]bool, 2*n)
+	for _, p := range byRSS[:n] {
+		keep[p.Name] = true
+	}
+	top := make([]procInfo, 0, 2*n)
+	for i, p := range processes {
+		if i < n || keep[p.Name] {
+			top = append(top, p)
+		}
+	}
+	return top
+}
+
+func cpuModeMetrics(b cpustats.Breakdown) *metrics.Family {
+	modes := []struct {
+		name    string
+		percent float64
+	}{
+		{"user", b.User}, {"system", b.System}, {"idle", b.Idle}, {"nice", b.Nice},
+		{"iowait", b.Iowait}, {"irq", b.Irq}, {"softirq", b.Softirq}, {"steal", b.Steal},
+	}
+	family := metrics.NewGauge(metricsNamespace+"cpu_mode_percent", "Share of CPU time spent in a mode, in percent.")
+	for _, m := range modes {
+		family.Add(m.percent, metrics.Labels{"mode": m.name})
+	}
+	return family
+}
+
+func loadMetrics(s loadstats.Stats) []*metrics.Family {
+	return []*metrics.Family{
+		metrics.NewGauge(metricsNamespace+"load_average", "Load average over 1, 5 and 15 minutes.").
+			Add(s.Load1, metrics.Labels{"period": "1m"}).
+			Add(s.Load5, metrics.Labels{"period": "5m"}).
+			Add(s.Load15, metrics.Labels{"period": "15m"}),
+		metrics.NewGauge(metricsNamespace+"processes", "Number of processes by state.").
+			Add(float64(s.ProcsTotal), metrics.Labels{"state": "total"}).
+			Add(float64(s.ProcsRunning), metrics.Labels{"state": "running"}).
+			Add(float64(s.ProcsBlocked), metrics.Labels{"state": "blocked"}),
+		metrics.NewGauge(metricsNamespace+"context_switches_per_second", "Smoothed context switch rate.").
+			Add(s.ContextSwitchesPS, nil),
+		metrics.NewGauge(metricsNamespace+"interrupts_per_second", "Smoothed interrupt rate.").
+			Add(s.InterruptsPS, nil),
+	}
+}
+
+func sensorMetrics(readings []sensors.Sensor) *metrics.Family {
+	family := metrics.NewGauge(metricsNamespace+"temperature_celsius", "Temperature of a sensor in degrees Celsius.")
+	for _, s := range readings {
+		family.Add(s.Temperature, metrics.Labels{"sensor": s.Sensor})
+	}
+	return family
+}
+
+func batteryMetrics(s battery.Status) []*metrics.Family {
+	families := []*metrics.Family{
+		metrics.NewGauge(metricsNamespace+"battery_charge_percent", "Battery charge in percent.").
+			Add(s.Percent, nil),
+		metrics.NewGauge(metricsNamespace+"battery_charging", "Whether the battery is charging.").
+			Add(boolValue(s.State == battery.StateCharging), nil),
+		metrics.NewGauge(metricsNamespace+"power_source_ac", "Whether the system runs on AC power.").
+			Add(boolValue(s.PowerSource == battery.PowerSourceAC), nil),
+		metrics.NewGauge(metricsNamespace+"battery_cycles", "Charge cycles of the battery.").
+			Add(float64(s.CycleCount), nil),
+	}
+	if s.HealthPercent > 0 {
+		families = append(families, metrics.NewGauge(metricsNamespace+"battery_health_percent", "Full charge capacity in percent of the design capacity.").
+			Add(s.HealthPercent, nil))
+	}
+	if s.TimeRemainingMin != nil {
+		families = append(families, metrics.NewGauge(metricsNamespace+"battery_time_remaining_seconds", "Estimated time until the battery is empty or full.").
+			Add(float64(*s.TimeRemainingMin*60), nil))
+	}
+	return families
+}
+
+func boolValue(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+func memoryMetrics(m memstats.Memory) *metrics.Family {
+	family := metrics.NewGauge(metricsNamespace+"memory_bytes", "Physical memory in a state, in bytes.")
+	states := map[string]uint64{
+		"available": m.Available, "free": m.Free, "active": m.Active, "inactive": m.Inactive,
+		"wired": m.Wired, "buffers": m.Buffers, "cached": m.Cached,
+	}
+	if m.Compressed != nil {
+		states["compressed"] = *m.Compressed
+	}
+	for _, state := range slices.Sorted(maps.Keys(states)) {
+		family.Add(float64(states[state]), metrics.Labels{"state": state})
+	}
+	return family
+}
+
+func networkMetrics(snapshots []network.InterfaceSnapshot, today map[string]network.DailyTraffic) []*metrics.Family {
+	rate := metrics.NewGauge(metricsNamespace+"network_rate_bytes_per_second", "Smoothed network throughput.")
+	total := metrics.NewCounter(metricsNamespace+"network_bytes_total", "Network traffic since boot.")
+	daily := metrics.NewGauge(metricsNamespace+"network_today_bytes", "Network traffic since midnight, as of the last persisted sample.")
+	for _, s := range snapshots {
+		down := metrics.Labels{"iface": s.Interface, "direction": "down"}
+		up := metrics.Labels{"iface": s.Interface, "direction": "up"}
+		rate.Add(s.Rate.DownBPS, down).Add(s.Rate.UpBPS, up)
+		total.Add(float64(s.SinceBoot.DownBytes), down).Add(float64(s.SinceBoot.UpBytes), up)
+		if t, ok := today[s.Interface]; ok {
+			daily.Add(float64(t.DownBytes), down).Add(float64(t.UpBytes), up)
+		}
+	}
+	return []*metrics.Family{rate, total, daily}
+}
@@ -0,0 +1,122 @@
+// Package metrics writes metric families in the Prometheus text exposition
+// format.
+package metrics
+
+import (
+	"bufio"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ContentType is the media type of the text exposition format.
+const ContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+// Type is the type of a metric family.
+type Type string
+
+const (
+	Gauge   Type = "gauge"
+	Counter Type = "counter"
+)
+
+// Labels are the label names and values of a sample.
+type Labels map[string]string
+
+type sample struct {
+	labels Labels
+	value  float64
+}
+
+// Family is a named group of samples of the same type.
+type Family struct {
+	Name    string
+	Help    string
+	Type    Type
+	samples []sample
+}
+
+// NewGauge creates an empty gauge family.
+func NewGauge(name, help string) *Family {
+	return &Family{Name: name, Help: help, Type: Gauge}
+}
+
+// NewCounter creates an empty counter family. By convention its name ends
+// in "_total".
+func NewCounter(name, help string) *Family {
+	return &Family{Name: name, Help: help, Type: Counter}
+}
+
+// Add appends a sample to the family.
+func (f *Family) Add(value float64, labels Labels) *Family {
+	f.samples = append(f.samples, sample{labels: labels, value: value})
+	return f
+}
+
+// Write writes the families in the text exposition format. Families without
+// samples are skipped.
+func Write(w io.Writer, families ...*Family) error {
+	bw := bufio.NewWriter(w)
+	for _, f := range families {
+		if len(f.samples) == 0 {
+			continue
+		}
+		bw.WriteString("# HELP " + f.Name + " " + escapeHelp(f.Help) + "\n")
+		bw.WriteString("# TYPE " + f.Name + " " + string(f.Type) + "\n")
+		for _, s := range f.samples {
+			bw.WriteString(f.Name)
+			writeLabels(bw, s.labels)
+			bw.WriteByte(' ')
+			bw.WriteString(formatValue(s.value))
+			bw.WriteByte('\n')
+		}
+	}
+	return bw.Flush()
+}
+
+func writeLabels(w *bufio.Writer, labels Labels) {
+	if len(labels) == 0 {
+		return
+	}
+	names := make([]string, 0, len(labels))
+	for name := range labels {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	w.WriteByte('{')
+	for i, name := range names {
+		if i > 0 {
+			w.WriteByte(',')
+		}
+		w.WriteString(name + `="` + escapeLabelValue(labels[name]) + `"`)
+	}
+	w.WriteByte('}')
+}
+
+var (
+	helpEscaper       = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+	labelValueEscaper = strings.NewReplacer(`\`, `\\`, "\n", `\n`, `"`, `\"`)
+)
+
+func escapeHelp(s string) string {
+	return helpEscaper.Replace(s)
+}
+
+func escapeLabelValue(s string) string {
+	return labelValueEscaper.Replace(s)
+}
+
+func formatValue(v float64) string {
+	switch {
+	case math.IsNaN(v):
+		return "NaN"
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
@@ -0,0 +1,40 @@
+package metrics
+
+import (
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestWrite(t *testing.T) {
+	families := []*Family{
+		NewGauge("temperature_celsius", "Temperature of a sensor\nin degrees \\ Celsius.").
+			Add(41.5, Labels{"sensor": "CPU"}).
+			Add(math.NaN(), Labels{"sensor": `GPU "die"`}).
+			Add(math.Inf(1), Labels{"sensor": "C:\\fan\nrear"}).
+			Add(math.Inf(-1), Labels{"zone": "b", "sensor": "a", "chip": "c"}),
+		// Families without samples are left out
+		NewGauge("empty", "No samples."),
+		NewCounter("processes_started_total", "Processes started.").
+			Add(1234567, nil).
+			Add(1e21, Labels{}),
+	}
+	want := `# HELP temperature_celsius Temperature of a sensor\nin degrees \\ Celsius.
+# TYPE temperature_celsius gauge
+temperature_celsius{sensor="CPU"} 41.5
+temperature_celsius{sensor="GPU \"die\""} NaN
+temperature_celsius{sensor="C:\\fan\nrear"} +Inf
+temperature_celsius{chip="c",sensor="a",zone="b"} -Inf
+# HELP processes_started_total Processes started.
+# TYPE processes_started_total counter
+processes_started_total 1.234567e+06
+processes_started_total 1e+21
+`
+	var b strings.Builder
+	if err := Write(&b, families...); err != nil {
+		t.Fatal(err)
+	}
+	if got := b.String(); got != want {
+		t.Errorf("Write() =\n%s\nwant\n%s", got, want)
+	}
+}
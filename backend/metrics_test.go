@@ -0,0 +1,42 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestTopGroups(t *testing.T) {
+	// Sorted by CPU usage, as collectDynamicSystemInfo returns them
+	processes := []procInfo{
+		{Name: "compiler", CPUPercent: 250, MemoryRss: 300},
+		{Name: "browser", CPUPercent: 40, MemoryRss: 4000},
+		{Name: "shell", CPUPercent: 5, MemoryRss: 10},
+		{Name: "database", CPUPercent: 2, MemoryRss: 2000},
+		{Name: "editor", CPUPercent: 1, MemoryRss: 500},
+		{Name: "daemon", CPUPercent: 0, MemoryRss: 20},
+	}
+	names := func(ps []procInfo) []string {
+		var names []string
+		for _, p := range ps {
+			names = append(names, p.Name)
+		}
+		return names
+	}
+	tests := []struct {
+		n    int
+		want []string
+	}{
+		{0, nil},
+		// The top by memory are already the top by CPU
+		{1, []string{"compiler", "browser"}},
+		{2, []string{"compiler", "browser", "database"}},
+		{3, []string{"compiler", "browser", "shell", "database", "editor"}},
+		{6, names(processes)},
+		{10, names(processes)},
+	}
+	for _, tt := range tests {
+		if got := names(topGroups(processes, tt.n)); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("topGroups(%d) = %v, want %v", tt.n, got, tt.want)
+		}
+	}
+}
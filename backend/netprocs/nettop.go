@@ -0,0 +1,86 @@
+package netprocs
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"macos-monitor/backend-go/platform"
+)
+
+// NettopSource reads per-process byte counters from the nettop command of
+// macOS.
+type NettopSource struct {
+	Run platform.Runner
+}
+
+// Counters runs a single nettop sample of every process.
+func (s NettopSource) Counters() ([]Counter, error) {
+	out, err := s.Run("nettop", "-P", "-L", "1", "-x", "-J", "bytes_in,bytes_out")
+	if err != nil {
+		return nil, fmt.Errorf("failed to run nettop: %w", err)
+	}
+	return parseNettop(out)
+}
+
+// CountsBytes reports that nettop counts bytes.
+func (NettopSource) CountsBytes() bool {
+	return true
+}
+
+// parseNettop parses the CSV output of nettop, in which a header line
+// names the columns and the process column, second after the time, holds
+// "<name>.<pid>". Bytes are totals since the process started.
+func parseNettop(out []byte) ([]Counter, error) {
+	r := csv.NewReader(bytes.NewReader(out))
+	r.FieldsPerRecord = -1
+	// Process names are not quoted and may contain quotes
+	r.LazyQuotes = true
+	records, err := r.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse nettop output: %w", err)
+	}
+	if len(records) == 0 {
+		return nil, fmt.Errorf("empty nettop output")
+	}
+
+	const processCol = 1
+	inCol, outCol := -1, -1
+	for i, name := range records[0] {
+		switch strings.TrimSpace(name) {
+		case "bytes_in":
+			inCol = i
+		case "bytes_out":
+			outCol = i
+		}
+	}
+	if inCol < 0 || outCol < 0 {
+		return nil, fmt.Errorf("nettop output has no byte columns: %q", strings.Join(records[0], ","))
+	}
+
+	var counters []Counter
+	for _, rec := range records[1:] {
+		if len(rec) <= max(processCol, inCol, outCol) {
+			continue
+		}
+		// Names may contain dots, the pid follows the last one
+		field := rec[processCol]
+		dot := strings.LastIndexByte(field, '.')
+		if dot < 0 {
+			continue
+		}
+		pid, err := strconv.ParseInt(field[dot+1:], 10, 32)
+		if err != nil {
+			continue
+		}
+		in, errIn := strconv.ParseUint(strings.TrimSpace(rec[inCol]), 10, 64)
+		out, errOut := strconv.ParseUint(strings.TrimSpace(rec[outCol]), 10, 64)
+		if errIn != nil || errOut != nil {
+			continue
+		}
+		counters = append(counters, Counter{Pid: int32(pid), Name: field[:dot], BytesIn: in, BytesOut: out})
+	}
+	return counters, nil
+}
@@ -0,0 +1,101 @@
+package netprocs
+
+import (
+	"errors"
+	"slices"
+	"testing"
+)
+
+// nettopOutput is recorded from "nettop -P -L 1 -x -J bytes_in,bytes_out".
+const nettopOutput = `time,,bytes_in,bytes_out,
+09:41:15.123456,launchd.1,0,0,
+09:41:15.123789,com.apple.WebKit.Networking.612,123456789,2345678,
+09:41:15.124001,Google Chrome He.1234,5555,666,
+09:41:15.124100,Spotify Helper "GPU".880,10,20,
+`
+
+func TestParseNettop(t *testing.T) {
+	got, err := parseNettop([]byte(nettopOutput))
+	if err != nil {
+		t.Fatalf("parseNettop: %v", err)
+	}
+	want := []Counter{
+		{Pid: 1, Name: "launchd"},
+		{Pid: 612, Name: "com.apple.WebKit.Networking", BytesIn: 123456789, BytesOut: 2345678},
+		{Pid: 1234, Name: "Google Chrome He", BytesIn: 5555, BytesOut: 666},
+		{Pid: 880, Name: `Spotify Helper "GPU"`, BytesIn: 10, BytesOut: 20},
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("parseNettop() = %+v, want %+v", got, want)
+	}
+}
+
+func TestParseNettopColumnOrder(t *testing.T) {
+	out := "time,,bytes_out,bytes_in,\n09:41:15.1,curl.42, 7 , 9 ,\n"
+	got, err := parseNettop([]byte(out))
+	if err != nil {
+		t.Fatalf("parseNettop: %v", err)
+	}
+	if want := []Counter{{Pid: 42, Name: "curl", BytesIn: 9, BytesOut: 7}}; !slices.Equal(got, want) {
+		t.Errorf("parseNettop() = %+v, want %+v", got, want)
+	}
+}
+
+func TestParseNettopMalformedRows(t *testing.T) {
+	out := `time,,bytes_in,bytes_out,
+09:41:15.1,nopid,1,2,
+09:41:15.1,bad.pid,1,2,
+09:41:15.1,big.99999999999,1,2,
+09:41:15.1,curl.42,many,2,
+09:41:15.1,curl.43,1,-2,
+09:41:15.1,short.44
+garbage
+
+09:41:15.1,ok.45,3,4,
+`
+	got, err := parseNettop([]byte(out))
+	if err != nil {
+		t.Fatalf("parseNettop: %v", err)
+	}
+	if want := []Counter{{Pid: 45, Name: "ok", BytesIn: 3, BytesOut: 4}}; !slices.Equal(got, want) {
+		t.Errorf("parseNettop() = %+v, want only the valid row %+v", got, want)
+	}
+}
+
+func TestParseNettopInvalid(t *testing.T) {
+	for _, out := range []string{
+		"",
+		"time,,bytes_in,\n09:41:15.1,curl.42,1,\n",
+		"nettop: must be run as root\n",
+	} {
+		if got, err := parseNettop([]byte(out)); err == nil {
+			t.Errorf("parseNettop(%q) = %+v, want an error", out, got)
+		}
+	}
+}
+
+func TestNettopSource(t *testing.T) {
+	var gotName string
+	var gotArgs []string
+	source := NettopSource{Run: func(name string, args ...string) ([]byte, error) {
+		gotName, gotArgs = name, args
+		return []byte(nettopOutput), nil
+	}}
+	counters, err := source.Counters()
+	if err != nil {
+		t.Fatalf("Counters: %v", err)
+	}
+	if wantArgs := []string{"-P", "-L", "1", "-x", "-J", "bytes_in,bytes_out"}; gotName != "nettop" || !slices.Equal(gotArgs, wantArgs) {
+		t.Errorf("ran %s %v, want nettop %v", gotName, gotArgs, wantArgs)
+	}
+	if len(counters) != 4 || !source.CountsBytes() {
+		t.Errorf("Counters() = %+v, want 4 counters with bytes", counters)
+	}
+
+	failing := NettopSource{Run: func(string, ...string) ([]byte, error) {
+		return nil, errors.New("exit status 1")
+	}}
+	if _, err := failing.Counters(); err == nil {
+		t.Error("Counters returned no error when nettop failed")
+	}
+}
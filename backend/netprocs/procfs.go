@@ -0,0 +1,156 @@
+package netprocs
+
+import (
+	"bufio"
+	"encoding/hex"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// socketTables are the files under /proc/<pid>/net listing the sockets of
+// a network namespace.
+var socketTables = []string{"tcp", "tcp6", "udp", "udp6"}
+
+// stateEstablished is the state of connected TCP and UDP sockets.
+const stateEstablished = "01"
+
+// ProcfsSource counts the connections of each process from the proc file
+// system of Linux, by matching the socket inodes of its file descriptors
+// against the socket tables of its network namespace. It does not count
+// bytes, which Linux only accounts per socket with eBPF.
+type ProcfsSource struct {
+	// Proc is the mount point of the proc file system.
+	Proc string
+}
+
+// Counters returns the processes with at least one established connection
+// to another host.
+func (s ProcfsSource) Counters() ([]Counter, error) {
+	entries, err := os.ReadDir(s.Proc)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list processes: %w", err)
+	}
+
+	// The socket tables are read once per network namespace
+	connected := make(map[string]map[uint64]bool)
+	var counters []Counter
+	for _, e := range entries {
+		pid, err := strconv.ParseInt(e.Name(), 10, 32)
+		if err != nil {
+			continue
+		}
+		dir := filepath.Join(s.Proc, e.Name())
+		inodes := socketInodes(dir)
+		if len(inodes) == 0 {
+			continue
+		}
+
+		ns, err := os.Readlink(filepath.Join(dir, "ns", "net"))
+		if err != nil {
+			ns = dir
+		}
+		sockets, ok := connected[ns]
+		if !ok {
+			sockets = readSocketTables(filepath.Join(dir, "net"))
+			connected[ns] = sockets
+		}
+
+		n := 0
+		for _, inode := range inodes {
+			if sockets[inode] {
+				n++
+			}
+		}
+		if n == 0 {
+			continue
+		}
+		comm, _ := os.ReadFile(filepath.Join(dir, "comm"))
+		counters = append(counters, Counter{Pid: int32(pid), Name: strings.TrimSpace(string(comm)), Connections: n})
+	}
+	return counters, nil
+}
+
+// CountsBytes reports that the proc file system only counts connections.
+func (ProcfsSource) CountsBytes() bool {
+	return false
+}
+
+// socketInodes returns the inodes of the sockets a process has open. The
+// file descriptors of other users' processes cannot be read without
+// privileges and are skipped.
+func socketInodes(dir string) []uint64 {
+	fdDir := filepath.Join(dir, "fd")
+	fds, err := os.ReadDir(fdDir)
+	if err != nil {
+		return nil
+	}
+	var inodes []uint64
+	for _, fd := range fds {
+		target, err := os.Readlink(filepath.Join(fdDir, fd.Name()))
+		if err != nil {
+			continue
+		}
+		// Sockets link to "socket:[<inode>]"
+		rest, ok := strings.CutPrefix(target, "socket:[")
+		if !ok {
+			continue
+		}
+		if inode, err := strconv.ParseUint(strings.TrimSuffix(rest, "]"), 10, 64); err == nil {
+			inodes = append(inodes, inode)
+		}
+	}
+	return inodes
+}
+
+// readSocketTables returns the inodes of the established sockets to other
+// hosts listed in the socket tables under netDir.
+func readSocketTables(netDir string) map[uint64]bool {
+	connected := make(map[uint64]bool)
+	for _, table := range socketTables {
+		f, err := os.Open(filepath.Join(netDir, table))
+		if err != nil {
+			continue
+		}
+		scanner := bufio.NewScanner(f)
+		// Skip the header
+		scanner.Scan()
+		for scanner.Scan() {
+			// sl local_address rem_address st tx_queue:rx_queue tr:tm->when retrnsmt uid timeout inode
+			fields := strings.Fields(scanner.Text())
+			if len(fields) < 10 || fields[3] != stateEstablished {
+				continue
+			}
+			remote, ok := parseSocketAddr(fields[2])
+			if !ok || remote.IsLoopback() || remote.IsUnspecified() {
+				continue
+			}
+			if inode, err := strconv.ParseUint(fields[9], 10, 64); err == nil && inode != 0 {
+				connected[inode] = true
+			}
+		}
+		f.Close()
+	}
+	return connected
+}
+
+// parseSocketAddr parses the IP of an "<address>:<port>" in a socket
+// table, where the address is hex in 32 bit words of host byte order.
+func parseSocketAddr(s string) (net.IP, bool) {
+	addr, _, ok := strings.Cut(s, ":")
+	if !ok {
+		return nil, false
+	}
+	b, err := hex.DecodeString(addr)
+	if err != nil || (len(b) != net.IPv4len && len(b) != net.IPv6len) {
+		return nil, false
+	}
+	// Reverse each word, assuming a little endian host
+	for i := 0; i < len(b); i += 4 {
+		b[i], b[i+1], b[i+2], b[i+3] = b[i+3], b[i+2], b[i+1], b[i]
+	}
+	return net.IP(b), true
+}
@@ -0,0 +1,158 @@
+package netprocs
+
+import (
+	"net"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"testing"
+)
+
+const tableHeader = "  sl  local_address rem_address   st tx_queue rx_queue tr tm->when retrnsmt   uid  timeout inode\n"
+
+// socketLine formats a socket table entry.
+func socketLine(local, remote, state, inode string) string {
+	return "   0: " + local + " " + remote + " " + state + " 00000000:00000000 00:00000000 00000000  1000        0 " + inode + " 1 0000000000000000 20 4 30 10 -1\n"
+}
+
+// fakeProc builds a proc file system tree under a temporary directory.
+type fakeProc struct {
+	t   *testing.T
+	dir string
+}
+
+func (p fakeProc) write(name, content string) {
+	p.t.Helper()
+	path := filepath.Join(p.dir, name)
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		p.t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		p.t.Fatal(err)
+	}
+}
+
+func (p fakeProc) link(name, target string) {
+	p.t.Helper()
+	path := filepath.Join(p.dir, name)
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		p.t.Fatal(err)
+	}
+	if err := os.Symlink(target, path); err != nil {
+		p.t.Fatal(err)
+	}
+}
+
+func TestProcfsSource(t *testing.T) {
+	p := fakeProc{t, t.TempDir()}
+
+	// A browser with connections to 93.184.216.34 and 2606:2800:220:1::1946,
+	// one to localhost and a file
+	p.write("100/comm", "firefox\n")
+	p.link("100/ns/net", "net:[4026531840]")
+	p.link("100/fd/3", "socket:[1001]")
+	p.link("100/fd/4", "socket:[1002]")
+	p.link("100/fd/5", "socket:[1003]")
+	p.link("100/fd/6", "/dev/null")
+	p.link("100/fd/7", "socket:[1004]")
+	p.write("100/net/tcp", tableHeader+
+		socketLine("0F02000A:D4C2", "22D8B85D:01BB", "01", "1001")+
+		socketLine("0100007F:D4C4", "0100007F:1F90", "01", "1002")+
+		// The other end of the connection to localhost, a listening socket
+		// and an SSH session from 192.168.1.100, all of other processes
+		socketLine("0100007F:1F90", "0100007F:D4C4", "01", "2002")+
+		socketLine("00000000:0016", "00000000:0000", "0A", "2001")+
+		socketLine("0F02000A:0016", "6401A8C0:C350", "01", "2003"))
+	p.write("100/net/tcp6", tableHeader+
+		// A malformed address is skipped
+		socketLine("00000000000000000000000000000000:D4C6", "0028062601002002000000004619000001BB", "01", "9999")+
+		socketLine("00000000000000000000000000000000:D4C6", "00280626010020020000000046190000:01BB", "01", "1003"))
+	p.write("100/net/udp", tableHeader+
+		// A connected UDP socket, such as for QUIC
+		socketLine("0F02000A:E0A1", "08080808:01BB", "01", "1004"))
+	p.write("100/net/udp6", tableHeader)
+
+	// A process in the same namespace: its tables are not read again, so
+	// the empty table below must not hide its connection
+	p.write("200/comm", "sshd\n")
+	p.link("200/ns/net", "net:[4026531840]")
+	p.link("200/fd/3", "socket:[2001]")
+	p.link("200/fd/4", "socket:[2003]")
+	p.write("200/net/tcp", tableHeader)
+
+	// A container in its own namespace, with an inode also used on the host
+	p.write("300/comm", "nginx\n")
+	p.link("300/ns/net", "net:[4026532500]")
+	p.link("300/fd/3", "socket:[1001]")
+	p.link("300/fd/4", "socket:[3001]")
+	p.write("300/net/tcp", tableHeader+
+		socketLine("0200110A:0050", "0100110A:C001", "01", "3001"))
+
+	// Only listening and local sockets
+	p.write("400/comm", "postgres\n")
+	p.link("400/ns/net", "net:[4026531840]")
+	p.link("400/fd/3", "socket:[2001]")
+	p.link("400/fd/4", "socket:[2002]")
+
+	// Without sockets, and file descriptors that cannot be read
+	p.write("500/comm", "sleep\n")
+	p.link("500/fd/0", "/dev/pts/0")
+	p.write("600/comm", "other-user\n")
+
+	p.write("stat", "cpu  1 2 3 4\n")
+	p.link("self", "100")
+
+	counters, err := ProcfsSource{Proc: p.dir}.Counters()
+	if err != nil {
+		t.Fatalf("Counters: %v", err)
+	}
+	sort.Slice(counters, func(i, j int) bool {
+		return counters[i].Pid < counters[j].Pid
+	})
+	want := []Counter{
+		{Pid: 100, Name: "firefox", Connections: 3},
+		{Pid: 200, Name: "sshd", Connections: 1},
+		{Pid: 300, Name: "nginx", Connections: 1},
+	}
+	if !slices.Equal(counters, want) {
+		t.Errorf("Counters() = %+v, want %+v", counters, want)
+	}
+	if (ProcfsSource{}).CountsBytes() {
+		t.Error("CountsBytes() = true, want false")
+	}
+}
+
+func TestProcfsSourceMissing(t *testing.T) {
+	if _, err := (ProcfsSource{Proc: filepath.Join(t.TempDir(), "missing")}).Counters(); err == nil {
+		t.Error("Counters returned no error for a missing proc file system")
+	}
+}
+
+func TestParseSocketAddr(t *testing.T) {
+	tests := []struct {
+		addr string
+		want net.IP
+	}{
+		{"0100007F:0035", net.IPv4(127, 0, 0, 1)},
+		{"22D8B85D:01BB", net.IPv4(93, 184, 216, 34)},
+		{"00000000:0000", net.IPv4zero},
+		{"00000000000000000000000001000000:0035", net.IPv6loopback},
+		{"00280626010020020000000046190000:01BB", net.ParseIP("2606:2800:220:1::1946")},
+		{"B80D0120000000000000000001000000:01BB", net.ParseIP("2001:db8::1")},
+		// IPv4-mapped
+		{"0000000000000000FFFF00000100007F:0035", net.IPv4(127, 0, 0, 1)},
+	}
+	for _, tt := range tests {
+		got, ok := parseSocketAddr(tt.addr)
+		if !ok || !got.Equal(tt.want) {
+			t.Errorf("parseSocketAddr(%q) = %v, %v, want %v", tt.addr, got, ok, tt.want)
+		}
+	}
+
+	for _, addr := range []string{"", "0100007F", "0100007G:0035", "01007F:0035", "0000000000000000000000000100000:0035"} {
+		if got, ok := parseSocketAddr(addr); ok {
+			t.Errorf("parseSocketAddr(%q) = %v, want an error", addr, got)
+		}
+	}
+}
@@ -0,0 +1,238 @@
+// Package netprocs attributes network traffic to processes and the
+// applications they belong to.
+package netprocs
+
+import (
+	"context"
+	"log"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/shirou/gopsutil/v3/process"
+	"macos-monitor/backend-go/series"
+)
+
+// Counter is the network activity of one process as read by a Source.
+type Counter struct {
+	Pid  int32
+	Name string
+	// Connections counts the open connections to other hosts, where the
+	// Source reports them.
+	Connections int
+	// BytesIn and BytesOut are running totals, where the Source reports
+	// them.
+	BytesIn  uint64
+	BytesOut uint64
+}
+
+// Source reads the network activity of every process that has any.
+type Source interface {
+	Counters() ([]Counter, error)
+	// CountsBytes reports whether the counters include bytes. Without them,
+	// the traffic of all interfaces is attributed by connection counts.
+	CountsBytes() bool
+}
+
+// Usage is the network usage of a process or an application.
+type Usage struct {
+	// Pid is 0 for applications.
+	Pid         int32   `json:"pid,omitempty"`
+	Name        string  `json:"name"`
+	Group       string  `json:"group,omitempty"`
+	Count       int     `json:"count,omitempty"`
+	Connections int     `json:"connections"`
+	DownBPS     float64 `json:"down_bps"`
+	UpBPS       float64 `json:"up_bps"`
+}
+
+// Snapshot is the network usage over the last interval.
+type Snapshot struct {
+	Timestamp int64 `json:"timestamp"`
+	// Estimated is set when the rates are the traffic of all interfaces
+	// split by connection counts rather than measured per process.
+	Estimated bool    `json:"estimated"`
+	Processes []Usage `json:"processes"`
+}
+
+// Options configures a Sampler.
+type Options struct {
+	Interval time.Duration
+	// Identify returns the group name of a process. It is called once per
+	// process.
+	Identify func(p *process.Process) string
+	// TotalRate returns the current download and upload rate of all
+	// interfaces, to be split by connection counts where the Source does
+	// not count bytes.
+	TotalRate func() (down, up float64)
+}
+
+// identity is the name and group of a process seen before.
+type identity struct {
+	name  string
+	group string
+}
+
+// Sampler reads a Source every interval and computes the usage of each
+// process.
+type Sampler struct {
+	source Source
+	opts   Options
+
+	// Only accessed by the sampling goroutine
+	failing    bool
+	last       map[int32]Counter
+	lastTime   time.Time
+	identities map[int32]identity
+
+	mu       sync.RWMutex
+	snapshot Snapshot
+}
+
+// NewSampler creates a Sampler reading source.
+func NewSampler(source Source, opts Options) *Sampler {
+	return &Sampler{
+		source:     source,
+		opts:       opts,
+		identities: make(map[int32]identity),
+		snapshot:   Snapshot{Estimated: !source.CountsBytes(), Processes: []Usage{}},
+	}
+}
+
+// Run samples right away and then every interval until ctx is done.
+func (s *Sampler) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.opts.Interval)
+	defer ticker.Stop()
+
+	s.performSample(time.Now())
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			s.performSample(now)
+		}
+	}
+}
+
+// Snapshot returns the usage of every process with network activity,
+// highest rates first.
+func (s *Sampler) Snapshot() Snapshot {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	snapshot := s.snapshot
+	snapshot.Processes = append([]Usage{}, s.snapshot.Processes...)
+	return snapshot
+}
+
+// Grouped returns the usage aggregated by application, highest rates
+// first.
+func (s *Sampler) Grouped() Snapshot {
+	snapshot := s.Snapshot()
+	byGroup := make(map[string]*Usage)
+	for _, u := range snapshot.Processes {
+		g, ok := byGroup[u.Group]
+		if !ok {
+			g = &Usage{Name: u.Group}
+			byGroup[u.Group] = g
+		}
+		g.Count++
+		g.Connections += u.Connections
+		g.DownBPS += u.DownBPS
+		g.UpBPS += u.UpBPS
+	}
+	groups := make([]Usage, 0, len(byGroup))
+	for _, g := range byGroup {
+		groups = append(groups, *g)
+	}
+	sortUsage(groups)
+	snapshot.Processes = groups
+	return snapshot
+}
+
+func (s *Sampler) performSample(now time.Time) {
+	counters, err := s.source.Counters()
+	if err != nil {
+		// Log only once while the source fails
+		if !s.failing {
+			log.Printf("Error reading process network usage: %v", err)
+		}
+		s.failing = true
+		return
+	}
+	s.failing = false
+
+	usage := make([]Usage, 0, len(counters))
+	seen := make(map[int32]Counter, len(counters))
+	deltaT := now.Sub(s.lastTime).Seconds()
+	totalConnections := 0
+	for _, c := range counters {
+		seen[c.Pid] = c
+		id := s.identify(c)
+		u := Usage{Pid: c.Pid, Name: id.name, Group: id.group, Connections: c.Connections}
+		// A process seen for the first time, or whose pid was reused, has
+		// no rate yet
+		if last, ok := s.last[c.Pid]; ok && last.Name == c.Name && deltaT > 0 {
+			u.DownBPS = series.CounterRate(last.BytesIn, c.BytesIn, deltaT)
+			u.UpBPS = series.CounterRate(last.BytesOut, c.BytesOut, deltaT)
+		}
+		totalConnections += c.Connections
+		usage = append(usage, u)
+	}
+	for pid := range s.identities {
+		if _, ok := seen[pid]; !ok {
+			delete(s.identities, pid)
+		}
+	}
+	s.last, s.lastTime = seen, now
+
+	estimated := !s.source.CountsBytes()
+	if estimated && totalConnections > 0 && s.opts.TotalRate != nil {
+		down, up := s.opts.TotalRate()
+		for i := range usage {
+			share := float64(usage[i].Connections) / float64(totalConnections)
+			usage[i].DownBPS, usage[i].UpBPS = down*share, up*share
+		}
+	}
+	sortUsage(usage)
+
+	s.mu.Lock()
+	s.snapshot = Snapshot{Timestamp: now.Unix(), Estimated: estimated, Processes: usage}
+	s.mu.Unlock()
+}
+
+// identify returns the name and group of the process of c, looking it up
+// the first time it is seen.
+func (s *Sampler) identify(c Counter) identity {
+	if id, ok := s.identities[c.Pid]; ok && (c.Name == "" || id.name == c.Name) {
+		return id
+	}
+	id := identity{name: c.Name}
+	if p, err := process.NewProcess(c.Pid); err == nil {
+		if id.name == "" {
+			id.name, _ = p.Name()
+		}
+		if s.opts.Identify != nil {
+			id.group = s.opts.Identify(p)
+		}
+	}
+	if id.group == "" {
+		id.group = id.name
+	}
+	s.identities[c.Pid] = id
+	return id
+}
+
+// sortUsage orders by total rate, then by connections and name.
+func sortUsage(usage []Usage) {
+	sort.Slice(usage, func(i, j int) bool {
+		a, b := usage[i], usage[j]
+		if ra, rb := a.DownBPS+a.UpBPS, b.DownBPS+b.UpBPS; ra != rb {
+			return ra > rb
+		}
+		if a.Connections != b.Connections {
+			return a.Connections > b.Connections
+		}
+		return a.Name < b.Name
+	})
+}
@@ -0,0 +1,144 @@
+package netprocs
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+// fakeSource returns its counters and error, which tests change between
+// samples.
+type fakeSource struct {
+	counters []Counter
+	err      error
+	bytes    bool
+}
+
+func (s *fakeSource) Counters() ([]Counter, error) {
+	return s.counters, s.err
+}
+
+func (s *fakeSource) CountsBytes() bool {
+	return s.bytes
+}
+
+// Pids far above any pid_max, so no process is found and names come from
+// the counters.
+const (
+	pidA int32 = 1<<31 - 3
+	pidB int32 = 1<<31 - 2
+)
+
+func TestSamplerRates(t *testing.T) {
+	source := &fakeSource{bytes: true}
+	s := NewSampler(source, Options{Interval: time.Second})
+	start := time.Unix(1700000000, 0)
+
+	source.counters = []Counter{
+		{Pid: pidA, Name: "curl", BytesIn: 1000, BytesOut: 100},
+		{Pid: pidB, Name: "Safari", BytesIn: 5000, BytesOut: 500},
+	}
+	s.performSample(start)
+	for _, u := range s.Snapshot().Processes {
+		if u.DownBPS != 0 || u.UpBPS != 0 {
+			t.Errorf("first sample of %s has rates %g/%g, want none", u.Name, u.DownBPS, u.UpBPS)
+		}
+	}
+
+	source.counters = []Counter{
+		{Pid: pidA, Name: "curl", BytesIn: 21000, BytesOut: 300},
+		// Counters that went backwards have no rate
+		{Pid: pidB, Name: "Safari", BytesIn: 1000, BytesOut: 700},
+	}
+	s.performSample(start.Add(2 * time.Second))
+	snapshot := s.Snapshot()
+	if snapshot.Estimated || snapshot.Timestamp != start.Unix()+2 {
+		t.Errorf("snapshot = %+v, want measured rates at the second sample", snapshot)
+	}
+	want := []Usage{
+		{Pid: pidA, Name: "curl", Group: "curl", DownBPS: 10000, UpBPS: 100},
+		{Pid: pidB, Name: "Safari", Group: "Safari", DownBPS: 0, UpBPS: 100},
+	}
+	if len(snapshot.Processes) != len(want) {
+		t.Fatalf("processes = %+v, want %+v", snapshot.Processes, want)
+	}
+	for i := range want {
+		if snapshot.Processes[i] != want[i] {
+			t.Errorf("process %d = %+v, want %+v", i, snapshot.Processes[i], want[i])
+		}
+	}
+
+	// A reused pid starts over
+	source.counters = []Counter{{Pid: pidA, Name: "ssh", BytesIn: 50000, BytesOut: 50000}}
+	s.performSample(start.Add(4 * time.Second))
+	if got := s.Snapshot().Processes; len(got) != 1 || got[0].Name != "ssh" || got[0].DownBPS != 0 {
+		t.Errorf("processes after pid reuse = %+v, want ssh without a rate", got)
+	}
+
+	// A failing source keeps the last snapshot
+	source.err = errors.New("nettop failed")
+	s.performSample(start.Add(6 * time.Second))
+	if got := s.Snapshot(); got.Timestamp != start.Unix()+4 {
+		t.Errorf("snapshot after failure = %+v, want the previous one", got)
+	}
+}
+
+func TestSamplerEstimated(t *testing.T) {
+	source := &fakeSource{counters: []Counter{
+		{Pid: pidA, Name: "curl", Connections: 1},
+		{Pid: pidB, Name: "firefox", Connections: 3},
+	}}
+	s := NewSampler(source, Options{
+		Interval: time.Second,
+		TotalRate: func() (float64, float64) {
+			return 8000, 400
+		},
+	})
+	s.performSample(time.Unix(1700000000, 0))
+
+	snapshot := s.Snapshot()
+	if !snapshot.Estimated {
+		t.Error("snapshot is not marked estimated")
+	}
+	want := []Usage{
+		{Pid: pidB, Name: "firefox", Group: "firefox", Connections: 3, DownBPS: 6000, UpBPS: 300},
+		{Pid: pidA, Name: "curl", Group: "curl", Connections: 1, DownBPS: 2000, UpBPS: 100},
+	}
+	if len(snapshot.Processes) != len(want) {
+		t.Fatalf("processes = %+v, want %+v", snapshot.Processes, want)
+	}
+	for i := range want {
+		if snapshot.Processes[i] != want[i] {
+			t.Errorf("process %d = %+v, want %+v", i, snapshot.Processes[i], want[i])
+		}
+	}
+}
+
+func TestSamplerGrouped(t *testing.T) {
+	source := &fakeSource{counters: []Counter{
+		{Pid: pidA, Name: "Chrome Helper", Connections: 2},
+		{Pid: pidB, Name: "Chrome Helper", Connections: 2},
+		{Pid: pidA - 1, Name: "curl", Connections: 1},
+	}}
+	s := NewSampler(source, Options{
+		Interval: time.Second,
+		TotalRate: func() (float64, float64) {
+			return 500, 50
+		},
+	})
+	s.performSample(time.Unix(1700000000, 0))
+
+	got := s.Grouped().Processes
+	want := []Usage{
+		{Name: "Chrome Helper", Count: 2, Connections: 4, DownBPS: 400, UpBPS: 40},
+		{Name: "curl", Count: 1, Connections: 1, DownBPS: 100, UpBPS: 10},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("groups = %+v, want %+v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("group %d = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+}
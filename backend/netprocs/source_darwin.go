@@ -0,0 +1,10 @@
+//go:build darwin
+
+package netprocs
+
+import "macos-monitor/backend-go/platform"
+
+// NewSource returns the Source of the platform.
+func NewSource() Source {
+	return NettopSource{Run: platform.ExecRunner}
+}
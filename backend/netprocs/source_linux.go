@@ -0,0 +1,8 @@
+//go:build linux
+
+package netprocs
+
+// NewSource returns the Source of the platform.
+func NewSource() Source {
+	return ProcfsSource{Proc: "/proc"}
+}
@@ -0,0 +1,21 @@
+//go:build !darwin && !linux
+
+package netprocs
+
+import "errors"
+
+// NewSource returns the Source of the platform, which cannot attribute
+// network usage.
+func NewSource() Source {
+	return unsupported{}
+}
+
+type unsupported struct{}
+
+func (unsupported) Counters() ([]Counter, error) {
+	return nil, errors.ErrUnsupported
+}
+
+func (unsupported) CountsBytes() bool {
+	return false
+}
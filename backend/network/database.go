@@ -5,21 +5,25 @@ import (
 	"fmt"
 	"sort"
 	"time"
-
-	_ "github.com/mattn/go-sqlite3"
 )
 
 const (
-	dbFileName    = "network_stats.db"
 	trafficTableStmt = `
 	CREATE TABLE IF NOT EXISTS daily_traffic (
-		date TEXT PRIMARY KEY,
+		date TEXT NOT NULL,
+		iface TEXT NOT NULL,
 		first_bytes_recv INTEGER NOT NULL,
 		first_bytes_sent INTEGER NOT NULL,
 		last_bytes_recv  INTEGER NOT NULL,
 		last_bytes_sent  INTEGER NOT NULL,
-		timestamp INTEGER NOT NULL
+		timestamp INTEGER NOT NULL,
+		PRIMARY KEY (date, iface)
 	);`
+
+	// legacyInterface is the interface that was hardcoded before the monitor
+	// tracked every interface. Rows from the old single-interface schema are
+	// attributed to it during migration.
+	legacyInterface = "en1"
 )
 
 // DBManager handles database operations for network statistics.
@@ -27,11 +31,10 @@ type DBManager struct {
 	db *sql.DB
 }
 
-// NewDBManager creates and initializes a new DBManager.
-func NewDBManager() (*DBManager, error) {
-	db, err := sql.Open("sqlite3", dbFileName)
-	if err != nil {
-		return nil, fmt.Errorf("failed to open database: %w", err)
+// NewDBManager creates and initializes a new DBManager on db.
+func NewDBManager(db *sql.DB) (*DBManager, error) {
+	if err := migrateTrafficTable(db); err != nil {
+		return nil, fmt.Errorf("failed to migrate traffic table: %w", err)
 	}
 
 	if _, err := db.Exec(trafficTableStmt); err != nil {
@@ -41,19 +44,64 @@ func NewDBManager() (*DBManager, error) {
 	return &DBManager{db: db}, nil
 }
 
-// Close closes the database connection.
-func (m *DBManager) Close() {
-	m.db.Close()
-}
+// migrateTrafficTable upgrades a daily_traffic table created before
+// per-interface tracking (keyed by date only) to the (date, iface) schema.
+func migrateTrafficTable(db *sql.DB) error {
+	rows, err := db.Query("PRAGMA table_info(daily_traffic)")
+	if err != nil {
+		return fmt.Errorf("failed to inspect table: %w", err)
+	}
+	var exists, hasIface bool
+	for rows.Next() {
+		var (
+			cid, notNull, pk int
+			name, colType    string
+			defaultValue     sql.NullString
+		)
+		if err := rows.Scan(&cid, &name, &colType, &notNull, &defaultValue, &pk); err != nil {
+			rows.Close()
+			return fmt.Errorf("failed to scan column info: %w", err)
+		}
+		exists = true
+		if name == "iface" {
+			hasIface = true
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to read column info: %w", err)
+	}
+	if !exists || hasIface {
+		return nil
+	}
 
-// UpdateSample persists the current cumulative network stats.
-func (m *DBManager) UpdateSample(stats IOStats) error {
-	date := time.Now().Format("2006-01-02")
-	var count int
-	err := m.db.QueryRow("SELECT COUNT(*) FROM daily_traffic WHERE date = ?", date).Scan(&count)
+	tx, err := db.Begin()
 	if err != nil {
-		return fmt.Errorf("failed to query for date: %w", err)
+		return fmt.Errorf("failed to begin transaction: %w", err)
 	}
+	defer tx.Rollback()
+
+	stmts := []string{
+		"ALTER TABLE daily_traffic RENAME TO daily_traffic_legacy",
+		trafficTableStmt,
+		`INSERT INTO daily_traffic (date, iface, first_bytes_recv, first_bytes_sent, last_bytes_recv, last_bytes_sent, timestamp)
+		 SELECT date, '` + legacyInterface + `', first_bytes_recv, first_bytes_sent, last_bytes_recv, last_bytes_sent, timestamp
+		 FROM daily_traffic_legacy`,
+		"DROP TABLE daily_traffic_legacy",
+	}
+	for _, stmt := range stmts {
+		if _, err := tx.Exec(stmt); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// UpdateSamples persists the current cumulative stats of each interface.
+// The first sample of a day for an interface records the day's baseline;
+// later samples only move the "last" counters forward.
+func (m *DBManager) UpdateSamples(samples map[string]IOStats) error {
+	date := time.Now().Format("2006-01-02")
 
 	tx, err := m.db.Begin()
 	if err != nil {
@@ -61,47 +109,55 @@ func (m *DBManager) UpdateSample(stats IOStats) error {
 	}
 	defer tx.Rollback()
 
-	if count == 0 {
-		// First sample of the day
-		stmt, err := tx.Prepare(`
-			INSERT INTO daily_traffic (date, first_bytes_recv, first_bytes_sent, last_bytes_recv, last_bytes_sent, timestamp)
-			VALUES (?, ?, ?, ?, ?, ?)
-		`)
-		if err != nil {
-			return fmt.Errorf("failed to prepare insert statement: %w", err)
-		}
-		defer stmt.Close()
-		_, err = stmt.Exec(date, stats.BytesRecv, stats.BytesSent, stats.BytesRecv, stats.BytesSent, stats.Time.Unix())
-		if err != nil {
-			return fmt.Errorf("failed to execute insert: %w", err)
-		}
-	} else {
-		// Subsequent sample of the day
-		stmt, err := tx.Prepare(`
-			UPDATE daily_traffic
-			SET last_bytes_recv = ?, last_bytes_sent = ?, timestamp = ?
-			WHERE date = ?
-		`)
-		if err != nil {
-			return fmt.Errorf("failed to prepare update statement: %w", err)
-		}
-		defer stmt.Close()
-		_, err = stmt.Exec(stats.BytesRecv, stats.BytesSent, stats.Time.Unix(), date)
+	stmt, err := tx.Prepare(`
+		INSERT INTO daily_traffic (date, iface, first_bytes_recv, first_bytes_sent, last_bytes_recv, last_bytes_sent, timestamp)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT (date, iface) DO UPDATE SET
+			last_bytes_recv = excluded.last_bytes_recv,
+			last_bytes_sent = excluded.last_bytes_sent,
+			timestamp = excluded.timestamp
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to prepare upsert statement: %w", err)
+	}
+	defer stmt.Close()
+
+	for iface, stats := range samples {
+		_, err = stmt.Exec(date, iface, stats.BytesRecv, stats.BytesSent, stats.BytesRecv, stats.BytesSent, stats.Time.Unix())
 		if err != nil {
-			return fmt.Errorf("failed to execute update: %w", err)
+			return fmt.Errorf("failed to execute upsert for '%s': %w", iface, err)
 		}
 	}
 
 	return tx.Commit()
 }
 
-func (m *DBManager) GetDailyTrafficForLast7Days() ([]DailyTraffic, error) {
-	sevenDaysAgo := time.Now().AddDate(0, 0, -6).Format("2006-01-02")
+// GetDailyTrafficForLast7Days returns the traffic of the given interface for
+// each of the last seven days, most recent first. AllInterfaces sums the
+// traffic of every interface.
+func (m *DBManager) GetDailyTrafficForLast7Days(iface string) ([]DailyTraffic, error) {
+	today := time.Now()
+	results, err := m.GetDailyTraffic(iface, today.AddDate(0, 0, -6), today)
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(results, func(i, j int) bool {
+		return results[i].Date > results[j].Date
+	})
+	return results, nil
+}
+
+// GetDailyTraffic returns the traffic of the given interface for each day
+// from "from" to "to", both inclusive, oldest first. Days without a record
+// are filled with zero traffic. AllInterfaces sums the traffic of every
+// interface.
+func (m *DBManager) GetDailyTraffic(iface string, from, to time.Time) ([]DailyTraffic, error) {
 	rows, err := m.db.Query(`
-        SELECT date, MAX(0, last_bytes_recv - first_bytes_recv), MAX(0, last_bytes_sent - first_bytes_sent)
+        SELECT date, SUM(MAX(0, last_bytes_recv - first_bytes_recv)), SUM(MAX(0, last_bytes_sent - first_bytes_sent))
         FROM daily_traffic
-        WHERE date >= ?
-    `, sevenDaysAgo)
+        WHERE date >= ? AND date <= ? AND (? = ? OR iface = ?)
+        GROUP BY date
+    `, from.Format("2006-01-02"), to.Format("2006-01-02"), iface, AllInterfaces, iface)
 	if err != nil {
 		return nil, fmt.Errorf("failed to query daily traffic: %w", err)
 	}
@@ -115,24 +171,58 @@ func (m *DBManager) GetDailyTrafficForLast7Days() ([]DailyTraffic, error) {
 		}
 		trafficByDate[dt.Date] = dt
 	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read daily traffic: %w", err)
+	}
 
 	var results []DailyTraffic
-	for i := 0; i < 7; i++ {
-		date := time.Now().AddDate(0, 0, -i).Format("2006-01-02")
+	for _, day := range daysBetween(from, to) {
+		date := day.Format("2006-01-02")
 		if traffic, ok := trafficByDate[date]; ok {
 			results = append(results, traffic)
 		} else {
-			results = append(results, DailyTraffic{
-				Date:      date,
-				DownBytes: 0,
-				UpBytes:   0,
-			})
+			results = append(results, DailyTraffic{Date: date})
 		}
 	}
-	sort.Slice(results, func(i, j int) bool {
-		return results[i].Date > results[j].Date
-	})
-
 	return results, nil
 }
 
+// daysBetween returns midnight of every calendar day from "from" to "to",
+// both inclusive. Days are stepped by date rather than by 24 hours so DST
+// changes cannot skip or repeat a day.
+func daysBetween(from, to time.Time) []time.Time {
+	y, mo, d := from.Date()
+	var days []time.Time
+	for i := 0; ; i++ {
+		day := time.Date(y, mo, d+i, 0, 0, 0, 0, from.Location())
+		if day.After(to) {
+			return days
+		}
+		days = append(days, day)
+	}
+}
+
+// GetDailyTrafficByInterface returns the traffic of each interface on date,
+// formatted as YYYY-MM-DD.
+func (m *DBManager) GetDailyTrafficByInterface(date string) (map[string]DailyTraffic, error) {
+	rows, err := m.db.Query(`
+        SELECT iface, MAX(0, last_bytes_recv - first_bytes_recv), MAX(0, last_bytes_sent - first_bytes_sent)
+        FROM daily_traffic
+        WHERE date = ?
+    `, date)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query daily traffic: %w", err)
+	}
+	defer rows.Close()
+
+	result := make(map[string]DailyTraffic)
+	for rows.Next() {
+		var iface string
+		dt := DailyTraffic{Date: date}
+		if err := rows.Scan(&iface, &dt.DownBytes, &dt.UpBytes); err != nil {
+			return nil, fmt.Errorf("failed to scan daily traffic row: %w", err)
+		}
+		result[iface] = dt
+	}
+	return result, rows.Err()
+}
@@ -2,6 +2,7 @@ package network
 
 // RealtimeRate represents the real-time upload and download speed.
 type RealtimeRate struct {
+	Interface string  `json:"iface"`
 	Timestamp int64   `json:"timestamp"`
 	DownBPS   float64 `json:"down_bps"`
 	UpBPS     float64 `json:"up_bps"`
@@ -16,6 +17,7 @@ type HourlyPoint struct {
 
 // HourlyStats represents the network speed over the last hour.
 type HourlyStats struct {
+	Interface   string        `json:"iface"`
 	IntervalMin int           `json:"interval_min"`
 	Points      []HourlyPoint `json:"points"`
 }
@@ -23,8 +25,8 @@ type HourlyStats struct {
 // DailyTraffic represents the total traffic for a single day.
 type DailyTraffic struct {
 	Date      string `json:"date"`
-	DownBytes int64 `json:"down_bytes"`
-	UpBytes   int64 `json:"up_bytes"`
+	DownBytes int64  `json:"down_bytes"`
+	UpBytes   int64  `json:"up_bytes"`
 }
 
 // SinceBootTraffic represents the total traffic since the system booted up.
@@ -33,8 +35,19 @@ type SinceBootTraffic struct {
 	UpBytes   int64 `json:"up_bytes"`
 }
 
-// Stats represents the consolidated traffic statistics.
+// Stats represents the consolidated traffic statistics. Either Daily7d or,
+// for an explicit date range, Range is set.
 type Stats struct {
-	Daily7d   []DailyTraffic   `json:"daily_7d"`
+	Interface string           `json:"iface"`
+	Daily7d   []DailyTraffic   `json:"daily_7d,omitempty"`
+	Range     *TrafficRange    `json:"range,omitempty"`
+	SinceBoot SinceBootTraffic `json:"since_boot"`
+}
+
+// InterfaceSnapshot holds the current rate and since-boot counters of one
+// interface.
+type InterfaceSnapshot struct {
+	Interface string           `json:"iface"`
+	Rate      RealtimeRate     `json:"rate"`
 	SinceBoot SinceBootTraffic `json:"since_boot"`
 }
@@ -1,23 +1,44 @@
 package network
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"log"
+	"net"
+	"slices"
+	"sort"
 	"sync"
 	"time"
 
 	psutil_net "github.com/shirou/gopsutil/v3/net"
+	"macos-monitor/backend-go/hub"
+	"macos-monitor/backend-go/series"
 )
 
-const (
-	wifiInterface        = "en1"
-	sampleInterval       = 1 * time.Second
-	persistenceInterval  = 1 * time.Minute
-	maxSleepInterval     = 10 * time.Second
-	movingAverageWindow  = 3
-	hourlyPoints         = 60 // 60 minutes / 5 minutes
-	hourlyInterval       = 1 * time.Minute
-)
+// AllInterfaces selects the aggregate of every monitored interface.
+const AllInterfaces = "all"
+
+// Options configures the sampling and aggregation of a Monitor.
+type Options struct {
+	// Interfaces restricts monitoring to the listed interfaces. When empty,
+	// every non-loopback interface is monitored.
+	Interfaces          []string
+	SampleInterval      time.Duration
+	PersistenceInterval time.Duration
+	// MaxSleepInterval is the longest gap between two samples that still
+	// yields a rate; longer gaps are treated as sleep/wake.
+	MaxSleepInterval    time.Duration
+	MovingAverageWindow int
+	HourlyPoints        int
+	HourlyInterval      time.Duration
+	// Quotas are checked after every persisted sample.
+	Quotas []Quota
+}
+
+// ErrUnknownInterface is returned when stats are requested for an interface
+// the monitor has not seen.
+var ErrUnknownInterface = errors.New("unknown interface")
 
 // IOStats holds the raw byte counters for an interface at a specific time.
 type IOStats struct {
@@ -26,322 +47,504 @@ type IOStats struct {
 	BytesRecv uint64
 }
 
+// ifaceState holds the rate calculation state of a single interface, or of
+// the aggregate of all interfaces.
+type ifaceState struct {
+	lastSample       IOStats
+	realtimeRate     RealtimeRate
+	trafficSinceBoot SinceBootTraffic
+	downRateMA       *series.MovingAverage
+	upRateMA         *series.MovingAverage
+	hourlyRingBuffer *series.RingBuffer
+	// gone is set while the interface is missing from the OS statistics
+	gone bool
+}
+
+func newIfaceState(name string, opts Options) *ifaceState {
+	return &ifaceState{
+		realtimeRate:     RealtimeRate{Interface: name},
+		downRateMA:       series.NewMovingAverage(opts.MovingAverageWindow),
+		upRateMA:         series.NewMovingAverage(opts.MovingAverageWindow),
+		hourlyRingBuffer: series.NewRingBuffer(opts.HourlyPoints, opts.HourlyInterval, 2),
+	}
+}
+
 // Monitor handles all network monitoring, calculation, and aggregation.
+// Every non-loopback interface reported by the OS is tracked separately, and
+// an aggregate view is kept under AllInterfaces.
 type Monitor struct {
-	db             *DBManager
-	realtimeRate      RealtimeRate
-	hourlyStats       HourlyStats
-	trafficSinceBoot SinceBootTraffic
+	db   *DBManager
+	opts Options
 
 	// Internal state
-	mu                 sync.RWMutex
-	lastSample         IOStats
-	downRateMA         *movingAverage
-	upRateMA           *movingAverage
-	hourlyRingBuffer   *ringBuffer
-	
+	mu       sync.RWMutex
+	ifaces   map[string]*ifaceState
+	total    *ifaceState
+	loopback map[string]bool
+
+	// Last quota levels, only accessed by the persistence loop
+	quotaStates map[string]quotaState
+
 	// WebSocket hub
-	hub *Hub
-}
+	hub *hub.Hub
 
-// NewMonitor creates and initializes a new Monitor.
-func NewMonitor() (*Monitor, error) {
-	db, err := NewDBManager()
-	if err != nil {
-		return nil, fmt.Errorf("failed to initialize database manager: %w", err)
-	}
+	// Called with every calculated rate, registered before Run
+	rateObservers []func(RealtimeRate)
+}
 
+// NewMonitor creates and initializes a new Monitor that persists daily
+// traffic through db and publishes rates and quota events on h.
+func NewMonitor(db *DBManager, h *hub.Hub, opts Options) (*Monitor, error) {
 	m := &Monitor{
-		db:           db,
-		downRateMA:   newMovingAverage(movingAverageWindow),
-		upRateMA:     newMovingAverage(movingAverageWindow),
-		hourlyRingBuffer: newRingBuffer(hourlyPoints),
-		hub:          NewHub(),
-	}
+		db:     db,
+		opts:   opts,
+		ifaces: make(map[string]*ifaceState),
+		total:  newIfaceState(AllInterfaces, opts),
+		hub:    h,
 
-	// Initialize stats structures
-	m.hourlyStats.IntervalMin = int(hourlyInterval.Minutes())
-	m.hourlyStats.Points = make([]HourlyPoint, 0, hourlyPoints)
+		quotaStates: make(map[string]quotaState),
+	}
 
 	// Fetch initial stats to establish a baseline
-	initialStats, err := getInterfaceStats(wifiInterface)
+	initialStats, err := getInterfaceStats()
 	if err != nil {
-		log.Printf("Warning: could not get initial stats for '%s': %v. Will retry.", wifiInterface, err)
+		log.Printf("Warning: could not get initial interface stats: %v. Will retry.", err)
 		// Let it start anyway, the loop will handle recovery
 	}
-	m.lastSample = initialStats
-
+	for name, stats := range m.filterInterfaces(initialStats) {
+		state := newIfaceState(name, opts)
+		state.lastSample = stats
+		m.ifaces[name] = state
+	}
 
 	return m, nil
 }
 
-// Start begins the monitoring goroutines.
-func (m *Monitor) Start() {
-	go m.hub.run()
-	go m.sampleLoop()
-	go m.persistenceLoop()
+// Run samples and persists traffic until ctx is done, then persists the
+// last sample of every interface.
+func (m *Monitor) Run(ctx context.Context) {
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		m.sampleLoop(ctx)
+	}()
+	go func() {
+		defer wg.Done()
+		m.persistenceLoop(ctx)
+	}()
+	wg.Wait()
+
+	m.persistSample()
 }
 
-// Close cleans up resources.
-func (m *Monitor) Close() {
-	m.db.Close()
+// AddRateObserver registers fn to be called from the sampling goroutine with
+// every calculated rate, including the aggregate. It must be called before
+// Run.
+func (m *Monitor) AddRateObserver(fn func(RealtimeRate)) {
+	m.rateObservers = append(m.rateObservers, fn)
 }
 
-// GetRealtimeRate returns the latest calculated real-time rate.
-func (m *Monitor) GetRealtimeRate() RealtimeRate {
+// Interfaces returns the names of all monitored interfaces, sorted.
+func (m *Monitor) Interfaces() []string {
 	m.mu.RLock()
 	defer m.mu.RUnlock()
-	return m.realtimeRate
+	names := make([]string, 0, len(m.ifaces))
+	for name := range m.ifaces {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
 }
 
-// GetHourlyStats returns the aggregated stats for the last hour.
-func (m *Monitor) GetHourlyStats() HourlyStats {
+// HasInterface reports whether iface is a monitored interface or
+// AllInterfaces.
+func (m *Monitor) HasInterface(iface string) bool {
 	m.mu.RLock()
 	defer m.mu.RUnlock()
-	// Build the points from the ring buffer on demand
-	m.hourlyStats.Points = m.hourlyRingBuffer.getPoints()
-	return m.hourlyStats
+	_, err := m.state(iface)
+	return err == nil
+}
+
+// GetRealtimeRate returns the latest calculated real-time rate of an interface.
+func (m *Monitor) GetRealtimeRate(iface string) (RealtimeRate, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	state, err := m.state(iface)
+	if err != nil {
+		return RealtimeRate{}, err
+	}
+	return state.realtimeRate, nil
 }
 
-// GetStats returns the 7-day and since-boot statistics.
-func (m *Monitor) GetStats() (Stats, error) {
+// GetHourlyStats returns the aggregated stats of an interface for the last hour.
+func (m *Monitor) GetHourlyStats(iface string) (HourlyStats, error) {
 	m.mu.RLock()
 	defer m.mu.RUnlock()
-	
-	daily7d, err := m.db.GetDailyTrafficForLast7Days()
+	state, err := m.state(iface)
+	if err != nil {
+		return HourlyStats{}, err
+	}
+	// Build the points from the ring buffer on demand
+	return HourlyStats{
+		Interface:   iface,
+		IntervalMin: int(m.opts.HourlyInterval.Minutes()),
+		Points:      hourlyPoints(state.hourlyRingBuffer.Points()),
+	}, nil
+}
+
+// GetStats returns the 7-day and since-boot statistics of an interface.
+func (m *Monitor) GetStats(iface string) (Stats, error) {
+	sinceBoot, err := m.GetSinceBootTraffic(iface)
+	if err != nil {
+		return Stats{}, err
+	}
+
+	daily7d, err := m.db.GetDailyTrafficForLast7Days(iface)
 	if err != nil {
 		return Stats{}, err
 	}
 
 	return Stats{
+		Interface: iface,
 		Daily7d:   daily7d,
-		SinceBoot: m.trafficSinceBoot,
+		SinceBoot: sinceBoot,
 	}, nil
 }
 
-// Hub returns the WebSocket hub.
-func (m *Monitor) Hub() *Hub {
-	return m.hub
+// GetTrafficRange returns the traffic of an interface between two dates,
+// both inclusive, grouped into periods.
+func (m *Monitor) GetTrafficRange(iface string, from, to time.Time, group Grouping) (TrafficRange, error) {
+	days, err := m.db.GetDailyTraffic(iface, from, to)
+	if err != nil {
+		return TrafficRange{}, err
+	}
+	buckets, err := groupTraffic(days, group)
+	if err != nil {
+		return TrafficRange{}, err
+	}
+
+	result := TrafficRange{
+		From:    from.Format("2006-01-02"),
+		To:      to.Format("2006-01-02"),
+		Group:   group,
+		Buckets: buckets,
+	}
+	for _, b := range buckets {
+		result.DownBytes += b.DownBytes
+		result.UpBytes += b.UpBytes
+	}
+	return result, nil
+}
+
+// GetSinceBootTraffic returns the since-boot counters of an interface.
+func (m *Monitor) GetSinceBootTraffic(iface string) (SinceBootTraffic, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	state, err := m.state(iface)
+	if err != nil {
+		return SinceBootTraffic{}, err
+	}
+	return state.trafficSinceBoot, nil
+}
+
+// GetSnapshots returns the current rate and since-boot counters of every
+// monitored interface, sorted by name. The aggregate is not included.
+func (m *Monitor) GetSnapshots() []InterfaceSnapshot {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	snapshots := make([]InterfaceSnapshot, 0, len(m.ifaces))
+	for name, state := range m.ifaces {
+		snapshots = append(snapshots, InterfaceSnapshot{
+			Interface: name,
+			Rate:      state.realtimeRate,
+			SinceBoot: state.trafficSinceBoot,
+		})
+	}
+	sort.Slice(snapshots, func(i, j int) bool {
+		return snapshots[i].Interface < snapshots[j].Interface
+	})
+	return snapshots
+}
+
+// GetTodayTraffic returns today's traffic of every interface with a daily
+// record, as of the last persisted sample.
+func (m *Monitor) GetTodayTraffic() (map[string]DailyTraffic, error) {
+	return m.db.GetDailyTrafficByInterface(time.Now().Format("2006-01-02"))
 }
 
 // --- Internal loops and helpers ---
 
-func (m *Monitor) sampleLoop() {
-	ticker := time.NewTicker(sampleInterval)
+// state returns the state for iface. The caller must hold m.mu.
+func (m *Monitor) state(iface string) (*ifaceState, error) {
+	if iface == AllInterfaces {
+		return m.total, nil
+	}
+	state, ok := m.ifaces[iface]
+	if !ok {
+		return nil, fmt.Errorf("%w: '%s'", ErrUnknownInterface, iface)
+	}
+	return state, nil
+}
+
+// filterInterfaces drops the interfaces that are not monitored from stats.
+// With no configured interfaces, only loopback interfaces are dropped:
+// loopback traffic never leaves the host, so it is neither tracked nor
+// counted towards the aggregate.
+func (m *Monitor) filterInterfaces(stats map[string]IOStats) map[string]IOStats {
+	if len(m.opts.Interfaces) > 0 {
+		for name := range stats {
+			if !slices.Contains(m.opts.Interfaces, name) {
+				delete(stats, name)
+			}
+		}
+		return stats
+	}
+
+	for name := range stats {
+		if _, known := m.loopback[name]; !known {
+			m.loopback = loopbackInterfaces()
+			break
+		}
+	}
+	for name := range stats {
+		if _, known := m.loopback[name]; !known {
+			// Not reported by the OS interface list; remember it so the
+			// list is not refreshed on every sample.
+			m.loopback[name] = false
+		}
+		if m.loopback[name] {
+			delete(stats, name)
+		}
+	}
+	return stats
+}
+
+func (m *Monitor) sampleLoop(ctx context.Context) {
+	ticker := time.NewTicker(m.opts.SampleInterval)
 	defer ticker.Stop()
 
-	for range ticker.C {
-		m.performSample()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			m.performSample()
+		}
 	}
 }
 
-func (m *Monitor) persistenceLoop() {
-	ticker := time.NewTicker(persistenceInterval)
+func (m *Monitor) persistenceLoop(ctx context.Context) {
+	ticker := time.NewTicker(m.opts.PersistenceInterval)
 	defer ticker.Stop()
-	
+
 	// Run once at the start
 	m.persistSample()
-
-	for range ticker.C {
-		m.persistSample()
+	m.checkQuotas()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			m.persistSample()
+			m.checkQuotas()
+		}
 	}
 }
 
 func (m *Monitor) performSample() {
-	currentStats, err := getInterfaceStats(wifiInterface)
+	currentStats, err := getInterfaceStats()
 	if err != nil {
-		log.Printf("Error sampling interface: %v", err)
-		// Pauses sampling as per requirements if interface is not available
+		log.Printf("Error sampling interfaces: %v", err)
+		// Pauses sampling as per requirements if interfaces are not available
 		return
 	}
 
 	m.mu.Lock()
-	defer m.mu.Unlock()
+	currentStats = m.filterInterfaces(currentStats)
+
+	var (
+		totalDownBPS, totalUpBPS float64
+		sampled                  int
+		rates                    = make([]RealtimeRate, 0, len(currentStats)+1)
+	)
+	m.total.trafficSinceBoot = SinceBootTraffic{}
+	for name, stats := range currentStats {
+		state, ok := m.ifaces[name]
+		if !ok {
+			log.Printf("Discovered network interface '%s'", name)
+			state = newIfaceState(name, m.opts)
+			m.ifaces[name] = state
+		}
+		state.gone = false
+
+		// Update total since boot stats. These values are cumulative since boot.
+		state.trafficSinceBoot.UpBytes = int64(stats.BytesSent)
+		state.trafficSinceBoot.DownBytes = int64(stats.BytesRecv)
+		m.total.trafficSinceBoot.UpBytes += state.trafficSinceBoot.UpBytes
+		m.total.trafficSinceBoot.DownBytes += state.trafficSinceBoot.DownBytes
+
+		downBPS, upBPS, ok := state.sample(stats, m.opts.MaxSleepInterval)
+		if !ok {
+			continue
+		}
+		totalDownBPS += downBPS
+		totalUpBPS += upBPS
+		sampled++
+		rates = append(rates, state.realtimeRate)
+	}
+
+	// Interfaces that went away, such as a VPN going down or an unplugged
+	// adapter, keep their history but no longer report their last rate
+	for name, state := range m.ifaces {
+		if _, ok := currentStats[name]; ok || state.gone {
+			continue
+		}
+		log.Printf("Network interface '%s' went away", name)
+		state.gone = true
+		state.clearRate()
+		rates = append(rates, state.realtimeRate)
+	}
+
+	if sampled == 0 {
+		m.total.clearRate()
+	} else {
+		m.total.record(totalDownBPS, totalUpBPS)
+	}
+	rates = append(rates, m.total.realtimeRate)
+	m.mu.Unlock()
+
+	// Broadcast to WebSocket clients
+	for _, rate := range rates {
+		m.hub.Publish(hub.TopicNetworkRate, "rate", rate.Interface, rate)
+		for _, fn := range m.rateObservers {
+			fn(rate)
+		}
+	}
+}
 
-	// Update total since boot stats. These values are cumulative since boot.
-	m.trafficSinceBoot.UpBytes = int64(currentStats.BytesSent)
-	m.trafficSinceBoot.DownBytes = int64(currentStats.BytesRecv)
-	
+// sample calculates the rates of an interface from its current counters. It
+// reports false when no rate could be calculated for this sample.
+func (s *ifaceState) sample(currentStats IOStats, maxSleepInterval time.Duration) (downBPS, upBPS float64, ok bool) {
 	// Check for sleep/wake or initial sample
-	deltaT := currentStats.Time.Sub(m.lastSample.Time).Seconds()
-	if m.lastSample.Time.IsZero() || deltaT <= 0 || deltaT > maxSleepInterval.Seconds() {
-		log.Printf("Interval too long (%.2fs) or invalid. Skipping rate calculation for this sample.", deltaT)
-		m.lastSample = currentStats
+	deltaT := currentStats.Time.Sub(s.lastSample.Time).Seconds()
+	if s.lastSample.Time.IsZero() || deltaT <= 0 || deltaT > maxSleepInterval.Seconds() {
+		log.Printf("Interval too long (%.2fs) or invalid for '%s'. Skipping rate calculation for this sample.", deltaT, s.realtimeRate.Interface)
+		s.lastSample = currentStats
 		// Reset moving average to avoid a spike on the next valid sample
-		m.downRateMA.reset()
-		m.upRateMA.reset()
-		return
+		s.downRateMA.Reset()
+		s.upRateMA.Reset()
+		return 0, 0, false
 	}
 
 	// Calculate raw BPS, checking for counter resets
-	var downBPS, upBPS float64
-	if currentStats.BytesRecv >= m.lastSample.BytesRecv {
-		downBPS = float64(currentStats.BytesRecv-m.lastSample.BytesRecv) / deltaT
+	if currentStats.BytesRecv >= s.lastSample.BytesRecv {
+		downBPS = float64(currentStats.BytesRecv-s.lastSample.BytesRecv) / deltaT
 	}
-	if currentStats.BytesSent >= m.lastSample.BytesSent {
-		upBPS = float64(currentStats.BytesSent-m.lastSample.BytesSent) / deltaT
+	if currentStats.BytesSent >= s.lastSample.BytesSent {
+		upBPS = float64(currentStats.BytesSent-s.lastSample.BytesSent) / deltaT
+	}
+
+	s.record(downBPS, upBPS)
+
+	// Update last sample
+	s.lastSample = currentStats
+	return downBPS, upBPS, true
+}
+
+// clearRate zeroes the real-time rate and resets the moving averages.
+func (s *ifaceState) clearRate() {
+	s.downRateMA.Reset()
+	s.upRateMA.Reset()
+	s.realtimeRate = RealtimeRate{
+		Interface: s.realtimeRate.Interface,
+		Timestamp: time.Now().Unix(),
 	}
+}
 
+// record feeds raw rates into the moving averages and the hourly ring buffer.
+func (s *ifaceState) record(downBPS, upBPS float64) {
 	// Update moving average
-	smoothDownBPS := m.downRateMA.add(downBPS)
-	smoothUpBPS := m.upRateMA.add(upBPS)
-	
+	smoothDownBPS := s.downRateMA.Add(downBPS)
+	smoothUpBPS := s.upRateMA.Add(upBPS)
+
 	// Update realtime rate for APIs
-	m.realtimeRate = RealtimeRate{
+	s.realtimeRate = RealtimeRate{
+		Interface: s.realtimeRate.Interface,
 		Timestamp: time.Now().Unix(),
 		DownBPS:   smoothDownBPS,
 		UpBPS:     smoothUpBPS,
 	}
 
 	// Update hourly ring buffer
-	m.hourlyRingBuffer.add(downBPS, upBPS)
-
-	// Update last sample
-	m.lastSample = currentStats
-	
-	// Broadcast to WebSocket clients
-	m.hub.broadcast <- m.realtimeRate
+	s.hourlyRingBuffer.Add(downBPS, upBPS)
 }
 
 func (m *Monitor) persistSample() {
 	m.mu.RLock()
-	// Use the most recent sample for persistence
-	sampleToPersist := m.lastSample
+	// Use the most recent sample of each interface for persistence
+	samplesToPersist := make(map[string]IOStats, len(m.ifaces))
+	for name, state := range m.ifaces {
+		if state.lastSample.Time.IsZero() {
+			continue // Don't persist if we have no valid sample
+		}
+		samplesToPersist[name] = state.lastSample
+	}
 	m.mu.RUnlock()
 
-	if sampleToPersist.Time.IsZero() {
-		return // Don't persist if we have no valid sample
+	if len(samplesToPersist) == 0 {
+		return
 	}
 
-	if err := m.db.UpdateSample(sampleToPersist); err != nil {
-		log.Printf("Error persisting sample: %v", err)
+	if err := m.db.UpdateSamples(samplesToPersist); err != nil {
+		log.Printf("Error persisting samples: %v", err)
 	}
 }
 
-
-func getInterfaceStats(name string) (IOStats, error) {
+// getInterfaceStats returns the current counters of every interface, keyed by
+// interface name.
+func getInterfaceStats() (map[string]IOStats, error) {
 	stats, err := psutil_net.IOCounters(true)
 	if err != nil {
-		return IOStats{}, fmt.Errorf("failed to get IOCounters: %w", err)
+		return nil, fmt.Errorf("failed to get IOCounters: %w", err)
 	}
 
+	now := time.Now()
+	result := make(map[string]IOStats, len(stats))
 	for _, s := range stats {
-		if s.Name == name {
-			return IOStats{
-				Time:      time.Now(),
-				BytesSent: s.BytesSent,
-				BytesRecv: s.BytesRecv,
-			}, nil
+		result[s.Name] = IOStats{
+			Time:      now,
+			BytesSent: s.BytesSent,
+			BytesRecv: s.BytesRecv,
 		}
 	}
-
-	return IOStats{}, fmt.Errorf("interface '%s' not found", name)
-}
-
-// --- Moving Average Helper ---
-
-type movingAverage struct {
-	window int
-	values []float64
-	index  int
-	count  int
-}
-
-func newMovingAverage(window int) *movingAverage {
-	return &movingAverage{
-		window: window,
-		values: make([]float64, window),
-	}
-}
-
-func (ma *movingAverage) add(value float64) float64 {
-	ma.values[ma.index] = value
-	ma.index = (ma.index + 1) % ma.window
-	if ma.count < ma.window {
-		ma.count++
-	}
-	return ma.average()
-}
-
-func (ma *movingAverage) average() float64 {
-	if ma.count == 0 {
-		return 0
-	}
-	var sum float64
-	for i := 0; i < ma.count; i++ {
-		sum += ma.values[i]
-	}
-	return sum / float64(ma.count)
+	return result, nil
 }
 
-func (ma *movingAverage) reset() {
-	ma.count = 0
-	ma.index = 0
-}
-
-
-// --- Ring Buffer Helper for Hourly Stats ---
-
-type hourlyBucket struct {
-	downBPSSum float64
-	upBPSSum   float64
-	count      int
-}
-
-type ringBuffer struct {
-	buckets    []hourlyBucket
-	size       int
-	cursor     int
-	lastUpdate time.Time
-}
-
-func newRingBuffer(size int) *ringBuffer {
-	return &ringBuffer{
-		buckets:    make([]hourlyBucket, size),
-		size:       size,
-		lastUpdate: time.Now(),
+// loopbackInterfaces returns the set of interface names flagged as loopback.
+func loopbackInterfaces() map[string]bool {
+	result := make(map[string]bool)
+	ifaces, err := net.Interfaces()
+	if err != nil {
+		log.Printf("Warning: could not list interfaces: %v", err)
+		return result
 	}
-}
-
-func (rb *ringBuffer) advance() {
-	now := time.Now()
-	// Loop to catch up for multiple missed intervals (e.g. after sleep)
-	for now.Sub(rb.lastUpdate) > hourlyInterval {
-		rb.cursor = (rb.cursor + 1) % rb.size
-		rb.buckets[rb.cursor] = hourlyBucket{} // Reset the new bucket
-		rb.lastUpdate = rb.lastUpdate.Add(hourlyInterval)
+	for _, iface := range ifaces {
+		result[iface.Name] = iface.Flags&net.FlagLoopback != 0
 	}
+	return result
 }
 
-func (rb *ringBuffer) add(downBPS, upBPS float64) {
-	rb.advance()
-	bucket := &rb.buckets[rb.cursor]
-	bucket.downBPSSum += downBPS
-	bucket.upBPSSum += upBPS
-	bucket.count++
-}
-
-func (rb *ringBuffer) getPoints() []HourlyPoint {
-	points := make([]HourlyPoint, 0, rb.size)
-	for i := 0; i < rb.size; i++ {
-		// Start from the oldest bucket and go to the newest
-		idx := (rb.cursor + 1 + i) % rb.size
-		bucket := rb.buckets[idx]
-
-		var downAvg, upAvg float64
-		if bucket.count > 0 {
-			downAvg = bucket.downBPSSum / float64(bucket.count)
-			upAvg = bucket.upBPSSum / float64(bucket.count)
-		}
-
-		// The last point is offset -5, the first is -60
-		offset := (i - (rb.size - 1)) * int(hourlyInterval.Minutes())
-
-		points = append(points, HourlyPoint{
-			OffsetMin: offset,
-			DownBPS:   downAvg,
-			UpBPS:     upAvg,
+// hourlyPoints converts the buckets of an interface's ring buffer.
+func hourlyPoints(points []series.Point) []HourlyPoint {
+	result := make([]HourlyPoint, 0, len(points))
+	for _, p := range points {
+		result = append(result, HourlyPoint{
+			OffsetMin: p.OffsetMin,
+			DownBPS:   p.Values[0],
+			UpBPS:     p.Values[1],
 		})
 	}
-	return points
+	return result
 }
@@ -0,0 +1,188 @@
+package network
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"time"
+
+	"macos-monitor/backend-go/hub"
+)
+
+// Direction selects which traffic counts towards a quota.
+type Direction string
+
+const (
+	DirectionDown  Direction = "down"
+	DirectionUp    Direction = "up"
+	DirectionTotal Direction = "total"
+)
+
+// QuotaLevel is how close the usage of a quota is to its limit.
+type QuotaLevel string
+
+const (
+	QuotaOK       QuotaLevel = "ok"
+	QuotaWarning  QuotaLevel = "warning"
+	QuotaExceeded QuotaLevel = "exceeded"
+)
+
+// quotaLevelRank orders the levels so only escalations raise an event.
+var quotaLevelRank = map[QuotaLevel]int{QuotaOK: 0, QuotaWarning: 1, QuotaExceeded: 2}
+
+// Quota is a data cap on an interface for a billing cycle.
+type Quota struct {
+	Name      string    `json:"name"`
+	Interface string    `json:"iface"`
+	Direction Direction `json:"direction"`
+	// LimitBytes is the traffic allowed per billing cycle.
+	LimitBytes uint64 `json:"limit_bytes"`
+	// ResetDay is the day of the month the cycle starts on. In months
+	// shorter than ResetDay, the cycle starts on the last day of the month.
+	ResetDay int `json:"reset_day"`
+	// WarnPercent is the share of the limit at which a warning is raised.
+	WarnPercent float64 `json:"warn_percent"`
+}
+
+// QuotaStatus is the consumption of a quota in the current billing cycle.
+type QuotaStatus struct {
+	Quota
+	CycleStart       string     `json:"cycle_start"`
+	CycleEnd         string     `json:"cycle_end"`
+	UsedBytes        int64      `json:"used_bytes"`
+	RemainingBytes   int64      `json:"remaining_bytes"`
+	UsedPercent      float64    `json:"used_percent"`
+	ProjectedBytes   int64      `json:"projected_bytes"`
+	ProjectedPercent float64    `json:"projected_percent"`
+	Level            QuotaLevel `json:"level"`
+}
+
+// QuotaEvent is published on the alerts topic when a quota reaches its
+// warning threshold or its limit.
+type QuotaEvent struct {
+	Timestamp int64       `json:"timestamp"`
+	Level     QuotaLevel  `json:"level"`
+	Status    QuotaStatus `json:"quota"`
+}
+
+// quotaState remembers the highest level seen for a quota within a cycle.
+type quotaState struct {
+	cycleStart string
+	level      QuotaLevel
+}
+
+// GetQuotaStatuses returns the status of every configured quota.
+func (m *Monitor) GetQuotaStatuses() ([]QuotaStatus, error) {
+	now := time.Now()
+	statuses := make([]QuotaStatus, 0, len(m.opts.Quotas))
+	for _, q := range m.opts.Quotas {
+		status, err := m.quotaStatus(q, now)
+		if err != nil {
+			return nil, err
+		}
+		statuses = append(statuses, status)
+	}
+	return statuses, nil
+}
+
+func (m *Monitor) quotaStatus(q Quota, now time.Time) (QuotaStatus, error) {
+	start, end := billingCycle(now, q.ResetDay)
+	days, err := m.db.GetDailyTraffic(q.Interface, start, now)
+	if err != nil {
+		return QuotaStatus{}, fmt.Errorf("failed to get traffic for quota '%s': %w", q.Name, err)
+	}
+
+	status := QuotaStatus{
+		Quota:      q,
+		CycleStart: start.Format("2006-01-02"),
+		CycleEnd:   end.AddDate(0, 0, -1).Format("2006-01-02"),
+		Level:      QuotaOK,
+	}
+	for _, day := range days {
+		switch q.Direction {
+		case DirectionDown:
+			status.UsedBytes += day.DownBytes
+		case DirectionUp:
+			status.UsedBytes += day.UpBytes
+		default:
+			status.UsedBytes += day.DownBytes + day.UpBytes
+		}
+	}
+
+	// Usage is counted in signed bytes, so larger limits are never reached
+	limit := int64(min(q.LimitBytes, math.MaxInt64))
+	status.RemainingBytes = max(0, limit-status.UsedBytes)
+	status.UsedPercent = float64(status.UsedBytes) / float64(limit) * 100
+
+	// Project the usage so far linearly over the whole cycle.
+	elapsed := now.Sub(start)
+	if elapsed > 0 {
+		status.ProjectedBytes = int64(float64(status.UsedBytes) * float64(end.Sub(start)) / float64(elapsed))
+	}
+	status.ProjectedPercent = float64(status.ProjectedBytes) / float64(limit) * 100
+
+	switch {
+	case status.UsedBytes >= limit:
+		status.Level = QuotaExceeded
+	case status.UsedPercent >= q.WarnPercent:
+		status.Level = QuotaWarning
+	}
+	return status, nil
+}
+
+// checkQuotas broadcasts a QuotaEvent for every quota that reached a new
+// level in the current billing cycle.
+func (m *Monitor) checkQuotas() {
+	for _, event := range m.quotaEvents(time.Now()) {
+		m.hub.Publish(hub.TopicAlerts, "quota", event.Status.Name, event)
+	}
+}
+
+// quotaEvents returns an event for every quota whose level is higher than
+// any seen before in its billing cycle, so each level is reported once per
+// cycle even if the usage drops back, e.g. after a counter reset.
+func (m *Monitor) quotaEvents(now time.Time) []QuotaEvent {
+	var events []QuotaEvent
+	for _, q := range m.opts.Quotas {
+		status, err := m.quotaStatus(q, now)
+		if err != nil {
+			log.Printf("Error checking quota: %v", err)
+			continue
+		}
+
+		last := m.quotaStates[q.Name]
+		if last.cycleStart != status.CycleStart {
+			last = quotaState{cycleStart: status.CycleStart, level: QuotaOK}
+		}
+		if quotaLevelRank[status.Level] > quotaLevelRank[last.level] {
+			log.Printf("Quota '%s' is at %.1f%% of its limit (%s)", q.Name, status.UsedPercent, status.Level)
+			events = append(events, QuotaEvent{
+				Timestamp: now.Unix(),
+				Level:     status.Level,
+				Status:    status,
+			})
+			last.level = status.Level
+		}
+		m.quotaStates[q.Name] = last
+	}
+	return events
+}
+
+// billingCycle returns the start of the cycle containing now and the start
+// of the next one, both at midnight.
+func billingCycle(now time.Time, resetDay int) (start, next time.Time) {
+	y, mo, d := now.Date()
+	start = cycleStartIn(y, mo, resetDay, now.Location())
+	if d < start.Day() {
+		start = cycleStartIn(y, mo-1, resetDay, now.Location())
+	}
+	y, mo, _ = start.Date()
+	return start, cycleStartIn(y, mo+1, resetDay, now.Location())
+}
+
+// cycleStartIn returns the cycle start in the given month, clamping
+// resetDay to the length of the month.
+func cycleStartIn(y int, mo time.Month, resetDay int, loc *time.Location) time.Time {
+	lastDay := time.Date(y, mo+1, 0, 0, 0, 0, 0, loc).Day()
+	return time.Date(y, mo, min(resetDay, lastDay), 0, 0, 0, 0, loc)
+}
@@ -0,0 +1,218 @@
+package network
+
+import (
+	"database/sql"
+	"math"
+	"path/filepath"
+	"testing"
+	"time"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+func TestBillingCycle(t *testing.T) {
+	tests := []struct {
+		now      string
+		resetDay int
+		start    string
+		next     string
+	}{
+		{"2026-03-20", 1, "2026-03-01", "2026-04-01"},
+		{"2026-05-15", 15, "2026-05-15", "2026-06-15"},
+		{"2026-05-14", 15, "2026-04-15", "2026-05-15"},
+		// The cycle starts on the last day of shorter months
+		{"2026-02-10", 31, "2026-01-31", "2026-02-28"},
+		{"2026-02-28", 29, "2026-02-28", "2026-03-29"},
+		{"2026-02-27", 30, "2026-01-30", "2026-02-28"},
+		{"2024-02-29", 30, "2024-02-29", "2024-03-30"},
+		{"2026-03-01", 31, "2026-02-28", "2026-03-31"},
+		{"2026-04-30", 31, "2026-04-30", "2026-05-31"},
+		{"2026-04-29", 31, "2026-03-31", "2026-04-30"},
+		{"2026-06-30", 30, "2026-06-30", "2026-07-30"},
+		// Across the new year
+		{"2026-01-10", 15, "2025-12-15", "2026-01-15"},
+		{"2026-12-20", 15, "2026-12-15", "2027-01-15"},
+		{"2026-01-30", 31, "2025-12-31", "2026-01-31"},
+	}
+	for _, tt := range tests {
+		now, _ := time.ParseInLocation("2006-01-02", tt.now, time.Local)
+		now = now.Add(15 * time.Hour)
+		start, next := billingCycle(now, tt.resetDay)
+		if got, want := start.Format("2006-01-02")+" "+next.Format("2006-01-02"), tt.start+" "+tt.next; got != want {
+			t.Errorf("billingCycle(%s, %d) = %s, want %s", tt.now, tt.resetDay, got, want)
+		}
+		if start.Hour() != 0 || next.Hour() != 0 {
+			t.Errorf("billingCycle(%s, %d) = %v, %v, want midnight", tt.now, tt.resetDay, start, next)
+		}
+	}
+}
+
+func TestCycleStartIn(t *testing.T) {
+	tests := []struct {
+		year     int
+		month    time.Month
+		resetDay int
+		want     string
+	}{
+		{2026, time.January, 31, "2026-01-31"},
+		{2026, time.February, 29, "2026-02-28"},
+		{2024, time.February, 31, "2024-02-29"},
+		{2026, time.September, 31, "2026-09-30"},
+		{2026, time.September, 1, "2026-09-01"},
+		// Month arithmetic wraps into the neighbouring years
+		{2026, 0, 31, "2025-12-31"},
+		{2026, 13, 15, "2027-01-15"},
+	}
+	for _, tt := range tests {
+		if got := cycleStartIn(tt.year, tt.month, tt.resetDay, time.Local).Format("2006-01-02"); got != tt.want {
+			t.Errorf("cycleStartIn(%d, %d, %d) = %s, want %s", tt.year, tt.month, tt.resetDay, got, tt.want)
+		}
+	}
+}
+
+func newTestMonitor(t *testing.T, quotas ...Quota) *Monitor {
+	t.Helper()
+	db, err := sql.Open("sqlite3", filepath.Join(t.TempDir(), "traffic.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	dbm, err := NewDBManager(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &Monitor{
+		db:          dbm,
+		opts:        Options{Quotas: quotas},
+		quotaStates: make(map[string]quotaState),
+	}
+}
+
+// setTraffic records the traffic of an interface on a day.
+func setTraffic(t *testing.T, m *Monitor, date, iface string, down, up int64) {
+	t.Helper()
+	_, err := m.db.db.Exec(`
+		INSERT OR REPLACE INTO daily_traffic (date, iface, first_bytes_recv, first_bytes_sent, last_bytes_recv, last_bytes_sent, timestamp)
+		VALUES (?, ?, 0, 0, ?, ?, 0)`, date, iface, down, up)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestQuotaStatus(t *testing.T) {
+	m := newTestMonitor(t)
+	setTraffic(t, m, "2026-03-05", "en0", 300, 100)
+	setTraffic(t, m, "2026-03-06", "en1", 200, 0)
+	// Before the cycle
+	setTraffic(t, m, "2026-02-28", "en0", 5000, 5000)
+	now := time.Date(2026, 3, 11, 0, 0, 0, 0, time.Local)
+
+	tests := []struct {
+		name      string
+		quota     Quota
+		used      int64
+		remaining int64
+		projected int64
+		level     QuotaLevel
+	}{
+		{
+			name:      "total of every interface",
+			quota:     Quota{Interface: AllInterfaces, Direction: DirectionTotal, LimitBytes: 1000, ResetDay: 1, WarnPercent: 80},
+			used:      600,
+			remaining: 400,
+			projected: 1860,
+			level:     QuotaOK,
+		},
+		{
+			name:      "warning",
+			quota:     Quota{Interface: "en0", Direction: DirectionTotal, LimitBytes: 500, ResetDay: 1, WarnPercent: 80},
+			used:      400,
+			remaining: 100,
+			projected: 1240,
+			level:     QuotaWarning,
+		},
+		{
+			name:      "exceeded",
+			quota:     Quota{Interface: AllInterfaces, Direction: DirectionDown, LimitBytes: 500, ResetDay: 1, WarnPercent: 80},
+			used:      500,
+			remaining: 0,
+			projected: 1550,
+			level:     QuotaExceeded,
+		},
+		{
+			name:      "upload only",
+			quota:     Quota{Interface: "en0", Direction: DirectionUp, LimitBytes: 1000, ResetDay: 1, WarnPercent: 80},
+			used:      100,
+			remaining: 900,
+			projected: 310,
+			level:     QuotaOK,
+		},
+		{
+			name:      "limit beyond int64",
+			quota:     Quota{Interface: AllInterfaces, Direction: DirectionTotal, LimitBytes: math.MaxUint64, ResetDay: 1, WarnPercent: 80},
+			used:      600,
+			remaining: math.MaxInt64 - 600,
+			projected: 1860,
+			level:     QuotaOK,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status, err := m.quotaStatus(tt.quota, now)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if status.UsedBytes != tt.used || status.RemainingBytes != tt.remaining || status.ProjectedBytes != tt.projected || status.Level != tt.level {
+				t.Errorf("quotaStatus() used %d, remaining %d, projected %d, level %s, want %d, %d, %d, %s",
+					status.UsedBytes, status.RemainingBytes, status.ProjectedBytes, status.Level,
+					tt.used, tt.remaining, tt.projected, tt.level)
+			}
+			if status.UsedPercent < 0 || status.ProjectedPercent < 0 {
+				t.Errorf("quotaStatus() percentages %.1f, %.1f, want them non-negative", status.UsedPercent, status.ProjectedPercent)
+			}
+			if status.CycleStart != "2026-03-01" || status.CycleEnd != "2026-03-31" {
+				t.Errorf("cycle = %s to %s, want 2026-03-01 to 2026-03-31", status.CycleStart, status.CycleEnd)
+			}
+		})
+	}
+}
+
+func TestQuotaEvents(t *testing.T) {
+	m := newTestMonitor(t, Quota{Name: "phone", Interface: AllInterfaces, Direction: DirectionTotal, LimitBytes: 1000, ResetDay: 1, WarnPercent: 80})
+	march := time.Date(2026, 3, 20, 12, 0, 0, 0, time.Local)
+	april := time.Date(2026, 4, 2, 12, 0, 0, 0, time.Local)
+
+	steps := []struct {
+		date string
+		down int64 // traffic of the day so far
+		now  time.Time
+		want QuotaLevel // level of the published event, if any
+	}{
+		{"2026-03-05", 500, march, ""},
+		{"2026-03-05", 850, march, QuotaWarning},
+		{"2026-03-05", 900, march, ""},
+		{"2026-03-05", 1200, march, QuotaExceeded},
+		{"2026-03-05", 1300, march, ""},
+		// Levels only rise within a cycle
+		{"2026-03-05", 850, march, ""},
+		{"2026-03-05", 1200, march, ""},
+		// and start over with the next one
+		{"2026-04-01", 100, april, ""},
+		{"2026-04-01", 1000, april, QuotaExceeded},
+		{"2026-04-01", 1000, april, ""},
+	}
+	for i, step := range steps {
+		setTraffic(t, m, step.date, "en0", step.down, 0)
+		events := m.quotaEvents(step.now)
+		var got QuotaLevel
+		if len(events) > 0 {
+			got = events[0].Level
+		}
+		if len(events) > 1 || got != step.want {
+			t.Fatalf("step %d: events = %+v, want level %q", i, events, step.want)
+		}
+		if len(events) == 1 && (events[0].Status.Name != "phone" || events[0].Timestamp != step.now.Unix()) {
+			t.Errorf("step %d: event = %+v, want the status of phone at %d", i, events[0], step.now.Unix())
+		}
+	}
+}
@@ -0,0 +1,88 @@
+package network
+
+import (
+	"fmt"
+	"time"
+)
+
+// Grouping selects how daily traffic is aggregated in a TrafficRange.
+type Grouping string
+
+const (
+	GroupDay   Grouping = "day"
+	GroupWeek  Grouping = "week"
+	GroupMonth Grouping = "month"
+	GroupYear  Grouping = "year"
+)
+
+// ParseGrouping validates a grouping name. An empty name means GroupDay.
+func ParseGrouping(s string) (Grouping, error) {
+	switch g := Grouping(s); g {
+	case "":
+		return GroupDay, nil
+	case GroupDay, GroupWeek, GroupMonth, GroupYear:
+		return g, nil
+	}
+	return "", fmt.Errorf("unknown grouping '%s'", s)
+}
+
+// TrafficBucket is the traffic of one period of a TrafficRange. Start and
+// End are clipped to the requested range, so the first and last buckets
+// may cover only part of their period.
+type TrafficBucket struct {
+	Period    string `json:"period"`
+	Start     string `json:"start"`
+	End       string `json:"end"`
+	Days      int    `json:"days"`
+	DownBytes int64  `json:"down_bytes"`
+	UpBytes   int64  `json:"up_bytes"`
+}
+
+// TrafficRange is the traffic between two dates, grouped into periods.
+type TrafficRange struct {
+	From      string          `json:"from"`
+	To        string          `json:"to"`
+	Group     Grouping        `json:"group"`
+	Buckets   []TrafficBucket `json:"buckets"`
+	DownBytes int64           `json:"down_bytes"`
+	UpBytes   int64           `json:"up_bytes"`
+}
+
+// groupTraffic aggregates gap-filled daily traffic, oldest first, into
+// periods of the given grouping.
+func groupTraffic(days []DailyTraffic, group Grouping) ([]TrafficBucket, error) {
+	buckets := []TrafficBucket{}
+	for _, day := range days {
+		date, err := time.ParseInLocation("2006-01-02", day.Date, time.Local)
+		if err != nil {
+			return nil, fmt.Errorf("invalid date '%s': %w", day.Date, err)
+		}
+		period := periodOf(date, group)
+		if n := len(buckets); n == 0 || buckets[n-1].Period != period {
+			buckets = append(buckets, TrafficBucket{Period: period, Start: day.Date})
+		}
+		b := &buckets[len(buckets)-1]
+		b.End = day.Date
+		b.Days++
+		b.DownBytes += day.DownBytes
+		b.UpBytes += day.UpBytes
+	}
+	return buckets, nil
+}
+
+// periodOf names the period containing date: "2006-01-02" for days, the ISO
+// week such as "2006-W01" for weeks, "2006-01" for months and "2006" for
+// years.
+func periodOf(date time.Time, group Grouping) string {
+	switch group {
+	case GroupWeek:
+		year, week := date.ISOWeek()
+		return fmt.Sprintf("%04d-W%02d", year, week)
+	case GroupMonth:
+		return date.Format("2006-01")
+	case GroupYear:
+		return date.Format("2006")
+	default:
+		return date.Format("2006-01-02")
+	}
+}
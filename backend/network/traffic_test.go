@@ -0,0 +1,164 @@
+package network
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestPeriodOf(t *testing.T) {
+	tests := []struct {
+		date  string
+		group Grouping
+		want  string
+	}{
+		{"2026-03-15", GroupDay, "2026-03-15"},
+		{"2026-03-15", GroupMonth, "2026-03"},
+		{"2026-03-15", GroupYear, "2026"},
+		{"2026-03-15", GroupWeek, "2026-W11"},
+		// ISO weeks belong to the year of their Thursday
+		{"2026-12-31", GroupWeek, "2026-W53"},
+		{"2027-01-01", GroupWeek, "2026-W53"},
+		{"2027-01-03", GroupWeek, "2026-W53"},
+		{"2027-01-04", GroupWeek, "2027-W01"},
+		{"2024-12-30", GroupWeek, "2025-W01"},
+	}
+	for _, tt := range tests {
+		date, _ := time.ParseInLocation("2006-01-02", tt.date, time.Local)
+		if got := periodOf(date, tt.group); got != tt.want {
+			t.Errorf("periodOf(%s, %s) = %q, want %q", tt.date, tt.group, got, tt.want)
+		}
+	}
+}
+
+func TestGroupTraffic(t *testing.T) {
+	// One byte down and two up on each day
+	traffic := func(from, to string) []DailyTraffic {
+		start, _ := time.ParseInLocation("2006-01-02", from, time.Local)
+		end, _ := time.ParseInLocation("2006-01-02", to, time.Local)
+		var days []DailyTraffic
+		for _, day := range daysBetween(start, end) {
+			days = append(days, DailyTraffic{Date: day.Format("2006-01-02"), DownBytes: 1, UpBytes: 2})
+		}
+		return days
+	}
+	bucket := func(period, start, end string, days int) TrafficBucket {
+		return TrafficBucket{Period: period, Start: start, End: end, Days: days, DownBytes: int64(days), UpBytes: 2 * int64(days)}
+	}
+	tests := []struct {
+		name  string
+		days  []DailyTraffic
+		group Grouping
+		want  []TrafficBucket
+	}{
+		{
+			name:  "empty",
+			group: GroupMonth,
+			want:  []TrafficBucket{},
+		},
+		{
+			name:  "days",
+			days:  traffic("2026-03-30", "2026-04-01"),
+			group: GroupDay,
+			want: []TrafficBucket{
+				bucket("2026-03-30", "2026-03-30", "2026-03-30", 1),
+				bucket("2026-03-31", "2026-03-31", "2026-03-31", 1),
+				bucket("2026-04-01", "2026-04-01", "2026-04-01", 1),
+			},
+		},
+		{
+			name:  "months clipped to the range",
+			days:  traffic("2026-03-15", "2026-05-04"),
+			group: GroupMonth,
+			want: []TrafficBucket{
+				bucket("2026-03", "2026-03-15", "2026-03-31", 17),
+				bucket("2026-04", "2026-04-01", "2026-04-30", 30),
+				bucket("2026-05", "2026-05-01", "2026-05-04", 4),
+			},
+		},
+		{
+			name:  "weeks across the new year",
+			days:  traffic("2026-12-23", "2027-01-05"),
+			group: GroupWeek,
+			want: []TrafficBucket{
+				bucket("2026-W52", "2026-12-23", "2026-12-27", 5),
+				bucket("2026-W53", "2026-12-28", "2027-01-03", 7),
+				bucket("2027-W01", "2027-01-04", "2027-01-05", 2),
+			},
+		},
+		{
+			name:  "years",
+			days:  traffic("2026-12-30", "2027-01-02"),
+			group: GroupYear,
+			want: []TrafficBucket{
+				bucket("2026", "2026-12-30", "2026-12-31", 2),
+				bucket("2027", "2027-01-01", "2027-01-02", 2),
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := groupTraffic(tt.days, tt.group)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("groupTraffic() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+
+	if _, err := groupTraffic([]DailyTraffic{{Date: "2026-02-30"}}, GroupDay); err == nil {
+		t.Error("groupTraffic() with an invalid date succeeded, want an error")
+	}
+}
+
+func TestDaysBetween(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Skipf("time zone database unavailable: %v", err)
+	}
+	tests := []struct {
+		name     string
+		from, to time.Time
+		want     []string
+	}{
+		{
+			name: "spring forward",
+			from: time.Date(2026, 3, 28, 0, 0, 0, 0, berlin),
+			to:   time.Date(2026, 3, 30, 0, 0, 0, 0, berlin),
+			want: []string{"2026-03-28", "2026-03-29", "2026-03-30"},
+		},
+		{
+			name: "fall back",
+			from: time.Date(2026, 10, 24, 23, 0, 0, 0, berlin),
+			to:   time.Date(2026, 10, 26, 12, 0, 0, 0, berlin),
+			want: []string{"2026-10-24", "2026-10-25", "2026-10-26"},
+		},
+		{
+			name: "single day",
+			from: time.Date(2026, 2, 28, 15, 0, 0, 0, berlin),
+			to:   time.Date(2026, 2, 28, 15, 0, 0, 0, berlin),
+			want: []string{"2026-02-28"},
+		},
+		{
+			name: "reversed",
+			from: time.Date(2026, 3, 2, 0, 0, 0, 0, berlin),
+			to:   time.Date(2026, 3, 1, 0, 0, 0, 0, berlin),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for _, day := range daysBetween(tt.from, tt.to) {
+				if day.Hour() != 0 || day.Minute() != 0 {
+					t.Errorf("day %v is not at midnight", day)
+				}
+				got = append(got, day.Format("2006-01-02"))
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("daysBetween() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,141 @@
+//go:build darwin
+
+package platform
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/shirou/gopsutil/v3/process"
+)
+
+// dataVolume holds the user data on APFS. The root volume is the read-only
+// system snapshot, so its usage barely changes.
+const dataVolume = "/System/Volumes/Data"
+
+// vmStatPageSize matches the page size in the header of vm_stat, which
+// reports every other count in pages.
+var vmStatPageSize = regexp.MustCompile(`page size of (\d+) bytes`)
+
+var current Platform = darwin{Run: ExecRunner}
+
+type darwin struct {
+	Run Runner
+}
+
+func (d darwin) OSVersion() string {
+	productVersion, err := d.Run("sw_vers", "-productVersion")
+	if err != nil {
+		return "N/A"
+	}
+	return "macOS " + strings.TrimSpace(string(productVersion))
+}
+
+// ProcessGroup aggregates processes by their parent .app bundle if
+// applicable.
+func (darwin) ProcessGroup(p *process.Process) string {
+	exe, err := p.Exe()
+	if err != nil {
+		// Fallback to name on error
+		return processName(p)
+	}
+
+	// Check if the process is part of a macOS .app bundle
+	idx := strings.Index(exe, ".app/")
+	if idx == -1 {
+		// Not in a bundle, use the process name
+		return processName(p)
+	}
+
+	// Full path to the app bundle, e.g., "/Applications/Google Chrome.app"
+	bundlePath := exe[:idx+4]
+
+	// Get the base name, e.g., "Google Chrome.app"
+	lastSlash := strings.LastIndex(bundlePath, "/")
+	if lastSlash == -1 {
+		// Fallback for unexpected paths
+		return strings.TrimSuffix(bundlePath, ".app")
+	}
+	baseName := bundlePath[lastSlash+1:]
+
+	// Trim the .app suffix to get "Google Chrome"
+	return strings.TrimSuffix(baseName, ".app")
+}
+
+// Identify reports no control group, as there are none.
+func (d darwin) Identify(p *process.Process) (group, cgroup, container string) {
+	return d.ProcessGroup(p), "", ""
+}
+
+func (d darwin) DefaultInterface() string {
+	out, err := d.Run("route", "-n", "get", "default")
+	if err != nil {
+		return ""
+	}
+	scanner := bufio.NewScanner(bytes.NewReader(out))
+	for scanner.Scan() {
+		if iface, ok := strings.CutPrefix(strings.TrimSpace(scanner.Text()), "interface:"); ok {
+			return strings.TrimSpace(iface)
+		}
+	}
+	return ""
+}
+
+func (darwin) PrimaryDisk() string {
+	if _, err := os.Stat(dataVolume); err == nil {
+		return dataVolume
+	}
+	return "/"
+}
+
+// VMStats parses vm_stat, as the compressor and swap counters are not
+// exposed by gopsutil.
+func (d darwin) VMStats() (VMStats, error) {
+	out, err := d.Run("vm_stat")
+	if err != nil {
+		return VMStats{}, fmt.Errorf("failed to run vm_stat: %w", err)
+	}
+	return parseVMStat(out)
+}
+
+func parseVMStat(out []byte) (VMStats, error) {
+	match := vmStatPageSize.FindSubmatch(out)
+	if match == nil {
+		return VMStats{}, errors.New("no page size in vm_stat output")
+	}
+	pageSize, _ := strconv.ParseUint(string(match[1]), 10, 64)
+
+	stats := VMStats{Compression: true}
+	scanner := bufio.NewScanner(bytes.NewReader(out))
+	for scanner.Scan() {
+		name, value, ok := strings.Cut(scanner.Text(), ":")
+		if !ok {
+			continue
+		}
+		pages, err := strconv.ParseUint(strings.TrimSuffix(strings.TrimSpace(value), "."), 10, 64)
+		if err != nil {
+			continue
+		}
+		switch name {
+		case "Pages occupied by compressor":
+			stats.Compressed = pages * pageSize
+		case "Swapins":
+			stats.SwapIn = pages * pageSize
+		case "Swapouts":
+			stats.SwapOut = pages * pageSize
+		}
+	}
+	return stats, nil
+}
+
+// SchedStats is unsupported, as macOS only exposes the counters through the
+// Mach host statistics API.
+func (darwin) SchedStats() (SchedStats, error) {
+	return SchedStats{}, fmt.Errorf("context switch and interrupt counters: %w", errors.ErrUnsupported)
+}
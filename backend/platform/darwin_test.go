@@ -0,0 +1,104 @@
+//go:build darwin
+
+package platform
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+// recorded runs commands by returning their recorded output.
+type recorded map[string]string
+
+func (r recorded) run(name string, args ...string) ([]byte, error) {
+	out, ok := r[name]
+	if !ok {
+		return nil, errors.New(name + ": not found")
+	}
+	return []byte(out), nil
+}
+
+func TestOSVersion(t *testing.T) {
+	if got := (darwin{Run: recorded{"sw_vers": "14.4.1\n"}.run}).OSVersion(); got != "macOS 14.4.1" {
+		t.Errorf("OSVersion() = %q, want macOS 14.4.1", got)
+	}
+	if got := (darwin{Run: recorded{}.run}).OSVersion(); got != "N/A" {
+		t.Errorf("OSVersion() without sw_vers = %q, want N/A", got)
+	}
+}
+
+func TestDefaultInterface(t *testing.T) {
+	route := `   route to: default
+destination: default
+       mask: default
+    gateway: 192.168.1.1
+  interface: en0
+      flags: <UP,GATEWAY,DONE,STATIC,PRCLONING,GLOBAL>
+ recvpipe  sendpipe  ssthresh  rtt,msec    rttvar  hopcount      mtu     expire
+       0         0         0         0         0         0      1500         0
+`
+	tests := []struct {
+		name string
+		run  Runner
+		want string
+	}{
+		{"default route", recorded{"route": route}.run, "en0"},
+		{"no interface", recorded{"route": "   route to: default\ndestination: default\n"}.run, ""},
+		// route fails with "not in table" without a default route
+		{"no default route", recorded{}.run, ""},
+	}
+	for _, tt := range tests {
+		if got := (darwin{Run: tt.run}).DefaultInterface(); got != tt.want {
+			t.Errorf("%s: DefaultInterface() = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestVMStats(t *testing.T) {
+	out := `Mach Virtual Memory Statistics: (page size of 16384 bytes)
+Pages free:                                4017.
+Pages active:                            306853.
+Pages inactive:                          302434.
+Pages speculative:                         1912.
+Pages throttled:                              0.
+Pages wired down:                        156447.
+Pages purgeable:                          12097.
+"Translation faults":                 898437615.
+Pages copy-on-write:                   24180313.
+Pages zero filled:                    396773466.
+Pages reactivated:                      9745234.
+Pages purged:                           2416040.
+File-backed pages:                       161378.
+Anonymous pages:                         449821.
+Pages stored in compressor:              961203.
+Pages occupied by compressor:            242690.
+Decompressions:                         8571220.
+Compressions:                          13203442.
+Pageins:                                8937741.
+Pageouts:                                 72619.
+Swapins:                                 118812.
+Swapouts:                                186530.
+`
+	got, err := darwin{Run: recorded{"vm_stat": out}.run}.VMStats()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := VMStats{
+		Compressed:  242690 * 16384,
+		Compression: true,
+		SwapIn:      118812 * 16384,
+		SwapOut:     186530 * 16384,
+	}
+	if got != want {
+		t.Errorf("VMStats() = %+v, want %+v", got, want)
+	}
+
+	if _, err := (darwin{Run: recorded{}.run}).VMStats(); err == nil || !strings.Contains(err.Error(), "vm_stat") {
+		t.Errorf("VMStats() without vm_stat = %v, want an error naming it", err)
+	}
+
+	if _, err := parseVMStat([]byte("Pages free: 4017.\n")); err == nil {
+		t.Error("parseVMStat() without a page size succeeded, want an error")
+	}
+}
@@ -0,0 +1,294 @@
+//go:build linux
+
+package platform
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/shirou/gopsutil/v3/host"
+	"github.com/shirou/gopsutil/v3/process"
+)
+
+// osReleaseFiles are read in order for the distribution name.
+var osReleaseFiles = []string{"/etc/os-release", "/usr/lib/os-release"}
+
+// containerPrefixes map the cgroup name prefixes of container scopes, as
+// created with the systemd cgroup driver, to their runtime.
+var containerPrefixes = []struct{ prefix, runtime string }{
+	{"docker-", "docker"},
+	{"libpod-", "podman"},
+	{"cri-containerd-", "containerd"},
+	{"crio-", "cri-o"},
+}
+
+// shortIDLength is the length container IDs are shortened to, as in the
+// docker and podman CLIs.
+const shortIDLength = 12
+
+var current Platform = linux{}
+
+type linux struct{}
+
+// OSVersion reports the PRETTY_NAME of os-release, falling back to NAME
+// and VERSION_ID, then to what gopsutil detects.
+func (linux) OSVersion() string {
+	for _, path := range osReleaseFiles {
+		fields, err := readOSRelease(path)
+		if err != nil {
+			continue
+		}
+		if name := fields["PRETTY_NAME"]; name != "" {
+			return name
+		}
+		if name := strings.TrimSpace(fields["NAME"] + " " + fields["VERSION_ID"]); name != "" {
+			return name
+		}
+	}
+	info, err := host.Info()
+	if err != nil || info.Platform == "" {
+		return "Linux"
+	}
+	return strings.TrimSpace(info.Platform + " " + info.PlatformVersion)
+}
+
+// ProcessGroup groups processes by the container or systemd service they
+// run in, or else by their cgroup. Processes of login sessions and kernel
+// threads are grouped by name.
+func (l linux) ProcessGroup(p *process.Process) string {
+	group, _, _ := l.Identify(p)
+	return group
+}
+
+// Identify derives the group, cgroup and container of a process from a
+// single read of its cgroup.
+func (linux) Identify(p *process.Process) (group, cgroup, container string) {
+	cgroup, err := cgroupPath(p.Pid)
+	if err != nil {
+		return processName(p), "", ""
+	}
+	container = containerOf(cgroup)
+	switch {
+	case container != "":
+		group = container
+	case cgroupGroup(cgroup) != "":
+		group = cgroupGroup(cgroup)
+	default:
+		group = processName(p)
+	}
+	return group, cgroup, container
+}
+
+// DefaultInterface reads the default route with the lowest metric from
+// /proc/net/route.
+func (linux) DefaultInterface() string {
+	f, err := os.Open("/proc/net/route")
+	if err != nil {
+		return ""
+	}
+	defer f.Close()
+
+	iface, bestMetric := "", -1
+	scanner := bufio.NewScanner(f)
+	scanner.Scan() // header
+	for scanner.Scan() {
+		// Iface Destination Gateway Flags RefCnt Use Metric Mask ...
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 8 || fields[1] != "00000000" || fields[7] != "00000000" {
+			continue
+		}
+		metric, err := strconv.Atoi(fields[6])
+		if err != nil {
+			continue
+		}
+		if bestMetric < 0 || metric < bestMetric {
+			iface, bestMetric = fields[0], metric
+		}
+	}
+	return iface
+}
+
+func (linux) PrimaryDisk() string {
+	return "/"
+}
+
+// VMStats reads the swap counters from /proc/vmstat and the size of the
+// zswap pool, where enabled, from /proc/meminfo.
+func (linux) VMStats() (VMStats, error) {
+	vmstat, err := readKeyValues("/proc/vmstat", " ")
+	if err != nil {
+		return VMStats{}, fmt.Errorf("failed to read swap counters: %w", err)
+	}
+	pageSize := uint64(os.Getpagesize())
+	stats := VMStats{SwapIn: vmstat["pswpin"] * pageSize, SwapOut: vmstat["pswpout"] * pageSize}
+
+	if meminfo, err := readKeyValues("/proc/meminfo", ":"); err == nil {
+		if kb, ok := meminfo["Zswap"]; ok {
+			stats.Compressed, stats.Compression = kb*1024, true
+		}
+	}
+	return stats, nil
+}
+
+// SchedStats reads the ctxt and intr lines of /proc/stat. The first value
+// of intr is the total of every interrupt.
+func (linux) SchedStats() (SchedStats, error) {
+	stat, err := readKeyValues("/proc/stat", " ")
+	if err != nil {
+		return SchedStats{}, fmt.Errorf("failed to read scheduler counters: %w", err)
+	}
+	return SchedStats{ContextSwitches: stat["ctxt"], Interrupts: stat["intr"]}, nil
+}
+
+// readKeyValues parses the lines of a /proc file of numeric values, such
+// as "pswpin 42" or "MemFree:  1024 kB", ignoring units.
+func readKeyValues(path, sep string) (map[string]uint64, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	values := make(map[string]uint64)
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		key, rest, ok := strings.Cut(scanner.Text(), sep)
+		if !ok {
+			continue
+		}
+		fields := strings.Fields(rest)
+		if len(fields) == 0 {
+			continue
+		}
+		if value, err := strconv.ParseUint(fields[0], 10, 64); err == nil {
+			values[key] = value
+		}
+	}
+	return values, scanner.Err()
+}
+
+// readOSRelease parses the KEY=value lines of an os-release file.
+func readOSRelease(path string) (map[string]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	fields := make(map[string]string)
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		key, value, ok := strings.Cut(strings.TrimSpace(scanner.Text()), "=")
+		if !ok || strings.HasPrefix(key, "#") {
+			continue
+		}
+		if unquoted, err := strconv.Unquote(value); err == nil {
+			value = unquoted
+		} else {
+			value = strings.Trim(value, `"'`)
+		}
+		fields[key] = value
+	}
+	return fields, scanner.Err()
+}
+
+// cgroupPath returns the cgroup of a process, preferring the unified
+// (v2) hierarchy and then the systemd one of cgroup v1.
+func cgroupPath(pid int32) (string, error) {
+	data, err := os.ReadFile(fmt.Sprintf("/proc/%d/cgroup", pid))
+	if err != nil {
+		return "", err
+	}
+	path := parseCgroup(string(data))
+	if path == "" {
+		return "", fmt.Errorf("no cgroup found for process %d", pid)
+	}
+	return path, nil
+}
+
+// parseCgroup returns the cgroup path of the contents of a
+// /proc/<pid>/cgroup file, or "" if it lists no hierarchy that is used.
+func parseCgroup(data string) string {
+	var path string
+	for _, line := range strings.Split(strings.TrimSpace(data), "\n") {
+		// hierarchy-ID:controller-list:cgroup-path
+		parts := strings.SplitN(line, ":", 3)
+		if len(parts) != 3 {
+			continue
+		}
+		switch {
+		case parts[0] == "0" && parts[1] == "":
+			return parts[2]
+		case parts[1] == "name=systemd":
+			path = parts[2]
+		}
+	}
+	return path
+}
+
+// cgroupGroup returns the group name of a cgroup path: the innermost
+// systemd service, such as "nginx" for
+// "/system.slice/nginx.service", or else the innermost cgroup that is not
+// a slice or a login session. It returns "" if there is neither.
+func cgroupGroup(path string) string {
+	segments := strings.Split(strings.Trim(path, "/"), "/")
+	for i := len(segments) - 1; i >= 0; i-- {
+		seg := segments[i]
+		// user@1000.service is the service manager of a user, whose own
+		// services are below it.
+		if unit, ok := strings.CutSuffix(seg, ".service"); ok && !strings.HasPrefix(unit, "user@") {
+			return unit
+		}
+	}
+	last := segments[len(segments)-1]
+	switch {
+	case last == "", last == "init.scope", strings.HasSuffix(last, ".slice"),
+		strings.HasPrefix(last, "session-") && strings.HasSuffix(last, ".scope"):
+		return ""
+	}
+	return strings.TrimSuffix(last, ".scope")
+}
+
+// containerOf returns the runtime and short ID of the container a cgroup
+// path belongs to, such as "docker:4f1c2b3a9d8e", or "" outside containers.
+// It recognises the systemd driver's scopes, e.g.
+// "/system.slice/docker-<id>.scope", and the cgroupfs driver's
+// directories, e.g. "/docker/<id>" or "/kubepods/burstable/pod<uid>/<id>".
+func containerOf(path string) string {
+	segments := strings.Split(strings.Trim(path, "/"), "/")
+	for i := len(segments) - 1; i >= 0; i-- {
+		seg := strings.TrimSuffix(segments[i], ".scope")
+		for _, c := range containerPrefixes {
+			if id, ok := strings.CutPrefix(seg, c.prefix); ok && isContainerID(id) {
+				return c.runtime + ":" + id[:shortIDLength]
+			}
+		}
+		if isContainerID(seg) {
+			runtime := "container"
+			switch root := segments[0]; {
+			case root == "docker":
+				runtime = "docker"
+			case strings.HasPrefix(root, "kubepods"):
+				runtime = "kubernetes"
+			}
+			return runtime + ":" + seg[:shortIDLength]
+		}
+	}
+	return ""
+}
+
+// isContainerID reports whether s is a full 64 hex digit container ID.
+func isContainerID(s string) bool {
+	if len(s) != 64 {
+		return false
+	}
+	for _, r := range s {
+		if (r < '0' || r > '9') && (r < 'a' || r > 'f') {
+			return false
+		}
+	}
+	return true
+}
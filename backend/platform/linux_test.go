@@ -0,0 +1,186 @@
+//go:build linux
+
+package platform
+
+import (
+	"maps"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// Container IDs are 64 hex digits and shown by their first 12.
+const (
+	id1 = "ca978112ca1bbdcafac231b39a23dc4da786eff8147c4e72b9807785afee48bb"
+	id2 = "3e23e8160039594a33894f6564e1b1348bbd7a0088d42c4acb73eeaed59c009d"
+)
+
+func TestCgroup(t *testing.T) {
+	tests := []struct {
+		name      string
+		cgroup    string // contents of /proc/<pid>/cgroup
+		path      string
+		group     string
+		container string
+	}{
+		{
+			name:   "systemd service",
+			cgroup: "0::/system.slice/nginx.service\n",
+			path:   "/system.slice/nginx.service",
+			group:  "nginx",
+		},
+		{
+			name:   "user service",
+			cgroup: "0::/user.slice/user-1000.slice/user@1000.service/app.slice/pipewire.service\n",
+			path:   "/user.slice/user-1000.slice/user@1000.service/app.slice/pipewire.service",
+			group:  "pipewire",
+		},
+		{
+			name:   "user service manager",
+			cgroup: "0::/user.slice/user-1000.slice/user@1000.service/init.scope\n",
+			path:   "/user.slice/user-1000.slice/user@1000.service/init.scope",
+		},
+		{
+			name:   "login session",
+			cgroup: "0::/user.slice/user-1000.slice/session-3.scope\n",
+			path:   "/user.slice/user-1000.slice/session-3.scope",
+		},
+		{
+			name:   "transient scope",
+			cgroup: "0::/user.slice/user-1000.slice/user@1000.service/app.slice/app-gnome-firefox-4242.scope\n",
+			path:   "/user.slice/user-1000.slice/user@1000.service/app.slice/app-gnome-firefox-4242.scope",
+			group:  "app-gnome-firefox-4242",
+		},
+		{
+			name:   "kernel thread",
+			cgroup: "0::/\n",
+			path:   "/",
+		},
+		{
+			name: "cgroup v1",
+			cgroup: "12:pids:/system.slice/ssh.service\n" +
+				"4:memory:/system.slice/ssh.service\n" +
+				"2:cpu,cpuacct:/system.slice/ssh.service\n" +
+				"1:name=systemd:/system.slice/ssh.service\n",
+			path:  "/system.slice/ssh.service",
+			group: "ssh",
+		},
+		{
+			name: "hybrid prefers the unified hierarchy",
+			cgroup: "4:memory:/\n" +
+				"1:name=systemd:/system.slice/cron.service\n" +
+				"0::/system.slice/cron.service\n",
+			path:  "/system.slice/cron.service",
+			group: "cron",
+		},
+		{
+			name:      "docker with the systemd driver",
+			cgroup:    "0::/system.slice/docker-" + id1 + ".scope\n",
+			path:      "/system.slice/docker-" + id1 + ".scope",
+			group:     "docker:ca978112ca1b",
+			container: "docker:ca978112ca1b",
+		},
+		{
+			name: "docker with the cgroupfs driver on cgroup v1",
+			cgroup: "12:pids:/docker/" + id1 + "\n" +
+				"3:cpu,cpuacct:/docker/" + id1 + "\n" +
+				"1:name=systemd:/docker/" + id1 + "\n",
+			path:      "/docker/" + id1,
+			group:     "docker:ca978112ca1b",
+			container: "docker:ca978112ca1b",
+		},
+		{
+			name:      "rootless podman",
+			cgroup:    "0::/user.slice/user-1000.slice/user@1000.service/user.slice/libpod-" + id2 + ".scope/container\n",
+			path:      "/user.slice/user-1000.slice/user@1000.service/user.slice/libpod-" + id2 + ".scope/container",
+			group:     "podman:3e23e8160039",
+			container: "podman:3e23e8160039",
+		},
+		{
+			name:      "containerd in kubernetes",
+			cgroup:    "0::/kubepods.slice/kubepods-burstable.slice/kubepods-burstable-pod7e1f5a2c_9b4d_4c1e_8f3a_2d6b0e9c4a71.slice/cri-containerd-" + id1 + ".scope\n",
+			path:      "/kubepods.slice/kubepods-burstable.slice/kubepods-burstable-pod7e1f5a2c_9b4d_4c1e_8f3a_2d6b0e9c4a71.slice/cri-containerd-" + id1 + ".scope",
+			group:     "containerd:ca978112ca1b",
+			container: "containerd:ca978112ca1b",
+		},
+		{
+			name:      "cri-o in kubernetes",
+			cgroup:    "0::/kubepods.slice/kubepods-besteffort.slice/kubepods-besteffort-pod0c9a8f1e_3d2b_4a6c_9e7f_1b5d8c2a4e60.slice/crio-" + id2 + ".scope\n",
+			path:      "/kubepods.slice/kubepods-besteffort.slice/kubepods-besteffort-pod0c9a8f1e_3d2b_4a6c_9e7f_1b5d8c2a4e60.slice/crio-" + id2 + ".scope",
+			group:     "cri-o:3e23e8160039",
+			container: "cri-o:3e23e8160039",
+		},
+		{
+			name: "kubepods with the cgroupfs driver on cgroup v1",
+			cgroup: "11:memory:/kubepods/burstable/pod7e1f5a2c-9b4d-4c1e-8f3a-2d6b0e9c4a71/" + id1 + "\n" +
+				"1:name=systemd:/kubepods/burstable/pod7e1f5a2c-9b4d-4c1e-8f3a-2d6b0e9c4a71/" + id1 + "\n",
+			path:      "/kubepods/burstable/pod7e1f5a2c-9b4d-4c1e-8f3a-2d6b0e9c4a71/" + id1,
+			group:     "kubernetes:ca978112ca1b",
+			container: "kubernetes:ca978112ca1b",
+		},
+		{
+			name:      "containerd with the cgroupfs driver",
+			cgroup:    "0::/default/" + id2 + "\n",
+			path:      "/default/" + id2,
+			group:     "container:3e23e8160039",
+			container: "container:3e23e8160039",
+		},
+		{
+			name:   "no hierarchy in use",
+			cgroup: "4:memory:/\n3:cpu,cpuacct:/\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := parseCgroup(tt.cgroup)
+			if path != tt.path {
+				t.Fatalf("parseCgroup() = %q, want %q", path, tt.path)
+			}
+			container := containerOf(path)
+			if container != tt.container {
+				t.Errorf("containerOf(%q) = %q, want %q", path, container, tt.container)
+			}
+			group := container
+			if group == "" {
+				group = cgroupGroup(path)
+			}
+			if group != tt.group {
+				t.Errorf("group of %q = %q, want %q", path, group, tt.group)
+			}
+		})
+	}
+}
+
+func TestReadOSRelease(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "os-release")
+	data := `PRETTY_NAME="Ubuntu 22.04.4 LTS"
+NAME="Ubuntu"
+VERSION_ID="22.04"
+VERSION="22.04.4 LTS (Jammy Jellyfish)"
+ID=ubuntu
+# A comment
+HOME_URL="https://www.ubuntu.com/"
+VARIANT='Server Edition'
+UBUNTU_CODENAME=jammy
+`
+	if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	got, err := readOSRelease(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{
+		"PRETTY_NAME":     "Ubuntu 22.04.4 LTS",
+		"NAME":            "Ubuntu",
+		"VERSION_ID":      "22.04",
+		"VERSION":         "22.04.4 LTS (Jammy Jellyfish)",
+		"ID":              "ubuntu",
+		"HOME_URL":        "https://www.ubuntu.com/",
+		"VARIANT":         "Server Edition",
+		"UBUNTU_CODENAME": "jammy",
+	}
+	if !maps.Equal(got, want) {
+		t.Errorf("readOSRelease() = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,56 @@
+//go:build !darwin && !linux
+
+package platform
+
+import (
+	"errors"
+	"fmt"
+	"runtime"
+	"strings"
+
+	"github.com/shirou/gopsutil/v3/host"
+	"github.com/shirou/gopsutil/v3/mem"
+	"github.com/shirou/gopsutil/v3/process"
+)
+
+var current Platform = other{}
+
+// other is the fallback for operating systems without specific support.
+type other struct{}
+
+func (other) OSVersion() string {
+	info, err := host.Info()
+	if err != nil || info.Platform == "" {
+		return runtime.GOOS
+	}
+	return strings.TrimSpace(info.Platform + " " + info.PlatformVersion)
+}
+
+func (other) ProcessGroup(p *process.Process) string {
+	return processName(p)
+}
+
+// Identify reports no control group, as there are none.
+func (o other) Identify(p *process.Process) (group, cgroup, container string) {
+	return o.ProcessGroup(p), "", ""
+}
+
+func (other) DefaultInterface() string {
+	return ""
+}
+
+func (other) PrimaryDisk() string {
+	return "/"
+}
+
+func (other) VMStats() (VMStats, error) {
+	swap, err := mem.SwapMemory()
+	if err != nil {
+		return VMStats{}, fmt.Errorf("failed to read swap counters: %w", err)
+	}
+	return VMStats{SwapIn: swap.Sin, SwapOut: swap.Sout}, nil
+}
+
+func (other) SchedStats() (SchedStats, error) {
+	return SchedStats{}, fmt.Errorf("context switch and interrupt counters: %w", errors.ErrUnsupported)
+}
@@ -0,0 +1,80 @@
+// Package platform hides the differences between the operating systems the
+// monitor runs on. The implementation is selected by build tags.
+package platform
+
+import (
+	"os/exec"
+
+	"github.com/shirou/gopsutil/v3/process"
+)
+
+// Platform describes the host operating system.
+type Platform interface {
+	// OSVersion returns the name and version of the operating system, such
+	// as "macOS 14.4" or "Ubuntu 22.04.4 LTS".
+	OSVersion() string
+
+	// ProcessGroup returns the name a process is aggregated under, such as
+	// its application bundle or systemd unit. It falls back to the process
+	// name.
+	ProcessGroup(p *process.Process) string
+
+	// Identify returns the group of a process, as ProcessGroup does,
+	// together with its control group and the container it runs in, such
+	// as "docker:4f1c2b3a9d8e". The control group and container are ""
+	// where unknown or unsupported.
+	Identify(p *process.Process) (group, cgroup, container string)
+
+	// DefaultInterface returns the network interface of the default route,
+	// or "" if there is none.
+	DefaultInterface() string
+
+	// PrimaryDisk returns the mount point whose usage is reported as the
+	// disk usage of the system.
+	PrimaryDisk() string
+
+	// VMStats returns the memory compression and swap counters.
+	VMStats() (VMStats, error)
+
+	// SchedStats returns the scheduler counters, or an error wrapping
+	// errors.ErrUnsupported where they cannot be read.
+	SchedStats() (SchedStats, error)
+}
+
+// VMStats are virtual memory counters gopsutil does not report on every
+// platform.
+type VMStats struct {
+	// Compressed is the memory held compressed, in bytes, if Compression
+	// is set: the macOS compressor or zswap on Linux.
+	Compressed  uint64
+	Compression bool
+	// SwapIn and SwapOut are the bytes swapped in and out since boot.
+	SwapIn  uint64
+	SwapOut uint64
+}
+
+// Current returns the platform the binary was built for.
+func Current() Platform {
+	return current
+}
+
+// processName returns the name of p, or "" if it cannot be read.
+func processName(p *process.Process) string {
+	name, _ := p.Name()
+	return name
+}
+
+// SchedStats are the scheduler counters since boot.
+type SchedStats struct {
+	ContextSwitches uint64
+	Interrupts      uint64
+}
+
+// Runner runs a command and returns its standard output. Readers of
+// command output take one, so tests can hand them recorded output instead.
+type Runner func(name string, args ...string) ([]byte, error)
+
+// ExecRunner runs commands with os/exec.
+func ExecRunner(name string, args ...string) ([]byte, error) {
+	return exec.Command(name, args...).Output()
+}
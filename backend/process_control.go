@@ -0,0 +1,156 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"mime"
+	"net/http"
+	"strconv"
+	"syscall"
+
+	"macos-monitor/backend-go/auth"
+	"macos-monitor/backend-go/procs"
+)
+
+const (
+	defaultAuditLimit = 100
+	maxAuditLimit     = 1000
+)
+
+// controlRequest is the body of a signal or renice request.
+type controlRequest struct {
+	Signal   string `json:"signal"`
+	Priority *int   `json:"priority"`
+}
+
+// parseSignal reads the action of a signal request, TERM by default.
+func parseSignal(req controlRequest) (procs.Action, error) {
+	if req.Signal == "" {
+		req.Signal = "TERM"
+	}
+	return procs.SignalAction(req.Signal)
+}
+
+// parseRenice reads the action of a renice request.
+func parseRenice(req controlRequest) (procs.Action, error) {
+	if req.Priority == nil {
+		return procs.Action{}, errors.New("'priority' is required")
+	}
+	return procs.ReniceAction(*req.Priority)
+}
+
+// processControlHandler performs the action parsed from the request body on
+// the process in the "pid" path value.
+func processControlHandler(ctl *procs.Controller, parse func(controlRequest) (procs.Action, error)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		pid, err := strconv.ParseInt(r.PathValue("pid"), 10, 32)
+		if err != nil || pid <= 0 {
+			http.Error(w, "Invalid process ID", http.StatusBadRequest)
+			return
+		}
+		action, ok := controlAction(w, r, parse)
+		if !ok {
+			return
+		}
+
+		entry, err := ctl.Apply(int32(pid), action, actor(r))
+		if err != nil {
+			writeControlError(w, err)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(entry)
+	}
+}
+
+// processGroupControlHandler performs the action parsed from the request
+// body on every process of the group in the "name" path value. Protected
+// members are skipped and reported with an error in their entry.
+func processGroupControlHandler(ctl *procs.Controller, parse func(controlRequest) (procs.Action, error)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		action, ok := controlAction(w, r, parse)
+		if !ok {
+			return
+		}
+
+		entries, err := ctl.ApplyGroup(r.PathValue("name"), action, actor(r))
+		if err != nil {
+			writeControlError(w, err)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(entries)
+	}
+}
+
+func processAuditHandler(ctl *procs.Controller) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		limit := defaultAuditLimit
+		if v := r.URL.Query().Get("limit"); v != "" {
+			n, err := strconv.Atoi(v)
+			if err != nil || n < 1 || n > maxAuditLimit {
+				http.Error(w, fmt.Sprintf("'limit' must be between 1 and %d", maxAuditLimit), http.StatusBadRequest)
+				return
+			}
+			limit = n
+		}
+
+		entries, err := ctl.Audit(limit)
+		if err != nil {
+			http.Error(w, "Could not retrieve process audit log", http.StatusInternalServerError)
+			log.Printf("Error getting process audit log: %v", err)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(entries)
+	}
+}
+
+// actor identifies the sender of r in the audit log.
+func actor(r *http.Request) string {
+	return fmt.Sprintf("%s (%s)", auth.FromContext(r.Context()).Name, r.RemoteAddr)
+}
+
+// controlAction decodes the JSON request body into an action. It writes a
+// 415 for other content types, which browsers send from any page without
+// a preflight, a 400 if the body is invalid or a 501 if the action is not
+// supported on this platform, and reports false.
+func controlAction(w http.ResponseWriter, r *http.Request, parse func(controlRequest) (procs.Action, error)) (procs.Action, bool) {
+	if mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type")); err != nil || mediaType != "application/json" {
+		http.Error(w, "Content-Type must be application/json", http.StatusUnsupportedMediaType)
+		return procs.Action{}, false
+	}
+	var req controlRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body: "+err.Error(), http.StatusBadRequest)
+		return procs.Action{}, false
+	}
+	action, err := parse(req)
+	if errors.Is(err, errors.ErrUnsupported) {
+		writeControlError(w, err)
+		return procs.Action{}, false
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return procs.Action{}, false
+	}
+	return action, true
+}
+
+func writeControlError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, procs.ErrNotFound), errors.Is(err, syscall.ESRCH):
+		http.Error(w, "Process not found", http.StatusNotFound)
+	case errors.Is(err, procs.ErrDenied):
+		http.Error(w, err.Error(), http.StatusForbidden)
+	case errors.Is(err, syscall.EPERM), errors.Is(err, syscall.EACCES):
+		http.Error(w, "Operation not permitted for the monitor's user", http.StatusForbidden)
+	case errors.Is(err, errors.ErrUnsupported):
+		http.Error(w, "Process control is not supported on this platform", http.StatusNotImplemented)
+	default:
+		http.Error(w, "Could not control process", http.StatusInternalServerError)
+		log.Printf("Error controlling process: %v", err)
+	}
+}
@@ -0,0 +1,182 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+
+	"macos-monitor/backend-go/auth"
+	"macos-monitor/backend-go/procs"
+)
+
+// processListResponse is a page of processes or process groups.
+type processListResponse struct {
+	Total     int           `json:"total"`
+	Offset    int           `json:"offset"`
+	Limit     int           `json:"limit"`
+	Sort      procs.SortKey `json:"sort"`
+	Order     string        `json:"order"`
+	Grouped   bool          `json:"grouped"`
+	GroupBy   procs.GroupBy `json:"group_by"`
+	Processes interface{}   `json:"processes"`
+}
+
+// processesHandler lists the processes of the latest sample, or their
+// groups with "group_by" (app, cgroup, user, container or none) or
+// "grouped=true", which groups by app. It accepts "sort" (cpu, mem, name,
+// pid, threads, io), "order" (asc, desc), "limit", "offset", and the "name"
+// and "user" filters.
+func processesHandler(pc *procs.Collector) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+
+		key, err := procs.ParseSortKey(q.Get("sort"))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		desc := key.Descending()
+		switch q.Get("order") {
+		case "":
+		case "asc":
+			desc = false
+		case "desc":
+			desc = true
+		default:
+			http.Error(w, "Invalid 'order', expected asc or desc", http.StatusBadRequest)
+			return
+		}
+		grouped := false
+		if v := q.Get("grouped"); v != "" {
+			if grouped, err = strconv.ParseBool(v); err != nil {
+				http.Error(w, "Invalid 'grouped', expected true or false", http.StatusBadRequest)
+				return
+			}
+		}
+		groupBy := procs.GroupByNone
+		if grouped {
+			groupBy = procs.GroupByApp
+		}
+		if v := q.Get("group_by"); v != "" {
+			if groupBy, err = procs.ParseGroupBy(v); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+		}
+		limit, ok := intParam(w, q.Get("limit"), "limit")
+		if !ok {
+			return
+		}
+		offset, ok := intParam(w, q.Get("offset"), "offset")
+		if !ok {
+			return
+		}
+
+		filter := procs.Filter{Name: q.Get("name"), User: q.Get("user")}
+		samples := filter.Apply(pc.Samples())
+
+		resp := processListResponse{
+			Offset:  offset,
+			Limit:   limit,
+			Sort:    key,
+			Order:   "asc",
+			Grouped: groupBy != procs.GroupByNone,
+			GroupBy: groupBy,
+		}
+		if desc {
+			resp.Order = "desc"
+		}
+		if resp.Grouped {
+			groups := procs.GroupSamples(samples, groupBy)
+			procs.SortGroups(groups, key, desc)
+			resp.Total = len(groups)
+			resp.Processes = page(groups, offset, limit)
+		} else {
+			procs.SortSamples(samples, key, desc)
+			resp.Total = len(samples)
+			resp.Processes = page(samples, offset, limit)
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(resp)
+	}
+}
+
+// processDetailHandler serves the detail of the process in the "pid" path
+// value. The environment is only included with "env=true".
+func processDetailHandler(pc *procs.Collector) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		pid, err := strconv.ParseInt(r.PathValue("pid"), 10, 32)
+		if err != nil || pid <= 0 {
+			http.Error(w, "Invalid process ID", http.StatusBadRequest)
+			return
+		}
+		withEnv := false
+		if v := r.URL.Query().Get("env"); v != "" {
+			if withEnv, err = strconv.ParseBool(v); err != nil {
+				http.Error(w, "Invalid 'env', expected true or false", http.StatusBadRequest)
+				return
+			}
+		}
+		// Environments often hold secrets
+		if withEnv && !auth.FromContext(r.Context()).Allows(auth.ScopeAdmin) {
+			http.Error(w, "Forbidden: the environment requires the 'admin' scope", http.StatusForbidden)
+			return
+		}
+
+		detail, err := pc.Detail(int32(pid), withEnv)
+		if errors.Is(err, procs.ErrNotFound) {
+			http.Error(w, "Process not found", http.StatusNotFound)
+			return
+		}
+		if err != nil {
+			http.Error(w, "Could not retrieve process detail", http.StatusInternalServerError)
+			log.Printf("Error getting process detail: %v", err)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(detail)
+	}
+}
+
+// processEventsHandler serves the recent process starts and exits.
+func processEventsHandler(pc *procs.Collector) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		events, started, exited := pc.Events()
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"started": started,
+			"exited":  exited,
+			"events":  events,
+		})
+	}
+}
+
+// intParam parses an optional non-negative integer query parameter,
+// defaulting to 0. It writes a 400 and reports false if it is invalid.
+func intParam(w http.ResponseWriter, value, name string) (int, bool) {
+	if value == "" {
+		return 0, true
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil || n < 0 {
+		http.Error(w, "Invalid '"+name+"', expected a non-negative integer", http.StatusBadRequest)
+		return 0, false
+	}
+	return n, true
+}
+
+// page returns up to limit items starting at offset; a zero limit returns
+// every remaining item.
+func page[T any](items []T, offset, limit int) []T {
+	if offset >= len(items) {
+		return []T{}
+	}
+	items = items[offset:]
+	if limit > 0 && limit < len(items) {
+		items = items[:limit]
+	}
+	return items
+}
@@ -0,0 +1,81 @@
+package procs
+
+import (
+	"database/sql"
+	"fmt"
+)
+
+const auditTableStmt = `
+	CREATE TABLE IF NOT EXISTS process_audit (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		timestamp INTEGER NOT NULL,
+		actor TEXT NOT NULL,
+		action TEXT NOT NULL,
+		argument TEXT NOT NULL,
+		pid INTEGER NOT NULL,
+		name TEXT NOT NULL,
+		grp TEXT NOT NULL,
+		error TEXT NOT NULL DEFAULT ''
+	);`
+
+// AuditEntry is a recorded control action on one process. Error is empty if
+// the action succeeded.
+type AuditEntry struct {
+	ID        int64  `json:"id,omitempty"`
+	Timestamp int64  `json:"timestamp"`
+	Actor     string `json:"actor"`
+	Action    string `json:"action"`
+	Argument  string `json:"argument"`
+	Pid       int32  `json:"pid"`
+	Name      string `json:"name"`
+	Group     string `json:"group"`
+	Error     string `json:"error,omitempty"`
+}
+
+// AuditStore persists the control actions taken on processes.
+type AuditStore struct {
+	db *sql.DB
+}
+
+// NewAuditStore creates the process audit table on db if needed.
+func NewAuditStore(db *sql.DB) (*AuditStore, error) {
+	if _, err := db.Exec(auditTableStmt); err != nil {
+		return nil, fmt.Errorf("failed to create process audit table: %w", err)
+	}
+	return &AuditStore{db: db}, nil
+}
+
+// Record appends an action to the audit log.
+func (s *AuditStore) Record(e AuditEntry) error {
+	_, err := s.db.Exec(`
+		INSERT INTO process_audit (timestamp, actor, action, argument, pid, name, grp, error)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+		e.Timestamp, e.Actor, e.Action, e.Argument, e.Pid, e.Name, e.Group, e.Error)
+	if err != nil {
+		return fmt.Errorf("failed to record process audit entry: %w", err)
+	}
+	return nil
+}
+
+// History returns up to limit actions, most recent first.
+func (s *AuditStore) History(limit int) ([]AuditEntry, error) {
+	rows, err := s.db.Query(`
+		SELECT id, timestamp, actor, action, argument, pid, name, grp, error
+		FROM process_audit
+		ORDER BY id DESC
+		LIMIT ?`, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query process audit log: %w", err)
+	}
+	defer rows.Close()
+
+	entries := []AuditEntry{}
+	for rows.Next() {
+		var e AuditEntry
+		if err := rows.Scan(&e.ID, &e.Timestamp, &e.Actor, &e.Action, &e.Argument, &e.Pid, &e.Name, &e.Group, &e.Error); err != nil {
+			return nil, fmt.Errorf("failed to scan process audit entry: %w", err)
+		}
+		entries = append(entries, e)
+	}
+	return entries, rows.Err()
+}
@@ -0,0 +1,361 @@
+// Package procs samples processes in the background. It keeps process
+// handles across ticks so CPU usage is measured over each interval rather
+// than averaged over a process's lifetime, and serves cached snapshots.
+package procs
+
+import (
+	"context"
+	"log"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/shirou/gopsutil/v3/process"
+)
+
+// maxEvents is the number of process start and exit events kept.
+const maxEvents = 200
+
+// Sample is the state of a single process at the last tick.
+type Sample struct {
+	Pid      int32  `json:"pid"`
+	PPid     int32  `json:"ppid"`
+	Name     string `json:"name"`
+	Group    string `json:"group"`
+	Username string `json:"user"`
+	Cmdline  string `json:"cmdline"`
+	Status   string `json:"status"`
+	// Cgroup is the control group and Container the container the process
+	// runs in, where the platform has them.
+	Cgroup    string `json:"cgroup,omitempty"`
+	Container string `json:"container,omitempty"`
+	// CPUPercent is the usage over the last interval, in percent of one
+	// core.
+	CPUPercent float64 `json:"cpu_percent"`
+	MemoryRss  uint64  `json:"memory_rss"`
+	NumThreads int32   `json:"threads"`
+	// IO rates over the last interval, zero where the platform does not
+	// report per-process I/O.
+	IOReadBPS  float64 `json:"io_read_bps"`
+	IOWriteBPS float64 `json:"io_write_bps"`
+	// CreateTime is the process start time in unix milliseconds.
+	CreateTime int64 `json:"create_time"`
+}
+
+// Group is the aggregate of the processes sharing a group.
+type Group struct {
+	Name string `json:"name"`
+	// Pid and CreateTime are those of the most recently started process of
+	// the group.
+	Pid        int32    `json:"pid"`
+	CreateTime int64    `json:"create_time"`
+	Count      int      `json:"count"`
+	Users      []string `json:"users"`
+	CPUPercent float64  `json:"cpu_percent"`
+	MemoryRss  uint64   `json:"memory_rss"`
+	NumThreads int32    `json:"threads"`
+	IOReadBPS  float64  `json:"io_read_bps"`
+	IOWriteBPS float64  `json:"io_write_bps"`
+}
+
+// EventType is the kind of an Event.
+type EventType string
+
+const (
+	EventStart EventType = "start"
+	EventExit  EventType = "exit"
+)
+
+// Event is a process that started or exited between two ticks.
+type Event struct {
+	Type      EventType `json:"type"`
+	Pid       int32     `json:"pid"`
+	Name      string    `json:"name"`
+	Group     string    `json:"group"`
+	Timestamp int64     `json:"timestamp"`
+}
+
+// Options configures a Collector.
+type Options struct {
+	Interval time.Duration
+	// Identify returns the group name of a new process, and the control
+	// group and container it runs in, if any. It is called once per
+	// process.
+	Identify func(p *process.Process) (group, cgroup, container string)
+}
+
+// tracked is a process followed across ticks.
+type tracked struct {
+	proc     *process.Process
+	sample   Sample
+	cpuTotal float64 // user + system seconds at the last tick
+	io       *process.IOCountersStat
+	lastTime time.Time
+}
+
+// Collector samples all processes every interval.
+type Collector struct {
+	opts Options
+
+	// Only accessed by the sampling goroutine
+	tracked map[int32]*tracked
+	primed  bool
+
+	mu      sync.RWMutex
+	samples []Sample
+	events  []Event
+	started uint64
+	exited  uint64
+}
+
+// NewCollector creates a Collector. Call Run to begin sampling.
+func NewCollector(opts Options) *Collector {
+	return &Collector{
+		opts:    opts,
+		tracked: make(map[int32]*tracked),
+	}
+}
+
+// Run takes a first sample and then samples every interval until ctx is
+// done.
+func (c *Collector) Run(ctx context.Context) {
+	c.collect()
+
+	ticker := time.NewTicker(c.opts.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			c.collect()
+		}
+	}
+}
+
+// Samples returns a copy of the processes of the last tick, sorted by CPU
+// usage.
+func (c *Collector) Samples() []Sample {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return append([]Sample(nil), c.samples...)
+}
+
+// Groups returns the processes of the last tick aggregated by by, sorted by
+// CPU usage.
+func (c *Collector) Groups(by GroupBy) []Group {
+	return GroupSamples(c.Samples(), by)
+}
+
+// Events returns the most recent process start and exit events, oldest
+// first, together with the total number of starts and exits seen.
+func (c *Collector) Events() (events []Event, started, exited uint64) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return append([]Event{}, c.events...), c.started, c.exited
+}
+
+// GroupSamples aggregates samples by by, sorted by CPU usage.
+func GroupSamples(samples []Sample, by GroupBy) []Group {
+	byKey := make(map[string]*Group)
+	users := make(map[string]map[string]bool)
+	for _, s := range samples {
+		key, name := by.group(s)
+		g, ok := byKey[key]
+		if !ok {
+			g = &Group{Name: name}
+			byKey[key] = g
+			users[key] = make(map[string]bool)
+		}
+		g.Count++
+		g.CPUPercent += s.CPUPercent
+		g.MemoryRss += s.MemoryRss
+		g.NumThreads += s.NumThreads
+		g.IOReadBPS += s.IOReadBPS
+		g.IOWriteBPS += s.IOWriteBPS
+		if s.CreateTime >= g.CreateTime {
+			g.CreateTime = s.CreateTime
+			g.Pid = s.Pid
+		}
+		if s.Username != "" && !users[key][s.Username] {
+			users[key][s.Username] = true
+			g.Users = append(g.Users, s.Username)
+		}
+	}
+
+	groups := make([]Group, 0, len(byKey))
+	for _, g := range byKey {
+		if g.Users == nil {
+			g.Users = []string{}
+		}
+		sort.Strings(g.Users)
+		groups = append(groups, *g)
+	}
+	sort.Slice(groups, func(i, j int) bool {
+		return groups[i].CPUPercent > groups[j].CPUPercent
+	})
+	return groups
+}
+
+func (c *Collector) collect() {
+	pids, err := process.Pids()
+	if err != nil {
+		log.Printf("Error listing processes: %v", err)
+		return
+	}
+
+	now := time.Now()
+	var events []Event
+	seen := make(map[int32]bool, len(pids))
+	samples := make([]Sample, 0, len(pids))
+	for _, pid := range pids {
+		t, ok := c.tracked[pid]
+		// A different start time means the PID now belongs to another
+		// process
+		if ok {
+			if createTime, err := readCreateTime(pid); err == nil && createTime != t.sample.CreateTime {
+				delete(c.tracked, pid)
+				events = append(events, newEvent(EventExit, t.sample, now))
+				ok = false
+			}
+		}
+		if !ok {
+			if t = c.track(pid); t == nil {
+				continue
+			}
+			c.tracked[pid] = t
+			if c.primed {
+				events = append(events, newEvent(EventStart, t.sample, now))
+			}
+		}
+		if !c.update(t, now) {
+			// The process exited while it was being sampled; it is
+			// reported as exited below.
+			continue
+		}
+		seen[pid] = true
+		samples = append(samples, t.sample)
+	}
+
+	for pid, t := range c.tracked {
+		if !seen[pid] {
+			delete(c.tracked, pid)
+			events = append(events, newEvent(EventExit, t.sample, now))
+		}
+	}
+
+	sort.Slice(samples, func(i, j int) bool {
+		return samples[i].CPUPercent > samples[j].CPUPercent
+	})
+
+	c.primed = true
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.samples = samples
+	for _, e := range events {
+		if e.Type == EventStart {
+			c.started++
+		} else {
+			c.exited++
+		}
+	}
+	c.events = append(c.events, events...)
+	if len(c.events) > maxEvents {
+		c.events = c.events[len(c.events)-maxEvents:]
+	}
+}
+
+// track starts following a process. The fields that do not change over
+// the life of a process are read once here.
+func (c *Collector) track(pid int32) *tracked {
+	p, err := process.NewProcess(pid)
+	if err != nil {
+		return nil
+	}
+	name, _ := p.Name()
+	createTime, _ := p.CreateTime()
+	ppid, _ := p.Ppid()
+	username, _ := p.Username()
+	cmdline, _ := p.Cmdline()
+	group := name
+	var cgroup, container string
+	if c.opts.Identify != nil {
+		group, cgroup, container = c.opts.Identify(p)
+	}
+	return &tracked{
+		proc: p,
+		sample: Sample{
+			Pid:        pid,
+			PPid:       ppid,
+			Name:       name,
+			Group:      group,
+			Username:   username,
+			Cmdline:    cmdline,
+			Cgroup:     cgroup,
+			Container:  container,
+			CreateTime: createTime,
+		},
+	}
+}
+
+// update refreshes the usage of a tracked process. It reports false if the
+// process can no longer be read.
+func (c *Collector) update(t *tracked, now time.Time) bool {
+	times, err := t.proc.Times()
+	if err != nil {
+		return false
+	}
+	memInfo, err := t.proc.MemoryInfo()
+	if err != nil {
+		return false
+	}
+
+	// Optional on some platforms, so failures leave the last value
+	if threads, err := t.proc.NumThreads(); err == nil {
+		t.sample.NumThreads = threads
+	}
+	if status, err := t.proc.Status(); err == nil && len(status) > 0 {
+		t.sample.Status = status[0]
+	}
+	io, err := t.proc.IOCounters()
+	if err != nil {
+		io = nil
+	}
+
+	total := times.User + times.System
+	wall := now.Sub(t.lastTime).Seconds()
+	if !t.lastTime.IsZero() && wall > 0 {
+		t.sample.CPUPercent = (total - t.cpuTotal) / wall * 100
+		t.sample.IOReadBPS, t.sample.IOWriteBPS = 0, 0
+		if io != nil && t.io != nil && io.ReadBytes >= t.io.ReadBytes && io.WriteBytes >= t.io.WriteBytes {
+			t.sample.IOReadBPS = float64(io.ReadBytes-t.io.ReadBytes) / wall
+			t.sample.IOWriteBPS = float64(io.WriteBytes-t.io.WriteBytes) / wall
+		}
+	} else {
+		t.sample.CPUPercent = 0
+		t.sample.IOReadBPS, t.sample.IOWriteBPS = 0, 0
+	}
+	t.cpuTotal = total
+	t.io = io
+	t.lastTime = now
+	t.sample.MemoryRss = memInfo.RSS
+	return true
+}
+
+// readCreateTime reads the start time of pid in unix milliseconds. A new
+// handle is used since process handles cache it.
+func readCreateTime(pid int32) (int64, error) {
+	return (&process.Process{Pid: pid}).CreateTime()
+}
+
+func newEvent(typ EventType, s Sample, now time.Time) Event {
+	return Event{
+		Type:      typ,
+		Pid:       s.Pid,
+		Name:      s.Name,
+		Group:     s.Group,
+		Timestamp: now.Unix(),
+	}
+}
@@ -0,0 +1,216 @@
+package procs
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/shirou/gopsutil/v3/process"
+)
+
+// ErrDenied is returned for an action on a protected process.
+var ErrDenied = errors.New("process is protected")
+
+// Priority bounds of ReniceAction, from highest to lowest priority.
+const (
+	MinPriority = -20
+	MaxPriority = 19
+)
+
+// Action is a control action on a process.
+type Action struct {
+	// Kind is "signal" or "renice".
+	Kind       string
+	SignalName string
+	Signal     syscall.Signal
+	Priority   int
+}
+
+// SignalAction returns the action sending the named signal, such as "TERM"
+// or "SIGKILL". It returns an error wrapping errors.ErrUnsupported where
+// signals cannot be sent.
+func SignalAction(name string) (Action, error) {
+	name = strings.TrimPrefix(strings.ToUpper(name), "SIG")
+	if len(signals) == 0 {
+		return Action{}, fmt.Errorf("failed to send signal '%s': %w", name, errors.ErrUnsupported)
+	}
+	sig, ok := signals[name]
+	if !ok {
+		return Action{}, fmt.Errorf("unknown signal '%s'", name)
+	}
+	return Action{Kind: "signal", SignalName: "SIG" + name, Signal: sig}, nil
+}
+
+// ReniceAction returns the action setting the scheduling priority. Raising
+// the priority above the current one usually requires root.
+func ReniceAction(priority int) (Action, error) {
+	if priority < MinPriority || priority > MaxPriority {
+		return Action{}, fmt.Errorf("priority must be between %d and %d", MinPriority, MaxPriority)
+	}
+	return Action{Kind: "renice", Priority: priority}, nil
+}
+
+// Argument returns the signal name or priority of the action.
+func (a Action) Argument() string {
+	if a.Kind == "signal" {
+		return a.SignalName
+	}
+	return strconv.Itoa(a.Priority)
+}
+
+// Policy restricts which processes can be controlled, by process or group
+// name. PID 1 and the server itself are always protected.
+type Policy struct {
+	// Allow, when not empty, is the only names that can be controlled.
+	Allow []string
+	// Deny names are never controlled.
+	Deny []string
+}
+
+// Controller sends signals to and renices processes, and records every
+// attempt in an audit log.
+type Controller struct {
+	collector *Collector
+	audit     *AuditStore
+	allow     map[string]bool
+	deny      map[string]bool
+	self      int32
+}
+
+// NewController creates a Controller acting on the processes known to
+// collector.
+func NewController(collector *Collector, audit *AuditStore, policy Policy) *Controller {
+	ctl := &Controller{
+		collector: collector,
+		audit:     audit,
+		allow:     make(map[string]bool),
+		deny:      make(map[string]bool),
+		self:      int32(os.Getpid()),
+	}
+	for _, name := range policy.Allow {
+		ctl.allow[name] = true
+	}
+	for _, name := range policy.Deny {
+		ctl.deny[name] = true
+	}
+	return ctl
+}
+
+// Apply performs action on the process pid on behalf of actor. It returns
+// ErrNotFound for an unknown process and ErrDenied for a protected one.
+func (ctl *Controller) Apply(pid int32, action Action, actor string) (AuditEntry, error) {
+	target, ok := ctl.lookup(pid)
+	if !ok {
+		return AuditEntry{}, ErrNotFound
+	}
+	return ctl.apply(target, action, actor)
+}
+
+// ApplyGroup performs action on every process of a group on behalf of
+// actor. Protected members are skipped and reported in their entry. It
+// returns ErrNotFound if the group has no processes.
+func (ctl *Controller) ApplyGroup(group string, action Action, actor string) ([]AuditEntry, error) {
+	var entries []AuditEntry
+	for _, s := range ctl.collector.Samples() {
+		if s.Group != group {
+			continue
+		}
+		entry, _ := ctl.apply(s, action, actor)
+		entries = append(entries, entry)
+	}
+	if entries == nil {
+		return nil, ErrNotFound
+	}
+	return entries, nil
+}
+
+// Audit returns up to limit recorded actions, most recent first.
+func (ctl *Controller) Audit(limit int) ([]AuditEntry, error) {
+	return ctl.audit.History(limit)
+}
+
+// lookup returns the process pid as last sampled, or reads it if it started
+// since.
+func (ctl *Controller) lookup(pid int32) (Sample, bool) {
+	for _, s := range ctl.collector.Samples() {
+		if s.Pid == pid {
+			return s, true
+		}
+	}
+	if t := ctl.collector.track(pid); t != nil {
+		return t.sample, true
+	}
+	return Sample{}, false
+}
+
+func (ctl *Controller) apply(target Sample, action Action, actor string) (AuditEntry, error) {
+	err := ctl.check(target)
+	if err == nil {
+		err = ctl.perform(target, action)
+	}
+
+	entry := AuditEntry{
+		Timestamp: time.Now().Unix(),
+		Actor:     actor,
+		Action:    action.Kind,
+		Argument:  action.Argument(),
+		Pid:       target.Pid,
+		Name:      target.Name,
+		Group:     target.Group,
+	}
+	if err != nil {
+		entry.Error = err.Error()
+	}
+	log.Printf("Process control by %s: %s %s on %d (%s): %s", actor, action.Kind, entry.Argument, target.Pid, target.Name, resultText(err))
+	if auditErr := ctl.audit.Record(entry); auditErr != nil {
+		log.Printf("Error recording process audit entry: %v", auditErr)
+	}
+	return entry, err
+}
+
+// check returns an error wrapping ErrDenied if target is protected.
+func (ctl *Controller) check(target Sample) error {
+	switch {
+	case target.Pid <= 1:
+		return fmt.Errorf("%w: PID %d", ErrDenied, target.Pid)
+	case target.Pid == ctl.self:
+		return fmt.Errorf("%w: the monitor itself", ErrDenied)
+	case ctl.deny[target.Name] || ctl.deny[target.Group]:
+		return fmt.Errorf("%w: '%s' is on the deny list", ErrDenied, target.Group)
+	case len(ctl.allow) > 0 && !ctl.allow[target.Name] && !ctl.allow[target.Group]:
+		return fmt.Errorf("%w: '%s' is not on the allow list", ErrDenied, target.Group)
+	}
+	return nil
+}
+
+func (ctl *Controller) perform(target Sample, action Action) error {
+	// Make sure the PID was not reused by another process since it was
+	// sampled.
+	p, err := process.NewProcess(target.Pid)
+	if err != nil {
+		return syscall.ESRCH
+	}
+	if createTime, err := p.CreateTime(); err == nil && createTime != target.CreateTime {
+		return syscall.ESRCH
+	}
+
+	switch action.Kind {
+	case "signal":
+		return kill(target.Pid, action.Signal)
+	case "renice":
+		return setPriority(target.Pid, action.Priority)
+	}
+	return fmt.Errorf("unknown action '%s'", action.Kind)
+}
+
+func resultText(err error) string {
+	if err != nil {
+		return err.Error()
+	}
+	return "ok"
+}
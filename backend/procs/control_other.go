@@ -0,0 +1,19 @@
+//go:build !unix
+
+package procs
+
+import (
+	"errors"
+	"syscall"
+)
+
+// signals is empty, as signals cannot be sent on this platform.
+var signals = map[string]syscall.Signal{}
+
+func kill(pid int32, sig syscall.Signal) error {
+	return errors.ErrUnsupported
+}
+
+func setPriority(pid int32, priority int) error {
+	return errors.ErrUnsupported
+}
@@ -0,0 +1,27 @@
+//go:build unix
+
+package procs
+
+import "syscall"
+
+// signals are the signals that can be sent, by name.
+var signals = map[string]syscall.Signal{
+	"HUP":  syscall.SIGHUP,
+	"INT":  syscall.SIGINT,
+	"QUIT": syscall.SIGQUIT,
+	"KILL": syscall.SIGKILL,
+	"TERM": syscall.SIGTERM,
+	"STOP": syscall.SIGSTOP,
+	"CONT": syscall.SIGCONT,
+	"TSTP": syscall.SIGTSTP,
+	"USR1": syscall.SIGUSR1,
+	"USR2": syscall.SIGUSR2,
+}
+
+func kill(pid int32, sig syscall.Signal) error {
+	return syscall.Kill(int(pid), sig)
+}
+
+func setPriority(pid int32, priority int) error {
+	return syscall.Setpriority(syscall.PRIO_PROCESS, int(pid), priority)
+}
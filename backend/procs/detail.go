@@ -0,0 +1,253 @@
+package procs
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"sort"
+	"strconv"
+	"syscall"
+
+	"github.com/shirou/gopsutil/v3/process"
+)
+
+// ErrNotFound is returned for a PID that does not belong to a running
+// process.
+var ErrNotFound = errors.New("process not found")
+
+// ProcessRef identifies a related process.
+type ProcessRef struct {
+	Pid  int32  `json:"pid"`
+	Name string `json:"name"`
+}
+
+// TreeNode is a process and its descendants.
+type TreeNode struct {
+	ProcessRef
+	Children []TreeNode `json:"children"`
+}
+
+// CPUTimes is the CPU time a process or thread used, in seconds.
+type CPUTimes struct {
+	User   float64 `json:"user"`
+	System float64 `json:"system"`
+	Iowait float64 `json:"iowait,omitempty"`
+}
+
+// Thread is the CPU time of one thread.
+type Thread struct {
+	ID int32 `json:"id"`
+	CPUTimes
+}
+
+// Memory is the memory usage of a process in bytes.
+type Memory struct {
+	Rss     uint64  `json:"rss"`
+	Vms     uint64  `json:"vms"`
+	Swap    uint64  `json:"swap"`
+	Percent float32 `json:"percent"`
+}
+
+// MemoryMaps summarizes the mapped memory regions of a process. Totals has
+// the platform-specific sums over all regions.
+type MemoryMaps struct {
+	Regions int                     `json:"regions"`
+	Totals  *process.MemoryMapsStat `json:"totals,omitempty"`
+}
+
+// Connection is a socket held by a process.
+type Connection struct {
+	Fd         uint32 `json:"fd"`
+	Family     string `json:"family"`
+	Type       string `json:"type"`
+	LocalAddr  string `json:"local_addr"`
+	RemoteAddr string `json:"remote_addr,omitempty"`
+	Status     string `json:"status,omitempty"`
+}
+
+// Detail is the full view of one process. Sections the platform does not
+// support, or the server is not allowed to read, are left empty.
+type Detail struct {
+	Sample
+	Exe         string                  `json:"exe,omitempty"`
+	Cwd         string                  `json:"cwd,omitempty"`
+	Args        []string                `json:"args"`
+	Nice        int32                   `json:"nice"`
+	NumFDs      int32                   `json:"num_fds"`
+	CPUTimes    *CPUTimes               `json:"cpu_times,omitempty"`
+	Memory      *Memory                 `json:"memory,omitempty"`
+	MemoryMaps  *MemoryMaps             `json:"memory_maps,omitempty"`
+	Parents     []ProcessRef            `json:"parents"`
+	Children    []TreeNode              `json:"children"`
+	Threads     []Thread                `json:"thread_times"`
+	OpenFiles   []process.OpenFilesStat `json:"open_files"`
+	Connections []Connection            `json:"connections"`
+	// GroupPids lists every process aggregated under the same group name,
+	// such as all processes of an app bundle.
+	GroupPids []int32 `json:"group_pids"`
+	// Environ is only filled when requested.
+	Environ []string `json:"environ,omitempty"`
+}
+
+// Detail reads the full view of a process. The parents, children and group
+// members come from the last tick, so very new processes may be missing
+// from them. The environment is only read if withEnv is set.
+func (c *Collector) Detail(pid int32, withEnv bool) (*Detail, error) {
+	p, err := process.NewProcess(pid)
+	if err != nil {
+		if errors.Is(err, process.ErrorProcessNotRunning) {
+			return nil, ErrNotFound
+		}
+		return nil, fmt.Errorf("failed to open process %d: %w", pid, err)
+	}
+
+	samples := c.Samples()
+	byPid := make(map[int32]Sample, len(samples))
+	for _, s := range samples {
+		byPid[s.Pid] = s
+	}
+	sample, ok := byPid[pid]
+	if !ok {
+		// Started since the last tick; report what does not need a delta.
+		t := c.track(pid)
+		if t == nil {
+			return nil, ErrNotFound
+		}
+		sample = t.sample
+	}
+
+	d := &Detail{
+		Sample:      sample,
+		Args:        []string{},
+		Parents:     parents(sample, byPid),
+		Children:    children(pid, samples, make(map[int32]bool)),
+		Threads:     []Thread{},
+		OpenFiles:   []process.OpenFilesStat{},
+		Connections: []Connection{},
+		GroupPids:   []int32{},
+	}
+	for _, s := range samples {
+		if s.Group == sample.Group {
+			d.GroupPids = append(d.GroupPids, s.Pid)
+		}
+	}
+	sort.Slice(d.GroupPids, func(i, j int) bool { return d.GroupPids[i] < d.GroupPids[j] })
+
+	d.Exe, _ = p.Exe()
+	d.Cwd, _ = p.Cwd()
+	if args, err := p.CmdlineSlice(); err == nil && args != nil {
+		d.Args = args
+	}
+	d.Nice, _ = p.Nice()
+	d.NumFDs, _ = p.NumFDs()
+	if times, err := p.Times(); err == nil {
+		d.CPUTimes = &CPUTimes{User: times.User, System: times.System, Iowait: times.Iowait}
+	}
+	if memInfo, err := p.MemoryInfo(); err == nil {
+		percent, _ := p.MemoryPercent()
+		d.Memory = &Memory{Rss: memInfo.RSS, Vms: memInfo.VMS, Swap: memInfo.Swap, Percent: percent}
+	}
+	if maps, err := p.MemoryMaps(false); err == nil && maps != nil {
+		d.MemoryMaps = &MemoryMaps{Regions: len(*maps)}
+		if totals, err := p.MemoryMaps(true); err == nil && totals != nil && len(*totals) > 0 {
+			d.MemoryMaps.Totals = &(*totals)[0]
+		}
+	}
+	if threads, err := p.Threads(); err == nil {
+		for id, times := range threads {
+			d.Threads = append(d.Threads, Thread{
+				ID:       id,
+				CPUTimes: CPUTimes{User: times.User, System: times.System, Iowait: times.Iowait},
+			})
+		}
+		sort.Slice(d.Threads, func(i, j int) bool { return d.Threads[i].ID < d.Threads[j].ID })
+	}
+	if files, err := p.OpenFiles(); err == nil && files != nil {
+		d.OpenFiles = files
+	}
+	if conns, err := p.Connections(); err == nil {
+		for _, conn := range conns {
+			d.Connections = append(d.Connections, Connection{
+				Fd:         conn.Fd,
+				Family:     familyName(conn.Family),
+				Type:       socketTypeName(conn.Type),
+				LocalAddr:  joinHostPort(conn.Laddr.IP, conn.Laddr.Port),
+				RemoteAddr: joinHostPort(conn.Raddr.IP, conn.Raddr.Port),
+				Status:     conn.Status,
+			})
+		}
+	}
+	if withEnv {
+		if env, err := p.Environ(); err == nil {
+			d.Environ = env
+		}
+	}
+	return d, nil
+}
+
+// parents returns the ancestors of s, closest first.
+func parents(s Sample, byPid map[int32]Sample) []ProcessRef {
+	refs := []ProcessRef{}
+	seen := map[int32]bool{s.Pid: true}
+	for pid := s.PPid; pid > 0 && !seen[pid]; {
+		parent, ok := byPid[pid]
+		if !ok {
+			break
+		}
+		seen[pid] = true
+		refs = append(refs, ProcessRef{Pid: parent.Pid, Name: parent.Name})
+		pid = parent.PPid
+	}
+	return refs
+}
+
+// children returns the tree of processes below pid, ordered by PID.
+func children(pid int32, samples []Sample, seen map[int32]bool) []TreeNode {
+	seen[pid] = true
+	nodes := []TreeNode{}
+	for _, s := range samples {
+		if s.PPid != pid || seen[s.Pid] {
+			continue
+		}
+		nodes = append(nodes, TreeNode{
+			ProcessRef: ProcessRef{Pid: s.Pid, Name: s.Name},
+			Children:   children(s.Pid, samples, seen),
+		})
+	}
+	sort.Slice(nodes, func(i, j int) bool { return nodes[i].Pid < nodes[j].Pid })
+	return nodes
+}
+
+func familyName(family uint32) string {
+	switch family {
+	case syscall.AF_INET:
+		return "inet"
+	case syscall.AF_INET6:
+		return "inet6"
+	case syscall.AF_UNIX:
+		return "unix"
+	}
+	return fmt.Sprint(family)
+}
+
+func socketTypeName(typ uint32) string {
+	switch typ {
+	case syscall.SOCK_STREAM:
+		return "tcp"
+	case syscall.SOCK_DGRAM:
+		return "udp"
+	}
+	return fmt.Sprint(typ)
+}
+
+// joinHostPort formats a socket address, or returns "" for the unset
+// remote address of a listening socket. Unix sockets have only a path.
+func joinHostPort(ip string, port uint32) string {
+	if port == 0 {
+		if ip == "::" || ip == "0.0.0.0" {
+			return ""
+		}
+		return ip
+	}
+	return net.JoinHostPort(ip, strconv.Itoa(int(port)))
+}
@@ -0,0 +1,178 @@
+package procs
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// SortKey selects the order of a process listing.
+type SortKey string
+
+const (
+	SortCPU     SortKey = "cpu"
+	SortMemory  SortKey = "mem"
+	SortName    SortKey = "name"
+	SortPid     SortKey = "pid"
+	SortThreads SortKey = "threads"
+	SortIO      SortKey = "io"
+)
+
+// ParseSortKey validates a sort key name. An empty name means SortCPU.
+func ParseSortKey(s string) (SortKey, error) {
+	switch k := SortKey(s); k {
+	case "":
+		return SortCPU, nil
+	case SortCPU, SortMemory, SortName, SortPid, SortThreads, SortIO:
+		return k, nil
+	}
+	return "", fmt.Errorf("unknown sort key '%s'", s)
+}
+
+// Descending reports whether the key sorts largest first by default, which
+// is the case for every usage key.
+func (k SortKey) Descending() bool {
+	return k != SortName && k != SortPid
+}
+
+// GroupBy selects how processes are aggregated into groups.
+type GroupBy string
+
+const (
+	// GroupByApp groups by the group name of each process, such as its app
+	// bundle or systemd service.
+	GroupByApp GroupBy = "app"
+	// GroupByCgroup groups by control group. Processes without one are
+	// grouped by name.
+	GroupByCgroup GroupBy = "cgroup"
+	// GroupByUser groups by owning user.
+	GroupByUser GroupBy = "user"
+	// GroupByContainer groups by container. Processes outside containers
+	// form the HostGroup.
+	GroupByContainer GroupBy = "container"
+	// GroupByNone makes every process its own group.
+	GroupByNone GroupBy = "none"
+)
+
+// HostGroup is the group of processes outside containers with
+// GroupByContainer.
+const HostGroup = "host"
+
+// ParseGroupBy validates a grouping name. An empty name means GroupByApp.
+func ParseGroupBy(s string) (GroupBy, error) {
+	switch g := GroupBy(s); g {
+	case "":
+		return GroupByApp, nil
+	case GroupByApp, GroupByCgroup, GroupByUser, GroupByContainer, GroupByNone:
+		return g, nil
+	}
+	return "", fmt.Errorf("unknown grouping '%s'", s)
+}
+
+// group returns the key and name of the group s belongs to.
+func (g GroupBy) group(s Sample) (key, name string) {
+	switch g {
+	case GroupByCgroup:
+		if s.Cgroup != "" {
+			return s.Cgroup, s.Cgroup
+		}
+		return s.Name, s.Name
+	case GroupByUser:
+		return s.Username, s.Username
+	case GroupByContainer:
+		if s.Container != "" {
+			return s.Container, s.Container
+		}
+		return HostGroup, HostGroup
+	case GroupByNone:
+		return strconv.Itoa(int(s.Pid)), s.Name
+	}
+	return s.Group, s.Group
+}
+
+// Filter selects processes by name and user. Empty fields match everything.
+type Filter struct {
+	// Name matches a case-insensitive substring of the process or group
+	// name.
+	Name string
+	// User matches the owning user exactly.
+	User string
+}
+
+// Apply returns the samples matching f.
+func (f Filter) Apply(samples []Sample) []Sample {
+	if f.Name == "" && f.User == "" {
+		return samples
+	}
+	name := strings.ToLower(f.Name)
+	var matched []Sample
+	for _, s := range samples {
+		if f.User != "" && s.Username != f.User {
+			continue
+		}
+		if name != "" && !strings.Contains(strings.ToLower(s.Name), name) &&
+			!strings.Contains(strings.ToLower(s.Group), name) {
+			continue
+		}
+		matched = append(matched, s)
+	}
+	return matched
+}
+
+// SortSamples sorts samples by key, ties broken by PID.
+func SortSamples(samples []Sample, key SortKey, desc bool) {
+	sort.SliceStable(samples, func(i, j int) bool {
+		a, b := samples[i], samples[j]
+		var less, greater bool
+		switch key {
+		case SortCPU:
+			less, greater = a.CPUPercent < b.CPUPercent, a.CPUPercent > b.CPUPercent
+		case SortMemory:
+			less, greater = a.MemoryRss < b.MemoryRss, a.MemoryRss > b.MemoryRss
+		case SortName:
+			less, greater = a.Name < b.Name, a.Name > b.Name
+		case SortThreads:
+			less, greater = a.NumThreads < b.NumThreads, a.NumThreads > b.NumThreads
+		case SortIO:
+			ioA, ioB := a.IOReadBPS+a.IOWriteBPS, b.IOReadBPS+b.IOWriteBPS
+			less, greater = ioA < ioB, ioA > ioB
+		}
+		if !less && !greater {
+			less, greater = a.Pid < b.Pid, a.Pid > b.Pid
+		}
+		if desc {
+			return greater
+		}
+		return less
+	})
+}
+
+// SortGroups sorts groups by key, ties broken by name. SortPid orders by
+// the PID each group reports.
+func SortGroups(groups []Group, key SortKey, desc bool) {
+	sort.SliceStable(groups, func(i, j int) bool {
+		a, b := groups[i], groups[j]
+		var less, greater bool
+		switch key {
+		case SortCPU:
+			less, greater = a.CPUPercent < b.CPUPercent, a.CPUPercent > b.CPUPercent
+		case SortMemory:
+			less, greater = a.MemoryRss < b.MemoryRss, a.MemoryRss > b.MemoryRss
+		case SortPid:
+			less, greater = a.Pid < b.Pid, a.Pid > b.Pid
+		case SortThreads:
+			less, greater = a.NumThreads < b.NumThreads, a.NumThreads > b.NumThreads
+		case SortIO:
+			ioA, ioB := a.IOReadBPS+a.IOWriteBPS, b.IOReadBPS+b.IOWriteBPS
+			less, greater = ioA < ioB, ioA > ioB
+		}
+		if !less && !greater {
+			less, greater = a.Name < b.Name, a.Name > b.Name
+		}
+		if desc {
+			return greater
+		}
+		return less
+	})
+}
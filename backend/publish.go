@@ -0,0 +1,44 @@
+package main
+
+import (
+	"context"
+	"time"
+
+	"macos-monitor/backend-go/cpustats"
+	"macos-monitor/backend-go/hub"
+	"macos-monitor/backend-go/memstats"
+	"macos-monitor/backend-go/procs"
+)
+
+// publishLoop pushes the dynamic system info and the top processes to their
+// topics every interval, but only collects them while someone subscribes.
+// It returns when ctx is done.
+func publishLoop(ctx context.Context, h *hub.Hub, cs *cpustats.Sampler, ms *memstats.Sampler, pc *procs.Collector, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		wantSystem := h.HasSubscribers(hub.TopicSystemDynamic)
+		wantProcesses := h.HasSubscribers(hub.TopicProcessesTop)
+		if !wantSystem && !wantProcesses {
+			continue
+		}
+
+		info := collectDynamicSystemInfo(cs, ms, pc, procs.GroupByApp)
+		if len(info.Processes) > topProcessCount {
+			info.Processes = info.Processes[:topProcessCount]
+		}
+		if wantSystem {
+			h.Publish(hub.TopicSystemDynamic, "system", "", info)
+		}
+		if wantProcesses {
+			h.Publish(hub.TopicProcessesTop, "processes", "", info.Processes)
+		}
+	}
+}
@@ -0,0 +1,158 @@
+package sensors
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"sync"
+	"time"
+
+	"macos-monitor/backend-go/hub"
+)
+
+// Sensor is the latest reading of a sensor with its range so far today.
+type Sensor struct {
+	Reading
+	TodayMin float64 `json:"today_min"`
+	TodayMax float64 `json:"today_max"`
+}
+
+// Collector reads a Source every interval, records the daily ranges and
+// publishes the readings on the hub.
+type Collector struct {
+	source   Source
+	store    *Store
+	hub      *hub.Hub
+	interval time.Duration
+
+	// Whether the last read failed, so failures are only logged once
+	failing bool
+
+	mu      sync.RWMutex
+	sensors []Sensor
+}
+
+// NewCollector creates a Collector that reads source every interval.
+func NewCollector(source Source, store *Store, h *hub.Hub, interval time.Duration) *Collector {
+	return &Collector{source: source, store: store, hub: h, interval: interval, sensors: []Sensor{}}
+}
+
+// Run reads the sensors right away and then every interval until ctx is
+// done.
+func (c *Collector) Run(ctx context.Context) {
+	ticker := time.NewTicker(c.interval)
+	defer ticker.Stop()
+
+	c.collect(time.Now())
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			c.collect(now)
+		}
+	}
+}
+
+// Sensors returns the latest reading of every sensor, by name.
+func (c *Collector) Sensors() []Sensor {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	sensors := make([]Sensor, len(c.sensors))
+	copy(sensors, c.sensors)
+	return sensors
+}
+
+// Daily returns the ranges of every sensor over the days from one date to
+// another (YYYY-MM-DD, inclusive).
+func (c *Collector) Daily(from, to string) ([]DailyRange, error) {
+	return c.store.Daily(from, to)
+}
+
+func (c *Collector) collect(now time.Time) {
+	readings, err := c.source.Temperatures()
+	if err != nil {
+		if !c.failing {
+			log.Printf("Error reading temperature sensors: %v", err)
+		}
+		c.failing = true
+		// Keep what could be read. If nothing could, clear the last
+		// readings rather than report them as current
+		if len(readings) == 0 {
+			c.clear()
+			return
+		}
+	} else if c.failing {
+		log.Printf("Temperature sensors readable again")
+		c.failing = false
+	}
+	uniqueNames(readings)
+
+	date := now.Format("2006-01-02")
+	if err := c.store.Record(date, readings); err != nil {
+		log.Printf("Error recording sensor ranges: %v", err)
+	}
+
+	// Today's ranges already include the readings
+	today := make(map[string]DailyRange)
+	if ranges, err := c.store.Daily(date, date); err == nil {
+		for _, r := range ranges {
+			today[r.Sensor] = r
+		}
+	} else {
+		log.Printf("Error reading today's sensor ranges: %v", err)
+	}
+	sensors := make([]Sensor, 0, len(readings))
+	for _, r := range readings {
+		s := Sensor{Reading: r, TodayMin: r.Temperature, TodayMax: r.Temperature}
+		if t, ok := today[r.Sensor]; ok {
+			s.TodayMin, s.TodayMax = t.Min, t.Max
+		}
+		sensors = append(sensors, s)
+	}
+	sort.Slice(sensors, func(i, j int) bool {
+		return sensors[i].Sensor < sensors[j].Sensor
+	})
+
+	c.mu.Lock()
+	c.sensors = sensors
+	c.mu.Unlock()
+
+	c.hub.Publish(hub.TopicSensors, "sensors", "", sensors)
+}
+
+// clear drops the latest readings and publishes the empty list once.
+func (c *Collector) clear() {
+	c.mu.Lock()
+	cleared := len(c.sensors) > 0
+	c.sensors = []Sensor{}
+	c.mu.Unlock()
+
+	if cleared {
+		c.hub.Publish(hub.TopicSensors, "sensors", "", []Sensor{})
+	}
+}
+
+// uniqueNames numbers sensors sharing a name, such as the same chip on two
+// devices, as "name_2", "name_3" and so on, in the order they were read,
+// skipping numbers another sensor is already named with.
+func uniqueNames(readings []Reading) {
+	used := make(map[string]bool, len(readings))
+	for _, r := range readings {
+		used[r.Sensor] = true
+	}
+	seen := make(map[string]bool, len(readings))
+	for i, r := range readings {
+		if !seen[r.Sensor] {
+			seen[r.Sensor] = true
+			continue
+		}
+		name := r.Sensor
+		for n := 2; used[name]; n++ {
+			name = fmt.Sprintf("%s_%d", r.Sensor, n)
+		}
+		used[name] = true
+		readings[i].Sensor = name
+	}
+}
@@ -0,0 +1,137 @@
+package sensors
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	_ "github.com/mattn/go-sqlite3"
+	"macos-monitor/backend-go/hub"
+)
+
+// fakeSource returns its readings and error, which tests change between
+// reads.
+type fakeSource struct {
+	readings []Reading
+	err      error
+}
+
+func (s *fakeSource) Temperatures() ([]Reading, error) {
+	return slices.Clone(s.readings), s.err
+}
+
+func newTestCollector(t *testing.T, source Source) *Collector {
+	t.Helper()
+	db, err := sql.Open("sqlite3", filepath.Join(t.TempDir(), "sensors.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	store, err := NewStore(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	h := hub.NewHub(hub.TopicSensors)
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	go h.Run(ctx)
+	return NewCollector(source, store, h, time.Minute)
+}
+
+func TestUniqueNames(t *testing.T) {
+	readings := []Reading{{Sensor: "nvme"}, {Sensor: "acpitz"}, {Sensor: "nvme"}, {Sensor: "nvme"}, {Sensor: "nvme_2"}}
+	uniqueNames(readings)
+	var got []string
+	for _, r := range readings {
+		got = append(got, r.Sensor)
+	}
+	// Numbers already taken by another sensor are skipped
+	want := []string{"nvme", "acpitz", "nvme_3", "nvme_4", "nvme_2"}
+	if !slices.Equal(got, want) {
+		t.Errorf("names = %v, want %v", got, want)
+	}
+}
+
+func TestCollectorDailyRange(t *testing.T) {
+	source := &fakeSource{}
+	c := newTestCollector(t, source)
+	day := time.Date(2026, 3, 14, 9, 0, 0, 0, time.Local)
+
+	if data, _ := json.Marshal(c.Sensors()); string(data) != "[]" {
+		t.Errorf("Sensors() before reading = %s, want []", data)
+	}
+	c.collect(day)
+	if data, _ := json.Marshal(c.Sensors()); string(data) != "[]" {
+		t.Errorf("Sensors() without sensors = %s, want []", data)
+	}
+
+	for i, temps := range [][2]float64{{50, 40}, {65, 38}, {55, 45}} {
+		source.readings = []Reading{{Sensor: "cpu", Temperature: temps[0]}, {Sensor: "nvme", Temperature: temps[1]}}
+		c.collect(day.Add(time.Duration(i) * time.Hour))
+	}
+	want := []Sensor{
+		{Reading: Reading{Sensor: "cpu", Temperature: 55}, TodayMin: 50, TodayMax: 65},
+		{Reading: Reading{Sensor: "nvme", Temperature: 45}, TodayMin: 38, TodayMax: 45},
+	}
+	if got := c.Sensors(); !slices.Equal(got, want) {
+		t.Errorf("Sensors() = %+v, want %+v", got, want)
+	}
+
+	// A failing read keeps the sensors that could be read
+	source.readings = []Reading{{Sensor: "cpu", Temperature: 70}}
+	source.err = errors.New("nvme unreadable")
+	c.collect(day.Add(3 * time.Hour))
+	want = []Sensor{{Reading: Reading{Sensor: "cpu", Temperature: 70}, TodayMin: 50, TodayMax: 70}}
+	if got := c.Sensors(); !slices.Equal(got, want) {
+		t.Errorf("Sensors() after partial failure = %+v, want %+v", got, want)
+	}
+
+	// A failing read without any readings clears them
+	source.readings = nil
+	c.collect(day.Add(4 * time.Hour))
+	if data, _ := json.Marshal(c.Sensors()); string(data) != "[]" {
+		t.Errorf("Sensors() after failure = %s, want []", data)
+	}
+
+	// The range starts over the next day
+	source.readings, source.err = []Reading{{Sensor: "cpu", Temperature: 48}}, nil
+	c.collect(day.Add(24 * time.Hour))
+	want = []Sensor{{Reading: Reading{Sensor: "cpu", Temperature: 48}, TodayMin: 48, TodayMax: 48}}
+	if got := c.Sensors(); !slices.Equal(got, want) {
+		t.Errorf("Sensors() the next day = %+v, want %+v", got, want)
+	}
+
+	ranges, err := c.Daily("2026-03-14", "2026-03-15")
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantRanges := []DailyRange{
+		{Date: "2026-03-14", Sensor: "cpu", Min: 50, Max: 70},
+		{Date: "2026-03-14", Sensor: "nvme", Min: 38, Max: 45},
+		{Date: "2026-03-15", Sensor: "cpu", Min: 48, Max: 48},
+	}
+	if !slices.Equal(ranges, wantRanges) {
+		t.Errorf("Daily() = %+v, want %+v", ranges, wantRanges)
+	}
+}
+
+func TestCollectorDuplicateNames(t *testing.T) {
+	source := &fakeSource{readings: []Reading{
+		{Sensor: "nvme", Temperature: 40},
+		{Sensor: "nvme", Temperature: 50},
+	}}
+	c := newTestCollector(t, source)
+	c.collect(time.Date(2026, 3, 14, 9, 0, 0, 0, time.Local))
+
+	got := c.Sensors()
+	if len(got) != 2 || got[0].Sensor != "nvme" || got[0].Temperature != 40 ||
+		got[1].Sensor != "nvme_2" || got[1].TodayMax != 50 {
+		t.Errorf("Sensors() = %+v, want nvme at 40 and nvme_2 at 50", got)
+	}
+}
@@ -0,0 +1,109 @@
+// Package sensors reads temperature sensors, keeps the latest readings and
+// records the daily minimum and maximum of each sensor.
+package sensors
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/shirou/gopsutil/v3/host"
+)
+
+// Reading is the temperature of one sensor in degrees Celsius. High and
+// Critical are the thresholds the sensor reports, or 0 where it has none.
+type Reading struct {
+	Sensor      string  `json:"sensor"`
+	Temperature float64 `json:"temperature"`
+	High        float64 `json:"high,omitempty"`
+	Critical    float64 `json:"critical,omitempty"`
+}
+
+// Source reads the current temperature of every sensor. It may return
+// readings along with an error when only some sensors could be read.
+type Source interface {
+	Temperatures() ([]Reading, error)
+}
+
+// HostSource reads the sensors gopsutil knows about: hwmon and thermal
+// zones on Linux, the SMC on macOS.
+type HostSource struct{}
+
+func (HostSource) Temperatures() ([]Reading, error) {
+	temps, err := host.SensorsTemperatures()
+	readings := make([]Reading, 0, len(temps))
+	for _, t := range temps {
+		readings = append(readings, Reading{Sensor: t.SensorKey, Temperature: t.Temperature, High: t.High, Critical: t.Critical})
+	}
+	return readings, err
+}
+
+// HwmonSource reads the temp*_input files of a Linux hwmon tree such as
+// /sys/class/hwmon. Sensors are named like gopsutil names them, after the
+// chip and the lowercased label, e.g. "coretemp_core_0".
+type HwmonSource struct {
+	Dir string
+}
+
+func (s HwmonSource) Temperatures() ([]Reading, error) {
+	inputs, err := filepath.Glob(filepath.Join(s.Dir, "hwmon*", "temp*_input"))
+	if err != nil {
+		return nil, err
+	}
+	// Some drivers keep the sensors in the device directory
+	deviceInputs, err := filepath.Glob(filepath.Join(s.Dir, "hwmon*", "device", "temp*_input"))
+	if err != nil {
+		return nil, err
+	}
+	inputs = append(inputs, deviceInputs...)
+
+	readings := make([]Reading, 0, len(inputs))
+	var failed []string
+	for _, input := range inputs {
+		dir := filepath.Dir(input)
+		base := filepath.Join(dir, strings.TrimSuffix(filepath.Base(input), "_input"))
+
+		millis, err := readMillidegrees(input)
+		if err != nil {
+			failed = append(failed, input)
+			continue
+		}
+		chip, err := os.ReadFile(filepath.Join(dir, "name"))
+		if err != nil {
+			// The device directory has no name of its own
+			if chip, err = os.ReadFile(filepath.Join(filepath.Dir(dir), "name")); err != nil {
+				failed = append(failed, input)
+				continue
+			}
+		}
+		name := strings.TrimSpace(string(chip))
+		if label, err := os.ReadFile(base + "_label"); err == nil && len(strings.TrimSpace(string(label))) > 0 {
+			name += "_" + strings.Join(strings.Fields(strings.ToLower(string(label))), "_")
+		}
+
+		r := Reading{Sensor: name, Temperature: millis}
+		r.High, _ = readMillidegrees(base + "_max")
+		r.Critical, _ = readMillidegrees(base + "_crit")
+		readings = append(readings, r)
+	}
+	if len(failed) > 0 {
+		return readings, fmt.Errorf("failed to read %d sensors: %s", len(failed), strings.Join(failed, ", "))
+	}
+	return readings, nil
+}
+
+// readMillidegrees reads a hwmon temperature file, which holds millidegrees
+// Celsius, in degrees.
+func readMillidegrees(path string) (float64, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return 0, err
+	}
+	value, err := strconv.ParseFloat(strings.TrimSpace(string(raw)), 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid temperature in '%s': %w", path, err)
+	}
+	return value / 1000, nil
+}
@@ -0,0 +1,61 @@
+package sensors
+
+import (
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func TestHwmonSource(t *testing.T) {
+	// testdata/hwmon holds labelled coretemp sensors with thresholds, one
+	// with an empty label and an invalid threshold, other files and
+	// thresholds without an input, an nvme device with its sensors and
+	// name in the device directory, and an amdgpu device with its sensors
+	// in the device directory, named in the hwmon directory.
+	readings, err := HwmonSource{Dir: filepath.Join("testdata", "hwmon")}.Temperatures()
+	if err != nil {
+		t.Fatalf("Temperatures: %v", err)
+	}
+	sort.Slice(readings, func(i, j int) bool {
+		return readings[i].Sensor < readings[j].Sensor
+	})
+	want := []Reading{
+		{Sensor: "amdgpu_edge", Temperature: 52},
+		{Sensor: "coretemp", Temperature: 41},
+		{Sensor: "coretemp_core_0", Temperature: 43.5},
+		{Sensor: "coretemp_package_id_0", Temperature: 45, High: 80, Critical: 100},
+		{Sensor: "nvme", Temperature: 38.85},
+	}
+	if len(readings) != len(want) {
+		t.Fatalf("got %d readings %+v, want %d", len(readings), readings, len(want))
+	}
+	for i := range want {
+		if readings[i] != want[i] {
+			t.Errorf("reading %d = %+v, want %+v", i, readings[i], want[i])
+		}
+	}
+}
+
+func TestHwmonSourcePartialFailure(t *testing.T) {
+	// An unreadable input in hwmon0, and a sensor without a name in hwmon1
+	readings, err := HwmonSource{Dir: filepath.Join("testdata", "hwmon_partial")}.Temperatures()
+	if err == nil {
+		t.Fatal("Temperatures returned no error")
+	}
+	if !strings.Contains(err.Error(), "failed to read 2 sensors") ||
+		!strings.Contains(err.Error(), filepath.Join("hwmon0", "temp2_input")) ||
+		!strings.Contains(err.Error(), filepath.Join("hwmon1", "temp1_input")) {
+		t.Errorf("error = %v, want both failed inputs", err)
+	}
+	if len(readings) != 1 || readings[0] != (Reading{Sensor: "acpitz", Temperature: 27.8}) {
+		t.Errorf("readings = %+v, want the readable acpitz sensor", readings)
+	}
+}
+
+func TestHwmonSourceEmpty(t *testing.T) {
+	readings, err := HwmonSource{Dir: t.TempDir()}.Temperatures()
+	if err != nil || len(readings) != 0 {
+		t.Errorf("Temperatures() = %+v, %v, want no readings and no error", readings, err)
+	}
+}
@@ -0,0 +1,88 @@
+package sensors
+
+import (
+	"database/sql"
+	"fmt"
+)
+
+const dailyTableStmt = `
+	CREATE TABLE IF NOT EXISTS sensor_daily (
+		date TEXT NOT NULL,
+		sensor TEXT NOT NULL,
+		min REAL NOT NULL,
+		max REAL NOT NULL,
+		PRIMARY KEY (date, sensor)
+	);`
+
+// DailyRange is the lowest and highest temperature of a sensor on one day.
+type DailyRange struct {
+	Date   string  `json:"date"`
+	Sensor string  `json:"sensor"`
+	Min    float64 `json:"min"`
+	Max    float64 `json:"max"`
+}
+
+// Store persists the daily minimum and maximum of each sensor.
+type Store struct {
+	db *sql.DB
+}
+
+// NewStore creates the sensor table on db if needed.
+func NewStore(db *sql.DB) (*Store, error) {
+	if _, err := db.Exec(dailyTableStmt); err != nil {
+		return nil, fmt.Errorf("failed to create sensor daily table: %w", err)
+	}
+	return &Store{db: db}, nil
+}
+
+// Record widens the range of each sensor on date (YYYY-MM-DD) to include
+// its reading.
+func (s *Store) Record(date string, readings []Reading) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.Prepare(`
+		INSERT INTO sensor_daily (date, sensor, min, max)
+		VALUES (?, ?, ?, ?)
+		ON CONFLICT (date, sensor) DO UPDATE SET
+			min = MIN(min, excluded.min),
+			max = MAX(max, excluded.max)`)
+	if err != nil {
+		return fmt.Errorf("failed to prepare sensor statement: %w", err)
+	}
+	defer stmt.Close()
+
+	for _, r := range readings {
+		if _, err := stmt.Exec(date, r.Sensor, r.Temperature, r.Temperature); err != nil {
+			return fmt.Errorf("failed to record sensor '%s': %w", r.Sensor, err)
+		}
+	}
+	return tx.Commit()
+}
+
+// Daily returns the ranges of every sensor from one date to another
+// (YYYY-MM-DD, inclusive), by date and sensor.
+func (s *Store) Daily(from, to string) ([]DailyRange, error) {
+	rows, err := s.db.Query(`
+		SELECT date, sensor, min, max
+		FROM sensor_daily
+		WHERE date BETWEEN ? AND ?
+		ORDER BY date, sensor`, from, to)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query sensor ranges: %w", err)
+	}
+	defer rows.Close()
+
+	ranges := []DailyRange{}
+	for rows.Next() {
+		var r DailyRange
+		if err := rows.Scan(&r.Date, &r.Sensor, &r.Min, &r.Max); err != nil {
+			return nil, fmt.Errorf("failed to scan sensor range: %w", err)
+		}
+		ranges = append(ranges, r)
+	}
+	return ranges, rows.Err()
+}
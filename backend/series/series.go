@@ -0,0 +1,145 @@
+// Package series calculates, smooths and buckets the rates of the samplers,
+// such as network and disk throughput.
+package series
+
+import "time"
+
+// MovingAverage is the average of the last values added, up to a window.
+type MovingAverage struct {
+	window int
+	values []float64
+	index  int
+	count  int
+}
+
+// NewMovingAverage creates a MovingAverage over window values.
+func NewMovingAverage(window int) *MovingAverage {
+	return &MovingAverage{
+		window: window,
+		values: make([]float64, window),
+	}
+}
+
+// Add adds a value and returns the new average.
+func (ma *MovingAverage) Add(value float64) float64 {
+	ma.values[ma.index] = value
+	ma.index = (ma.index + 1) % ma.window
+	if ma.count < ma.window {
+		ma.count++
+	}
+	return ma.Average()
+}
+
+// Average returns the average of the values in the window, or 0 if there
+// are none.
+func (ma *MovingAverage) Average() float64 {
+	if ma.count == 0 {
+		return 0
+	}
+	var sum float64
+	for i := 0; i < ma.count; i++ {
+		sum += ma.values[i]
+	}
+	return sum / float64(ma.count)
+}
+
+// Reset drops every value, e.g. after a gap in sampling.
+func (ma *MovingAverage) Reset() {
+	ma.count = 0
+	ma.index = 0
+}
+
+// CounterRate returns the per-second increase of a cumulative counter over
+// seconds, or 0 if the counter went backwards because it was reset.
+func CounterRate(prev, cur uint64, seconds float64) float64 {
+	if cur < prev {
+		return 0
+	}
+	return float64(cur-prev) / seconds
+}
+
+// Point is the average of each series over one bucket of a RingBuffer.
+type Point struct {
+	// OffsetMin is the start of the bucket relative to the current one, in
+	// minutes; the newest point has offset 0.
+	OffsetMin int
+	Values    []float64
+}
+
+type bucket struct {
+	sums  []float64
+	count int
+}
+
+// RingBuffer averages several series, such as download and upload rates,
+// over fixed intervals and keeps the most recent buckets.
+type RingBuffer struct {
+	buckets    []bucket
+	size       int
+	series     int
+	interval   time.Duration
+	cursor     int
+	lastUpdate time.Time
+}
+
+// NewRingBuffer creates a RingBuffer of size buckets of interval each, for
+// the given number of series.
+func NewRingBuffer(size int, interval time.Duration, series int) *RingBuffer {
+	rb := &RingBuffer{
+		buckets:    make([]bucket, size),
+		size:       size,
+		series:     series,
+		interval:   interval,
+		lastUpdate: time.Now(),
+	}
+	for i := range rb.buckets {
+		rb.buckets[i].sums = make([]float64, series)
+	}
+	return rb
+}
+
+func (rb *RingBuffer) advance() {
+	now := time.Now()
+	// Loop to catch up for multiple missed intervals (e.g. after sleep)
+	for now.Sub(rb.lastUpdate) > rb.interval {
+		rb.cursor = (rb.cursor + 1) % rb.size
+		// Reset the new bucket
+		clear(rb.buckets[rb.cursor].sums)
+		rb.buckets[rb.cursor].count = 0
+		rb.lastUpdate = rb.lastUpdate.Add(rb.interval)
+	}
+}
+
+// Add records one value of each series, in the order the series were
+// counted in NewRingBuffer.
+func (rb *RingBuffer) Add(values ...float64) {
+	rb.advance()
+	b := &rb.buckets[rb.cursor]
+	for i := 0; i < rb.series && i < len(values); i++ {
+		b.sums[i] += values[i]
+	}
+	b.count++
+}
+
+// Points returns the averages of every bucket, oldest first. Buckets
+// without values average to 0.
+func (rb *RingBuffer) Points() []Point {
+	points := make([]Point, 0, rb.size)
+	for i := 0; i < rb.size; i++ {
+		// Start from the oldest bucket and go to the newest
+		b := rb.buckets[(rb.cursor+1+i)%rb.size]
+
+		averages := make([]float64, rb.series)
+		if b.count > 0 {
+			for j, sum := range b.sums {
+				averages[j] = sum / float64(b.count)
+			}
+		}
+
+		// The last point is offset 0, the first -(size-1) intervals
+		offset := (i - (rb.size - 1)) * int(rb.interval.Minutes())
+
+		points = append(points, Point{OffsetMin: offset, Values: averages})
+	}
+	return points
+}
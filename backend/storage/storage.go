@@ -0,0 +1,24 @@
+// Package storage opens the SQLite database shared by all persistent stores.
+package storage
+
+import (
+	"database/sql"
+	"fmt"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// Open opens the SQLite database at path. Several stores write to the same
+// database from different goroutines, so writers wait for the lock instead
+// of failing with SQLITE_BUSY, and WAL mode keeps readers from blocking them.
+func Open(path string) (*sql.DB, error) {
+	db, err := sql.Open("sqlite3", "file:"+path+"?_busy_timeout=5000&_journal_mode=WAL")
+	if err != nil {
+		return nil, fmt.Errorf("failed to open database: %w", err)
+	}
+	if err := db.Ping(); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to open database: %w", err)
+	}
+	return db, nil
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"macos-monitor/backend-go/battery"
+	"macos-monitor/backend-go/cpustats"
+	"macos-monitor/backend-go/loadstats"
+	"macos-monitor/backend-go/memstats"
+	"macos-monitor/backend-go/sensors"
+)
+
+// cpuHandler serves the CPU usage of the last sample interval, in total and
+// per core.
+func cpuHandler(s *cpustats.Sampler) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(s.Snapshot())
+	}
+}
+
+// memoryHandler serves the latest memory and swap usage with the memory
+// pressure level.
+func memoryHandler(s *memstats.Sampler) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(s.Snapshot())
+	}
+}
+
+// loadHandler serves the current load average, process counts and
+// scheduler rates.
+func loadHandler(s *loadstats.Sampler) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(s.Stats())
+	}
+}
+
+// loadHourlyHandler serves the load of the last hour.
+func loadHourlyHandler(s *loadstats.Sampler) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(s.GetHourlyStats())
+	}
+}
+
+// sensorsHandler serves the latest temperature of every sensor with its
+// range so far today.
+func sensorsHandler(c *sensors.Collector) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(c.Sensors())
+	}
+}
+
+// maxSensorRangeDays bounds the days of sensor ranges returned at once.
+const maxSensorRangeDays = 366
+
+// sensorsDailyHandler serves the daily temperature ranges of the last
+// "days" days, including today; 7 by default.
+func sensorsDailyHandler(c *sensors.Collector) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		days := 7
+		if v := r.URL.Query().Get("days"); v != "" {
+			n, err := strconv.Atoi(v)
+			if err != nil || n < 1 || n > maxSensorRangeDays {
+				http.Error(w, fmt.Sprintf("Invalid 'days', expected 1 to %d", maxSensorRangeDays), http.StatusBadRequest)
+				return
+			}
+			days = n
+		}
+		to := time.Now()
+		from := to.AddDate(0, 0, 1-days)
+		ranges, err := c.Daily(from.Format("2006-01-02"), to.Format("2006-01-02"))
+		if err != nil {
+			http.Error(w, "Could not retrieve sensor ranges", http.StatusInternalServerError)
+			log.Printf("Error getting sensor ranges: %v", err)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(ranges)
+	}
+}
+
+// batteryHandler serves the status of the battery; "present" is false on
+// systems without one.
+func batteryHandler(c *battery.Collector) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(c.Status())
+	}
+}
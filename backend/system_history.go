@@ -0,0 +1,125 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"slices"
+	"strconv"
+	"time"
+
+	"macos-monitor/backend-go/history"
+)
+
+const (
+	defaultHistoryRange  = 1 * time.Hour
+	defaultHistoryPoints = 300
+	maxHistoryPoints     = 10000
+)
+
+type systemHistoryResponse struct {
+	Metric     string          `json:"metric"`
+	Label      string          `json:"label,omitempty"`
+	From       int64           `json:"from"`
+	To         int64           `json:"to"`
+	StepSec    int64           `json:"step_sec"`
+	Resolution int64           `json:"resolution_sec"`
+	Points     []history.Point `json:"points"`
+}
+
+// systemHistoryHandler serves a recorded metric between "from" and "to"
+// (unix seconds or RFC 3339), bucketed by "step" (a duration such as "5m"
+// or seconds). Without "step", the range is split into about 300 points,
+// but never finer than the sample interval.
+func systemHistoryHandler(store *history.Store, sampleInterval time.Duration) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		metric := q.Get("metric")
+		if !slices.Contains(history.Metrics, metric) {
+			http.Error(w, fmt.Sprintf("Unknown metric '%s'", metric), http.StatusBadRequest)
+			return
+		}
+
+		to := time.Now()
+		if v := q.Get("to"); v != "" {
+			t, err := parseTimeParam(v)
+			if err != nil {
+				http.Error(w, fmt.Sprintf("Invalid 'to': %v", err), http.StatusBadRequest)
+				return
+			}
+			to = t
+		}
+		from := to.Add(-defaultHistoryRange)
+		if v := q.Get("from"); v != "" {
+			t, err := parseTimeParam(v)
+			if err != nil {
+				http.Error(w, fmt.Sprintf("Invalid 'from': %v", err), http.StatusBadRequest)
+				return
+			}
+			from = t
+		}
+		if !from.Before(to) {
+			http.Error(w, "'from' must be before 'to'", http.StatusBadRequest)
+			return
+		}
+
+		step := to.Sub(from) / defaultHistoryPoints
+		if v := q.Get("step"); v != "" {
+			d, err := parseStepParam(v)
+			if err != nil {
+				http.Error(w, fmt.Sprintf("Invalid 'step': %v", err), http.StatusBadRequest)
+				return
+			}
+			step = d
+		}
+		// Timestamps have a resolution of one second
+		step = max(step, sampleInterval, time.Second).Truncate(time.Second)
+		if to.Sub(from)/step > maxHistoryPoints {
+			http.Error(w, fmt.Sprintf("Too many points; use a step of at least %s", to.Sub(from)/maxHistoryPoints), http.StatusBadRequest)
+			return
+		}
+
+		label := q.Get("label")
+		points, res, err := store.Query(metric, label, from, to, step)
+		if err != nil {
+			http.Error(w, "Could not retrieve system history", http.StatusInternalServerError)
+			log.Printf("Error querying system history: %v", err)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(systemHistoryResponse{
+			Metric:     metric,
+			Label:      label,
+			From:       from.Unix(),
+			To:         to.Unix(),
+			StepSec:    int64(step / time.Second),
+			Resolution: int64(res),
+			Points:     points,
+		})
+	}
+}
+
+// parseTimeParam accepts unix seconds or an RFC 3339 timestamp.
+func parseTimeParam(v string) (time.Time, error) {
+	if sec, err := strconv.ParseInt(v, 10, 64); err == nil {
+		return time.Unix(sec, 0), nil
+	}
+	return time.Parse(time.RFC3339, v)
+}
+
+// parseStepParam accepts a duration such as "5m" or a number of seconds.
+func parseStepParam(v string) (time.Duration, error) {
+	if sec, err := strconv.ParseInt(v, 10, 64); err == nil {
+		if sec <= 0 {
+			return 0, fmt.Errorf("must be positive")
+		}
+		return time.Duration(sec) * time.Second, nil
+	}
+	d, err := time.ParseDuration(v)
+	if err == nil && d <= 0 {
+		return 0, fmt.Errorf("must be positive")
+	}
+	return d, err
+}
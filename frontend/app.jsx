@@ -491,7 +491,7 @@ const App = () => {
 
   useEffect(() => {
     // WebSocket for real-time network speed
-    const ws = new WebSocket(`${API_URL.replace('http:', 'ws:')}/ws/network/realtime`);
+    const ws = new WebSocket(`${API_URL.replace(/^http/, 'ws')}/ws/network/realtime`);
     ws.onmessage = (event) => {
       const data = JSON.parse(event.data);
       setUploadSpeed((data.up_bps || 0)); 